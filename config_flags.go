@@ -0,0 +1,91 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/buildkite/test-engine-client/internal/debug"
+)
+
+// configFlag is a command line flag that mirrors a configuration environment variable.
+type configFlag struct {
+	name    string
+	envName string
+	usage   string
+	isBool  bool
+}
+
+// configFlags are the command line flags of the commands that read the configuration.
+// The flags take precedence over the environment variables, which take precedence
+// over the config file. A word in backquotes in the usage is shown as the flag value name.
+var configFlags = []configFlag{
+	{name: "access-token", envName: "BUILDKITE_TEST_ENGINE_API_ACCESS_TOKEN", usage: "Buildkite API access `token` with the read_suites, read_test_plan, and write_test_plan scopes"},
+	{name: "annotate", envName: "BUILDKITE_TEST_ENGINE_ANNOTATE", usage: "annotate the Buildkite build with the summary of the run, using buildkite-agent", isBool: true},
+	{name: "base-url", envName: "BUILDKITE_TEST_ENGINE_BASE_URL", usage: "base `url` of the Test Plan API"},
+	{name: "branch", envName: "BUILDKITE_TEST_ENGINE_BRANCH", usage: "git `branch` of the build, defaults to the branch provided by the CI system"},
+	{name: "build-id", envName: "BUILDKITE_TEST_ENGINE_BUILD_ID", usage: "`id` of the build, used with the step id to identify the test plan instead of the CI system's variables"},
+	{name: "config", envName: "BUILDKITE_TEST_ENGINE_CONFIG_FILE", usage: "`path` of the config file, defaults to .bktec.yml"},
+	{name: "debug", envName: "BUILDKITE_TEST_ENGINE_DEBUG_ENABLED", usage: "print debug information", isBool: true},
+	{name: "flaky-policy", envName: "BUILDKITE_TEST_ENGINE_FLAKY_POLICY", usage: "how to exit when failed tests pass on retry, either \"pass\", \"fail\" or \"soft-fail\""},
+	{name: "json-report-path", envName: "BUILDKITE_TEST_ENGINE_JSON_REPORT_PATH", usage: "`path` to write the JSON report of the tests to, combining all retries"},
+	{name: "junit-report-path", envName: "BUILDKITE_TEST_ENGINE_JUNIT_REPORT_PATH", usage: "`path` to write the JUnit XML report of the tests to, combining all retries"},
+	{name: "mode", envName: "BUILDKITE_TEST_ENGINE_MODE", usage: "how the tests are distributed to the nodes, either \"static\" or \"dynamic\""},
+	{name: "node-index", envName: "BUILDKITE_TEST_ENGINE_NODE_INDEX", usage: "zero-based `index` of this node"},
+	{name: "output-timeout", envName: "BUILDKITE_TEST_ENGINE_OUTPUT_TIMEOUT", usage: "terminate a test command without output for this `duration`, e.g. \"10m\""},
+	{name: "organization-slug", envName: "BUILDKITE_ORGANIZATION_SLUG", usage: "`slug` of the Buildkite organization"},
+	{name: "parallelism", envName: "BUILDKITE_TEST_ENGINE_PARALLELISM", usage: "total `count` of nodes"},
+	{name: "plan-cache-dir", envName: "BUILDKITE_TEST_ENGINE_PLAN_CACHE_DIR", usage: "`directory` to cache the test plan in, shared between nodes"},
+	{name: "quarantine-file", envName: "BUILDKITE_TEST_ENGINE_QUARANTINE_FILE", usage: "`path` of a file listing the quarantined tests, one per line"},
+	{name: "quarantine-from-api", envName: "BUILDKITE_TEST_ENGINE_QUARANTINE_FROM_API", usage: "fetch the quarantined tests from Test Engine", isBool: true},
+	{name: "result-path", envName: "BUILDKITE_TEST_ENGINE_RESULT_PATH", usage: "`path` of the test result file read by bktec"},
+	{name: "retry-cmd", envName: "BUILDKITE_TEST_ENGINE_RETRY_CMD", usage: "`command` to retry the failed tests, defaults to the test command"},
+	{name: "retry-count", envName: "BUILDKITE_TEST_ENGINE_RETRY_COUNT", usage: "maximum `count` of retries of the failed tests"},
+	{name: "runner", envName: "BUILDKITE_TEST_ENGINE_TEST_RUNNER", usage: "`name` of the test runner, e.g. \"rspec\" or \"jest\""},
+	{name: "split-by-example", envName: "BUILDKITE_TEST_ENGINE_SPLIT_BY_EXAMPLE", usage: "split slow test files into individual examples", isBool: true},
+	{name: "step-id", envName: "BUILDKITE_TEST_ENGINE_STEP_ID", usage: "`id` of the step, used with the build id to identify the test plan instead of the CI system's variables"},
+	{name: "suite-slug", envName: "BUILDKITE_TEST_ENGINE_SUITE_SLUG", usage: "`slug` of the Test Engine suite"},
+	{name: "suite-token", envName: "BUILDKITE_ANALYTICS_TOKEN", usage: "API `token` of the Test Engine suite, used to upload the results of the tests"},
+	{name: "summary-dir", envName: "BUILDKITE_TEST_ENGINE_SUMMARY_DIR", usage: "`directory` to write the summary of the run to, defaults to the working directory"},
+	{name: "test-cmd", envName: "BUILDKITE_TEST_ENGINE_TEST_CMD", usage: "`command` to run the tests"},
+	{name: "test-file-exclude-pattern", envName: "BUILDKITE_TEST_ENGINE_TEST_FILE_EXCLUDE_PATTERN", usage: "glob `pattern` of the test files to exclude"},
+	{name: "test-file-pattern", envName: "BUILDKITE_TEST_ENGINE_TEST_FILE_PATTERN", usage: "glob `pattern` of the test files to include"},
+	{name: "timeout", envName: "BUILDKITE_TEST_ENGINE_TIMEOUT", usage: "terminate a test command that runs longer than this `duration`, e.g. \"30m\""},
+	{name: "timeout-grace-period", envName: "BUILDKITE_TEST_ENGINE_TIMEOUT_GRACE_PERIOD", usage: "`duration` to wait for a terminated test command to exit before killing it"},
+	{name: "upload-results", envName: "BUILDKITE_TEST_ENGINE_UPLOAD_RESULTS", usage: "upload the results of the tests to Test Engine, authenticated with the suite token", isBool: true},
+	{name: "workers", envName: "BUILDKITE_TEST_ENGINE_WORKERS", usage: "`count` of test runner processes to run at the same time on this node"},
+}
+
+// addConfigFlags defines the config flags on the flag set.
+// It returns a function to call after the flags are parsed, which returns the values
+// of the flags set on the command line keyed by their environment variable name,
+// to be passed as the overrides of config.New.
+func addConfigFlags(flags *flag.FlagSet) func() map[string]string {
+	envNames := map[string]string{}
+	for _, f := range configFlags {
+		usage := fmt.Sprintf("%s (%s)", f.usage, f.envName)
+		if f.isBool {
+			flags.Bool(f.name, false, usage)
+		} else {
+			flags.String(f.name, "", usage)
+		}
+		envNames[f.name] = f.envName
+	}
+
+	return func() map[string]string {
+		overrides := map[string]string{}
+		flags.Visit(func(f *flag.Flag) {
+			if envName, ok := envNames[f.Name]; ok {
+				overrides[envName] = f.Value.String()
+			}
+		})
+		return overrides
+	}
+}
+
+// setDebugFromOverrides enables or disables debug output when it's set by the --debug flag,
+// because the debug setting is read before the flags are parsed.
+func setDebugFromOverrides(overrides map[string]string) {
+	if value, ok := overrides["BUILDKITE_TEST_ENGINE_DEBUG_ENABLED"]; ok {
+		debug.SetDebug(value == "true")
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/buildkite/test-engine-client/internal/config"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestAddConfigFlags(t *testing.T) {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	configOverrides := addConfigFlags(flags)
+
+	err := flags.Parse([]string{
+		"--runner", "jest",
+		"--test-cmd", "yarn test {{testExamples}}",
+		"--retry-count", "2",
+		"--parallelism=4",
+		"--node-index", "3",
+		"--split-by-example",
+		"--result-path", "tmp/results.json",
+	})
+	if err != nil {
+		t.Fatalf("flags.Parse() error = %v", err)
+	}
+
+	want := map[string]string{
+		"BUILDKITE_TEST_ENGINE_TEST_RUNNER":      "jest",
+		"BUILDKITE_TEST_ENGINE_TEST_CMD":         "yarn test {{testExamples}}",
+		"BUILDKITE_TEST_ENGINE_RETRY_COUNT":      "2",
+		"BUILDKITE_TEST_ENGINE_PARALLELISM":      "4",
+		"BUILDKITE_TEST_ENGINE_NODE_INDEX":       "3",
+		"BUILDKITE_TEST_ENGINE_SPLIT_BY_EXAMPLE": "true",
+		"BUILDKITE_TEST_ENGINE_RESULT_PATH":      "tmp/results.json",
+	}
+
+	if diff := cmp.Diff(configOverrides(), want); diff != "" {
+		t.Errorf("configOverrides() diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestAddConfigFlags_Precedence(t *testing.T) {
+	t.Setenv("BUILDKITE_BUILD_ID", "123")
+	t.Setenv("BUILDKITE_STEP_ID", "456")
+	t.Setenv("BUILDKITE_ORGANIZATION_SLUG", "my_org")
+	t.Setenv("BUILDKITE_TEST_ENGINE_SUITE_SLUG", "my_suite")
+	t.Setenv("BUILDKITE_TEST_ENGINE_API_ACCESS_TOKEN", "my_token")
+	t.Setenv("BUILDKITE_PARALLEL_JOB_COUNT", "10")
+	t.Setenv("BUILDKITE_PARALLEL_JOB", "0")
+	t.Setenv("BUILDKITE_TEST_ENGINE_TEST_RUNNER", "rspec")
+	t.Setenv("BUILDKITE_TEST_ENGINE_RESULT_PATH", "tmp/rspec.json")
+	t.Setenv("BUILDKITE_TEST_ENGINE_RETRY_COUNT", "1")
+	t.Setenv("BUILDKITE_TEST_ENGINE_SPLIT_BY_EXAMPLE", "true")
+
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	configOverrides := addConfigFlags(flags)
+
+	err := flags.Parse([]string{"--retry-count", "3", "--split-by-example=false", "--parallelism", "4"})
+	if err != nil {
+		t.Fatalf("flags.Parse() error = %v", err)
+	}
+
+	cfg, err := config.New(configOverrides())
+	if err != nil {
+		t.Fatalf("config.New() error = %v", err)
+	}
+
+	if cfg.MaxRetries != 3 {
+		t.Errorf("MaxRetries = %d, want %d", cfg.MaxRetries, 3)
+	}
+
+	if cfg.SplitByExample {
+		t.Errorf("SplitByExample = %v, want %v", cfg.SplitByExample, false)
+	}
+
+	if cfg.Parallelism != 4 {
+		t.Errorf("Parallelism = %d, want %d", cfg.Parallelism, 4)
+	}
+
+	if cfg.NodeIndex != 0 {
+		t.Errorf("NodeIndex = %d, want %d", cfg.NodeIndex, 0)
+	}
+}
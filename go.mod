@@ -15,6 +15,7 @@ require (
 	github.com/pact-foundation/pact-go/v2 v2.0.8
 	golang.org/x/sys v0.26.0
 	golang.org/x/tools v0.24.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
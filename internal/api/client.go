@@ -35,13 +35,14 @@ type ClientConfig struct {
 
 // authTransport is a middleware for the HTTP client.
 type authTransport struct {
-	accessToken string
-	version     string
+	// authorization is the value of the Authorization header, e.g. "Bearer <access token>".
+	authorization string
+	version       string
 }
 
 // RoundTrip adds the Authorization header to all requests made by the HTTP client.
 func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
-	req.Header.Set("Authorization", "Bearer "+t.accessToken)
+	req.Header.Set("Authorization", t.authorization)
 	req.Header.Set("User-Agent", fmt.Sprintf("Buildkite Test Engine Client/%s (%s/%s)", t.version, runtime.GOOS, runtime.GOARCH))
 	return http.DefaultTransport.RoundTrip(req)
 }
@@ -51,8 +52,8 @@ func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 func NewClient(cfg ClientConfig) *Client {
 	httpClient := &http.Client{
 		Transport: &authTransport{
-			accessToken: cfg.AccessToken,
-			version:     cfg.Version,
+			authorization: "Bearer " + cfg.AccessToken,
+			version:       cfg.Version,
 		},
 	}
 
@@ -93,7 +94,7 @@ type httpRequest struct {
 }
 
 // DoWithRetry sends http request with retries.
-// Successful API response (status code 200) is JSON decoded and stored in the value pointed to by v.
+// Successful API response (status code 2xx) is JSON decoded and stored in the value pointed to by v.
 // The request will be retried when the server returns 429 or 5xx status code, or when there is a network error.
 // After reaching the retry timeout, the function will return ErrRetryTimeout.
 // The request will not be retried when the server returns 4xx status code,
@@ -172,7 +173,7 @@ func (c *Client) DoWithRetry(ctx context.Context, reqOptions httpRequest, v inte
 		}
 		defer resp.Body.Close()
 
-		if resp.StatusCode != http.StatusOK {
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
 			var respError responseError
 			err = json.Unmarshal(responseBody, &respError)
 			if err != nil {
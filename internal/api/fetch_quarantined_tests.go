@@ -0,0 +1,30 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+type quarantinedTestsResponse struct {
+	Tests []string `json:"tests"`
+}
+
+// FetchQuarantinedTests fetches the identifiers of the quarantined tests of the suite,
+// such as RSpec example IDs or Jest full names. The failures of quarantined tests don't fail the build.
+// ErrRetryTimeout is returned if the client failed to communicate with the server after exceeding the retry limit.
+func (c Client) FetchQuarantinedTests(ctx context.Context, suiteSlug string) ([]string, error) {
+	url := fmt.Sprintf("%s/v2/analytics/organizations/%s/suites/%s/quarantined_tests", c.ServerBaseUrl, c.OrganizationSlug, suiteSlug)
+
+	var response quarantinedTestsResponse
+	_, err := c.DoWithRetry(ctx, httpRequest{
+		Method: http.MethodGet,
+		URL:    url,
+	}, &response)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return response.Tests, nil
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pact-foundation/pact-go/v2/consumer"
+	"github.com/pact-foundation/pact-go/v2/matchers"
+)
+
+func TestFetchQuarantinedTests(t *testing.T) {
+	mockProvider, err := consumer.NewV2Pact(consumer.MockHTTPProviderConfig{
+		Consumer: "TestEngineClient",
+		Provider: "TestPlanServer",
+	})
+
+	if err != nil {
+		t.Error("Error mocking provider", err)
+	}
+
+	err = mockProvider.
+		AddInteraction().
+		Given("Quarantined tests exist").
+		UponReceiving("A request for quarantined tests").
+		WithRequest("GET", "/v2/analytics/organizations/buildkite/suites/rspec/quarantined_tests", func(b *consumer.V2RequestBuilder) {
+			b.Header("Authorization", matchers.Like("Bearer asdf1234"))
+		}).
+		WillRespondWith(200, func(b *consumer.V2ResponseBuilder) {
+			b.Header("Content-Type", matchers.Like("application/json; charset=utf-8"))
+			b.JSONBody(matchers.MapMatcher{
+				"tests": matchers.EachLike(matchers.Like("./spec/banana_spec.rb[1:2]"), 1),
+			})
+		}).
+		ExecuteTest(t, func(config consumer.MockServerConfig) error {
+			url := fmt.Sprintf("http://%s:%d", config.Host, config.Port)
+			c := NewClient(ClientConfig{
+				AccessToken:      "asdf1234",
+				OrganizationSlug: "buildkite",
+				ServerBaseUrl:    url,
+			})
+
+			got, err := c.FetchQuarantinedTests(context.Background(), "rspec")
+			if err != nil {
+				t.Errorf("FetchQuarantinedTests() error = %v", err)
+			}
+
+			want := []string{"./spec/banana_spec.rb[1:2]"}
+			if diff := cmp.Diff(got, want); diff != "" {
+				t.Errorf("FetchQuarantinedTests() diff (-got +want):\n%s", diff)
+			}
+
+			return nil
+		})
+
+	if err != nil {
+		t.Error(err)
+	}
+}
@@ -9,8 +9,10 @@ import (
 )
 
 type FilterTestsParams struct {
-	Files []plan.TestCase   `json:"files"`
-	Env   map[string]string `json:"env"`
+	Files []plan.TestCase `json:"files"`
+	// Parallelism is the number of nodes resolved by the client, whichever CI provider it comes from.
+	Parallelism int               `json:"parallelism"`
+	Env         map[string]string `json:"env"`
 }
 
 type FilteredTest struct {
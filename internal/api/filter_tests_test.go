@@ -37,6 +37,7 @@ func TestFilterTests_SlowFiles(t *testing.T) {
 				Path: "./turtle_spec.rb",
 			},
 		},
+		Parallelism: 3,
 		Env: map[string]string{
 			"BUILDKITE_PARALLEL_JOB_COUNT":           "3",
 			"BUILDKITE_TEST_ENGINE_SPLIT_BY_EXAMPLE": "true",
@@ -15,6 +15,8 @@ type TestPlanMetadataParams struct {
 	Version  string            `json:"version"`
 	Env      map[string]string `json:"env"`
 	Timeline []Timeline        `json:"timeline"`
+	// FlakyTests are the tests that failed and then passed when they were retried.
+	FlakyTests []string `json:"flaky_tests,omitempty"`
 }
 
 func (c Client) PostTestPlanMetadata(ctx context.Context, suiteSlug string, identifier string, params TestPlanMetadataParams) error {
@@ -1,6 +1,9 @@
 package api
 
-import "strings"
+import (
+	"fmt"
+	"strings"
+)
 
 //go:generate go run golang.org/x/tools/cmd/stringer -type=Runner
 type Runner int
@@ -13,3 +16,13 @@ const (
 func (r Runner) MarshalText() ([]byte, error) {
 	return []byte(strings.ToLower(r.String())), nil
 }
+
+func (r *Runner) UnmarshalText(text []byte) error {
+	for i := Rspec; i <= Jest; i++ {
+		if strings.ToLower(i.String()) == string(text) {
+			*r = i
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown runner %q", text)
+}
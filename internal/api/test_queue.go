@@ -0,0 +1,55 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/buildkite/test-engine-client/internal/plan"
+)
+
+// NextTestBatchParams represents the params sent when fetching the next batch of tests from the queue.
+type NextTestBatchParams struct {
+	Identifier string `json:"identifier"`
+	NodeNumber int    `json:"node_number"`
+}
+
+type nextTestBatchResponse struct {
+	Tests []plan.TestCase `json:"tests"`
+}
+
+// CreateTestQueue creates a queue of tests on the server, which the nodes pull batches of tests from.
+// The queue is identified by the identifier in the params, and creating a queue that already exists
+// is a no-op, so every node can call it.
+// ErrRetryTimeout is returned if the client failed to communicate with the server after exceeding the retry limit.
+func (c Client) CreateTestQueue(ctx context.Context, suiteSlug string, params TestPlanParams) error {
+	url := fmt.Sprintf("%s/v2/analytics/organizations/%s/suites/%s/test_plan/queue", c.ServerBaseUrl, c.OrganizationSlug, suiteSlug)
+
+	_, err := c.DoWithRetry(ctx, httpRequest{
+		Method: http.MethodPost,
+		URL:    url,
+		Body:   params,
+	}, nil)
+
+	return err
+}
+
+// FetchNextTestBatch fetches the next batch of tests for the node from the queue.
+// An empty batch is returned when the queue is drained.
+// ErrRetryTimeout is returned if the client failed to communicate with the server after exceeding the retry limit.
+func (c Client) FetchNextTestBatch(ctx context.Context, suiteSlug string, params NextTestBatchParams) ([]plan.TestCase, error) {
+	url := fmt.Sprintf("%s/v2/analytics/organizations/%s/suites/%s/test_plan/queue/next", c.ServerBaseUrl, c.OrganizationSlug, suiteSlug)
+
+	var response nextTestBatchResponse
+	_, err := c.DoWithRetry(ctx, httpRequest{
+		Method: http.MethodPost,
+		URL:    url,
+		Body:   params,
+	}, &response)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return response.Tests, nil
+}
@@ -0,0 +1,159 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/buildkite/test-engine-client/internal/plan"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pact-foundation/pact-go/v2/consumer"
+	"github.com/pact-foundation/pact-go/v2/matchers"
+)
+
+func TestCreateTestQueue(t *testing.T) {
+	mockProvider, err := consumer.NewV2Pact(consumer.MockHTTPProviderConfig{
+		Consumer: "TestEngineClient",
+		Provider: "TestPlanServer",
+	})
+
+	if err != nil {
+		t.Error("Error mocking provider", err)
+	}
+
+	params := TestPlanParams{
+		Runner:      Rspec,
+		Identifier:  "abc123",
+		Parallelism: 3,
+		Branch:      "main",
+		Tests: TestPlanParamsTest{
+			Files: []plan.TestCase{
+				{Path: "apple_spec.rb"},
+				{Path: "banana_spec.rb"},
+			},
+		},
+	}
+
+	err = mockProvider.
+		AddInteraction().
+		Given("A test queue doesn't exist").
+		UponReceiving("A request to create test queue with identifier abc123").
+		WithRequest("POST", "/v2/analytics/organizations/buildkite/suites/rspec/test_plan/queue", func(b *consumer.V2RequestBuilder) {
+			b.Header("Authorization", matchers.Like("Bearer asdf1234"))
+			b.JSONBody(params)
+		}).
+		WillRespondWith(200, func(b *consumer.V2ResponseBuilder) {
+			b.Header("Content-Type", matchers.Like("application/json; charset=utf-8"))
+			b.JSONBody(matchers.MapMatcher{
+				"identifier": matchers.Like("abc123"),
+			})
+		}).
+		ExecuteTest(t, func(config consumer.MockServerConfig) error {
+			url := fmt.Sprintf("http://%s:%d", config.Host, config.Port)
+			c := NewClient(ClientConfig{
+				AccessToken:      "asdf1234",
+				OrganizationSlug: "buildkite",
+				ServerBaseUrl:    url,
+			})
+
+			if err := c.CreateTestQueue(context.Background(), "rspec", params); err != nil {
+				t.Errorf("CreateTestQueue() error = %v", err)
+			}
+			return nil
+		})
+
+	if err != nil {
+		t.Error("mockProvider error", err)
+	}
+}
+
+func TestFetchNextTestBatch(t *testing.T) {
+	mockProvider, err := consumer.NewV2Pact(consumer.MockHTTPProviderConfig{
+		Consumer: "TestEngineClient",
+		Provider: "TestPlanServer",
+	})
+
+	if err != nil {
+		t.Error("Error mocking provider", err)
+	}
+
+	params := NextTestBatchParams{
+		Identifier: "abc123",
+		NodeNumber: 1,
+	}
+
+	err = mockProvider.
+		AddInteraction().
+		Given("A test queue with tests exists").
+		UponReceiving("A request for the next batch of tests with identifier abc123").
+		WithRequest("POST", "/v2/analytics/organizations/buildkite/suites/rspec/test_plan/queue/next", func(b *consumer.V2RequestBuilder) {
+			b.Header("Authorization", matchers.Like("Bearer asdf1234"))
+			b.JSONBody(params)
+		}).
+		WillRespondWith(200, func(b *consumer.V2ResponseBuilder) {
+			b.Header("Content-Type", matchers.Like("application/json; charset=utf-8"))
+			b.JSONBody(matchers.MapMatcher{
+				"tests": matchers.EachLike(matchers.MapMatcher{
+					"path":               matchers.Like("apple_spec.rb"),
+					"format":             matchers.Like("file"),
+					"estimated_duration": matchers.Like(1000),
+				}, 1),
+			})
+		}).
+		ExecuteTest(t, func(config consumer.MockServerConfig) error {
+			url := fmt.Sprintf("http://%s:%d", config.Host, config.Port)
+			c := NewClient(ClientConfig{
+				AccessToken:      "asdf1234",
+				OrganizationSlug: "buildkite",
+				ServerBaseUrl:    url,
+			})
+
+			got, err := c.FetchNextTestBatch(context.Background(), "rspec", params)
+			if err != nil {
+				t.Errorf("FetchNextTestBatch() error = %v", err)
+			}
+
+			want := []plan.TestCase{
+				{Path: "apple_spec.rb", Format: plan.TestCaseFormatFile, EstimatedDuration: 1000},
+			}
+
+			if diff := cmp.Diff(got, want); diff != "" {
+				t.Errorf("FetchNextTestBatch() diff (-got +want):\n%s", diff)
+			}
+			return nil
+		})
+
+	if err != nil {
+		t.Error("mockProvider error", err)
+	}
+}
+
+func TestFetchNextTestBatch_InternalServerError(t *testing.T) {
+	originalTimeout := retryTimeout
+	retryTimeout = 1 * time.Millisecond
+	t.Cleanup(func() {
+		retryTimeout = originalTimeout
+	})
+
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message": "something went wrong"}`, http.StatusInternalServerError)
+	}))
+	defer svr.Close()
+
+	c := NewClient(ClientConfig{
+		OrganizationSlug: "my-org",
+		ServerBaseUrl:    svr.URL,
+	})
+
+	_, err := c.FetchNextTestBatch(context.Background(), "my-suite", NextTestBatchParams{
+		Identifier: "abc123",
+	})
+
+	if !errors.Is(err, ErrRetryTimeout) {
+		t.Errorf("FetchNextTestBatch() error = %v, want %v", err, ErrRetryTimeout)
+	}
+}
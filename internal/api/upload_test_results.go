@@ -0,0 +1,122 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"net/http"
+)
+
+// UploadClientConfig is the configuration for the Test Engine upload API client.
+type UploadClientConfig struct {
+	// SuiteToken is the API token of the Test Engine suite, which is not the same as the access token of the Test Plan API.
+	SuiteToken    string
+	ServerBaseUrl string
+	Version       string
+}
+
+// NewUploadClient creates a new client for the Test Engine upload API with the given configuration.
+// Unlike the Test Plan API, the upload API is authenticated with the token of the suite.
+func NewUploadClient(cfg UploadClientConfig) *Client {
+	httpClient := &http.Client{
+		Transport: &authTransport{
+			authorization: fmt.Sprintf("Token token=%q", cfg.SuiteToken),
+			version:       cfg.Version,
+		},
+	}
+
+	return &Client{
+		ServerBaseUrl: cfg.ServerBaseUrl,
+		httpClient:    httpClient,
+	}
+}
+
+// UploadRunEnv describes the CI run of the uploaded test results.
+// Test Engine groups the uploads with the same key into a single run.
+type UploadRunEnv struct {
+	CI        string `json:"CI"`
+	Key       string `json:"key"`
+	JobId     string `json:"job_id,omitempty"`
+	Branch    string `json:"branch,omitempty"`
+	Collector string `json:"collector"`
+	Version   string `json:"version,omitempty"`
+}
+
+// UploadTestResult is the result of an execution of a test, in the JSON format of the upload API.
+type UploadTestResult struct {
+	// Id identifies the execution of the test. UploadTestResults generates a random UUID when it's empty.
+	Id       string `json:"id"`
+	Scope    string `json:"scope"`
+	Name     string `json:"name"`
+	Location string `json:"location,omitempty"`
+	FileName string `json:"file_name,omitempty"`
+	// Result is either "passed", "failed" or "skipped".
+	Result  string            `json:"result"`
+	History UploadTestHistory `json:"history"`
+}
+
+// UploadTestHistory is the timing of an execution of a test, in seconds.
+type UploadTestHistory struct {
+	Section  string  `json:"section"`
+	StartAt  float64 `json:"start_at"`
+	EndAt    float64 `json:"end_at"`
+	Duration float64 `json:"duration"`
+}
+
+type uploadParams struct {
+	Format string             `json:"format"`
+	RunEnv UploadRunEnv       `json:"run_env"`
+	Data   []UploadTestResult `json:"data"`
+}
+
+// uploadBatchSize is the maximum number of test results in a single request, which is the limit of the upload API.
+var uploadBatchSize = 5000
+
+// UploadTestResults uploads the test results to Test Engine in batches of up to 5000 results,
+// one request after another. Each request is retried by DoWithRetry,
+// and error is returned for the first batch that can't be uploaded.
+func (c Client) UploadTestResults(ctx context.Context, runEnv UploadRunEnv, results []UploadTestResult) error {
+	url := fmt.Sprintf("%s/v1/uploads", c.ServerBaseUrl)
+
+	for start := 0; start < len(results); start += uploadBatchSize {
+		end := min(start+uploadBatchSize, len(results))
+
+		data := make([]UploadTestResult, 0, end-start)
+		for _, result := range results[start:end] {
+			if result.Id == "" {
+				id, err := newUUID()
+				if err != nil {
+					return fmt.Errorf("failed to generate test result id: %w", err)
+				}
+				result.Id = id
+			}
+			data = append(data, result)
+		}
+
+		_, err := c.DoWithRetry(ctx, httpRequest{
+			Method: http.MethodPost,
+			URL:    url,
+			Body: uploadParams{
+				Format: "json",
+				RunEnv: runEnv,
+				Data:   data,
+			},
+		}, nil)
+		if err != nil {
+			return fmt.Errorf("failed to upload test results %d to %d of %d: %w", start+1, end, len(results), err)
+		}
+	}
+
+	return nil
+}
+
+// newUUID returns a random (version 4) UUID.
+func newUUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
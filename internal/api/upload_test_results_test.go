@@ -0,0 +1,113 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestUploadTestResults(t *testing.T) {
+	originalBatchSize := uploadBatchSize
+	uploadBatchSize = 2
+	t.Cleanup(func() {
+		uploadBatchSize = originalBatchSize
+	})
+
+	var requests []uploadParams
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/uploads" {
+			t.Errorf("request = %s %s, want POST /v1/uploads", r.Method, r.URL.Path)
+		}
+		if got, want := r.Header.Get("Authorization"), `Token token="suite-token"`; got != want {
+			t.Errorf("Authorization header = %q, want %q", got, want)
+		}
+
+		var params uploadParams
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			t.Errorf("decoding request body error = %v", err)
+		}
+		requests = append(requests, params)
+
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(`{"id": "abc", "queued": 2, "skipped": 0, "errors": []}`))
+	}))
+	defer svr.Close()
+
+	c := NewUploadClient(UploadClientConfig{
+		SuiteToken:    "suite-token",
+		ServerBaseUrl: svr.URL,
+	})
+
+	runEnv := UploadRunEnv{CI: "buildkite", Key: "build/step", Collector: "bktec"}
+	results := []UploadTestResult{
+		{Scope: "Apple", Name: "is red", FileName: "./spec/apple_spec.rb", Result: "passed", History: UploadTestHistory{Section: "top", EndAt: 1.5, Duration: 1.5}},
+		{Id: "given-id", Scope: "Apple", Name: "is sweet", Result: "failed"},
+		{Scope: "Banana", Name: "is yellow", Result: "skipped"},
+	}
+
+	if err := c.UploadTestResults(context.Background(), runEnv, results); err != nil {
+		t.Errorf("UploadTestResults() error = %v", err)
+	}
+
+	if len(requests) != 2 {
+		t.Fatalf("request count = %d, want 2", len(requests))
+	}
+
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	var got []UploadTestResult
+	for _, request := range requests {
+		if request.Format != "json" {
+			t.Errorf("format = %q, want %q", request.Format, "json")
+		}
+		if diff := cmp.Diff(request.RunEnv, runEnv); diff != "" {
+			t.Errorf("run_env diff (-got +want):\n%s", diff)
+		}
+		for _, result := range request.Data {
+			if result.Id != "given-id" && !uuid.MatchString(result.Id) {
+				t.Errorf("id = %q, want a UUID", result.Id)
+			}
+			result.Id = ""
+			got = append(got, result)
+		}
+	}
+
+	want := []UploadTestResult{
+		{Scope: "Apple", Name: "is red", FileName: "./spec/apple_spec.rb", Result: "passed", History: UploadTestHistory{Section: "top", EndAt: 1.5, Duration: 1.5}},
+		{Scope: "Apple", Name: "is sweet", Result: "failed"},
+		{Scope: "Banana", Name: "is yellow", Result: "skipped"},
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("uploaded test results diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestUploadTestResults_InternalServerError(t *testing.T) {
+	originalTimeout := retryTimeout
+	retryTimeout = 1 * time.Millisecond
+	t.Cleanup(func() {
+		retryTimeout = originalTimeout
+	})
+
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message": "something went wrong"}`, http.StatusInternalServerError)
+	}))
+	defer svr.Close()
+
+	c := NewUploadClient(UploadClientConfig{
+		SuiteToken:    "suite-token",
+		ServerBaseUrl: svr.URL,
+	})
+
+	err := c.UploadTestResults(context.Background(), UploadRunEnv{Key: "build/step"}, []UploadTestResult{{Name: "is red", Result: "passed"}})
+
+	if !errors.Is(err, ErrRetryTimeout) {
+		t.Errorf("UploadTestResults() error = %v, want %v", err, ErrRetryTimeout)
+	}
+}
@@ -1,5 +1,7 @@
 package config
 
+import "time"
+
 // Config is the internal representation of the complete test engine client configuration.
 type Config struct {
 	// AccessToken is the access token for the API.
@@ -10,14 +12,37 @@ type Config struct {
 	MaxRetries int
 	// RetryCommand is the command to run the retry tests.
 	RetryCommand string
+	// Mode is how the tests are distributed to the nodes, either "static" or "dynamic".
+	// In static mode, each node runs its task of the test plan.
+	// In dynamic mode, each node pulls batches of tests from a queue until it's drained.
+	Mode string
 	// Node index is index of the current node.
 	NodeIndex int
+	// PlanFile is the path to a test plan file to run instead of fetching the plan from the API.
+	PlanFile string
 	// OrganizationSlug is the slug of the organization.
 	OrganizationSlug string
 	// Parallelism is the number of parallel tasks to run.
 	Parallelism int
+	// PlanCacheDir is the directory to cache the test plan in, shared between nodes and retried jobs.
+	PlanCacheDir string
 	// The path to the result file.
 	ResultPath string
+	// JUnitReportPath is the path to write the JUnit XML report of the tests to, combining all attempts.
+	JUnitReportPath string
+	// JSONReportPath is the path to write the JSON report of the tests to, combining all attempts.
+	JSONReportPath string
+	// SummaryDir is the directory to write the summary of the run of this node to,
+	// the working directory when empty.
+	SummaryDir string
+	// Annotate is the flag to annotate the Buildkite build with the summary of the run.
+	Annotate bool
+	// UploadResults is the flag to upload the results of the tests to Test Engine.
+	UploadResults bool
+	// SuiteToken is the API token of the suite, used to upload the results of the tests.
+	SuiteToken string
+	// UploadBaseUrl is the base URL of the Test Engine upload API.
+	UploadBaseUrl string
 	// ServerBaseUrl is the base URL of the test plan server.
 	ServerBaseUrl string
 	// SplitByExample is the flag to enable split the test by example.
@@ -32,18 +57,53 @@ type Config struct {
 	TestFileExcludePattern string
 	// TestRunner is the name of the runner.
 	TestRunner string
-	// Branch is the string value of the git branch name, used by Buildkite only.
+	// Timeout is the maximum duration of a test command, zero means no limit.
+	Timeout time.Duration
+	// OutputTimeout is the maximum duration without output from a test command, zero means no limit.
+	OutputTimeout time.Duration
+	// TimeoutGracePeriod is how long to wait for a timed out test command to exit before killing it.
+	TimeoutGracePeriod time.Duration
+	// QuarantineFile is the path to a file listing the quarantined tests, one per line.
+	QuarantineFile string
+	// QuarantineFromAPI is the flag to fetch the quarantined tests from Test Engine.
+	QuarantineFromAPI bool
+	// FlakyPolicy is how a run whose failed tests all passed on retry exits,
+	// either "pass", "fail" or "soft-fail".
+	FlakyPolicy string
+	// Workers is the number of test runner processes to run at the same time on this node.
+	Workers int
+	// Branch is the string value of the git branch name.
 	Branch string
+	// CIProvider is the name of the detected CI provider, e.g. "buildkite" or "github_actions".
+	CIProvider string
+	// JobId is the ID of the CI job.
+	JobId string
 	// errs is a map of environment variables name and the validation errors associated with them.
 	errs InvalidConfigError
+	// overrides is a map of environment variables name and the values that take precedence over the environment.
+	overrides map[string]string
+	// file is a map of environment variables name and the values read from the config file,
+	// which are used when the environment variables are not set.
+	file map[string]string
+	// configFile is the path of the config file that has been read.
+	configFile string
+	// nodeIndexEnv is the environment variable that NodeIndex has been read from.
+	nodeIndexEnv string
+	// nodeIndexBase is the index of the first node in nodeIndexEnv, which is subtracted from NodeIndex.
+	nodeIndexBase int
+	// parallelismEnv is the environment variable that Parallelism has been read from.
+	parallelismEnv string
 }
 
 // New wraps the readFromEnv and validate functions to create a new Config struct.
+// The overrides are keyed by environment variable name and take precedence over
+// the environment, e.g. values given as command line flags. It can be nil.
 // It returns Config struct and an InvalidConfigError if there is an invalid configuration.
-func New() (Config, error) {
-	c := Config{errs: InvalidConfigError{}}
+func New(overrides map[string]string) (Config, error) {
+	c := Config{errs: InvalidConfigError{}, overrides: overrides}
 
-	// TODO: remove error from readFromEnv and validate functions
+	// TODO: remove error from readFromFile, readFromEnv and validate functions
+	_ = c.readFromFile()
 	_ = c.readFromEnv()
 	_ = c.validate()
 
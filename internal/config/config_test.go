@@ -4,6 +4,7 @@ import (
 	"errors"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
@@ -28,23 +29,29 @@ func TestNewConfig(t *testing.T) {
 	setEnv(t)
 	defer os.Clearenv()
 
-	c, err := New()
+	c, err := New(nil)
 	if err != nil {
 		t.Errorf("config.New() error = %v", err)
 	}
 
 	want := Config{
-		Parallelism:      60,
-		NodeIndex:        7,
-		ServerBaseUrl:    "https://build.kite",
-		Identifier:       "123/456",
-		TestCommand:      "bin/rspec {{testExamples}}",
-		AccessToken:      "my_token",
-		OrganizationSlug: "my_org",
-		ResultPath:       "tmp/rspec.json",
-		SuiteSlug:        "my_suite",
-		TestRunner:       "rspec",
-		errs:             InvalidConfigError{},
+		Parallelism:        60,
+		NodeIndex:          7,
+		Mode:               "static",
+		ServerBaseUrl:      "https://build.kite",
+		Identifier:         "123/456",
+		CIProvider:         "buildkite",
+		Workers:            1,
+		FlakyPolicy:        "pass",
+		UploadBaseUrl:      "https://analytics-api.buildkite.com",
+		TimeoutGracePeriod: 10 * time.Second,
+		TestCommand:        "bin/rspec {{testExamples}}",
+		AccessToken:        "my_token",
+		OrganizationSlug:   "my_org",
+		ResultPath:         "tmp/rspec.json",
+		SuiteSlug:          "my_suite",
+		TestRunner:         "rspec",
+		errs:               InvalidConfigError{},
 	}
 
 	if diff := cmp.Diff(c, want, cmpopts.IgnoreUnexported(Config{})); diff != "" {
@@ -55,7 +62,7 @@ func TestNewConfig(t *testing.T) {
 func TestNewConfig_EmptyConfig(t *testing.T) {
 	os.Clearenv()
 
-	_, err := New()
+	_, err := New(nil)
 
 	if !errors.As(err, new(InvalidConfigError)) {
 		t.Errorf("config.Validate() error = %v, want InvalidConfigError", err)
@@ -69,21 +76,27 @@ func TestNewConfig_MissingConfigWithDefault(t *testing.T) {
 	os.Unsetenv("BUILDKITE_TEST_ENGINE_TEST_CMD")
 	defer os.Clearenv()
 
-	c, err := New()
+	c, err := New(nil)
 	if err != nil {
 		t.Errorf("config.New() error = %v", err)
 	}
 
 	want := Config{
-		Parallelism:      60,
-		NodeIndex:        7,
-		ServerBaseUrl:    "https://api.buildkite.com",
-		Identifier:       "123/456",
-		AccessToken:      "my_token",
-		OrganizationSlug: "my_org",
-		SuiteSlug:        "my_suite",
-		TestRunner:       "rspec",
-		ResultPath:       "tmp/rspec.json",
+		Parallelism:        60,
+		NodeIndex:          7,
+		Mode:               "static",
+		ServerBaseUrl:      "https://api.buildkite.com",
+		Identifier:         "123/456",
+		CIProvider:         "buildkite",
+		Workers:            1,
+		FlakyPolicy:        "pass",
+		UploadBaseUrl:      "https://analytics-api.buildkite.com",
+		TimeoutGracePeriod: 10 * time.Second,
+		AccessToken:        "my_token",
+		OrganizationSlug:   "my_org",
+		SuiteSlug:          "my_suite",
+		TestRunner:         "rspec",
+		ResultPath:         "tmp/rspec.json",
 	}
 
 	if diff := cmp.Diff(c, want, cmpopts.IgnoreUnexported(Config{})); diff != "" {
@@ -97,7 +110,7 @@ func TestNewConfig_InvalidConfig(t *testing.T) {
 	os.Unsetenv("BUILDKITE_TEST_ENGINE_API_ACCESS_TOKEN")
 	defer os.Clearenv()
 
-	_, err := New()
+	_, err := New(nil)
 
 	var invConfigError InvalidConfigError
 	if !errors.As(err, &invConfigError) {
@@ -108,3 +121,76 @@ func TestNewConfig_InvalidConfig(t *testing.T) {
 		t.Errorf("config.readFromEnv() error length = %d, want 2", len(invConfigError))
 	}
 }
+
+func TestNewConfig_Overrides(t *testing.T) {
+	setEnv(t)
+	defer os.Clearenv()
+
+	c, err := New(map[string]string{
+		"BUILDKITE_PARALLEL_JOB":            "3",
+		"BUILDKITE_TEST_ENGINE_RESULT_PATH": "tmp/override.json",
+	})
+	if err != nil {
+		t.Errorf("config.New() error = %v", err)
+	}
+
+	want := Config{
+		Parallelism:        60,
+		NodeIndex:          3,
+		Mode:               "static",
+		ServerBaseUrl:      "https://build.kite",
+		Identifier:         "123/456",
+		CIProvider:         "buildkite",
+		Workers:            1,
+		FlakyPolicy:        "pass",
+		UploadBaseUrl:      "https://analytics-api.buildkite.com",
+		TimeoutGracePeriod: 10 * time.Second,
+		TestCommand:        "bin/rspec {{testExamples}}",
+		AccessToken:        "my_token",
+		OrganizationSlug:   "my_org",
+		ResultPath:         "tmp/override.json",
+		SuiteSlug:          "my_suite",
+		TestRunner:         "rspec",
+	}
+
+	if diff := cmp.Diff(c, want, cmpopts.IgnoreUnexported(Config{})); diff != "" {
+		t.Errorf("config.New() diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestNewConfig_PlanFile(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("BUILDKITE_PARALLEL_JOB_COUNT", "2")
+	os.Setenv("BUILDKITE_PARALLEL_JOB", "1")
+	os.Setenv("BUILDKITE_TEST_ENGINE_TEST_RUNNER", "rspec")
+	os.Setenv("BUILDKITE_TEST_ENGINE_RESULT_PATH", "tmp/rspec.json")
+	defer os.Clearenv()
+
+	// API access token, organization, suite, build and step are not required when running a plan file.
+	c, err := New(map[string]string{
+		"BUILDKITE_TEST_ENGINE_PLAN_FILE": "plan.json",
+	})
+	if err != nil {
+		t.Errorf("config.New() error = %v", err)
+	}
+
+	want := Config{
+		Parallelism:        2,
+		NodeIndex:          1,
+		Mode:               "static",
+		ServerBaseUrl:      "https://api.buildkite.com",
+		Identifier:         "/",
+		CIProvider:         "buildkite",
+		Workers:            1,
+		FlakyPolicy:        "pass",
+		UploadBaseUrl:      "https://analytics-api.buildkite.com",
+		TimeoutGracePeriod: 10 * time.Second,
+		PlanFile:           "plan.json",
+		ResultPath:         "tmp/rspec.json",
+		TestRunner:         "rspec",
+	}
+
+	if diff := cmp.Diff(c, want, cmpopts.IgnoreUnexported(Config{})); diff != "" {
+		t.Errorf("config.New() diff (-got +want):\n%s", diff)
+	}
+}
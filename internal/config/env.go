@@ -3,24 +3,35 @@ package config
 import (
 	"os"
 	"strconv"
+	"time"
 )
 
+// getEnv retrieves the value of the environment variable named by the key.
+// If the key is overridden, the overridden value is returned instead.
+// If the environment variable is not set, the value from the config file is returned.
+func (c Config) getEnv(key string) string {
+	if value, ok := c.overrides[key]; ok {
+		return value
+	}
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return c.file[key]
+}
+
 // getEnvWithDefault retrieves the value of the environment variable named by the key.
 // If the variable is present and not empty, the value is returned.
 // Otherwise the returned value will be the default value.
-func getEnvWithDefault(key string, defaultValue string) string {
-	value, ok := os.LookupEnv(key)
-	if !ok {
-		return defaultValue
-	}
+func (c Config) getEnvWithDefault(key string, defaultValue string) string {
+	value := c.getEnv(key)
 	if value == "" {
 		return defaultValue
 	}
 	return value
 }
 
-func getIntEnvWithDefault(key string, defaultValue int) (int, error) {
-	value := os.Getenv(key)
+func (c Config) getIntEnvWithDefault(key string, defaultValue int) (int, error) {
+	value := c.getEnv(key)
 	// If the environment variable is not set, return the default value.
 	if value == "" {
 		return defaultValue, nil
@@ -34,29 +45,59 @@ func getIntEnvWithDefault(key string, defaultValue int) (int, error) {
 	return valueInt, nil
 }
 
+// getDurationEnvWithDefault retrieves the value of the environment variable named by the key
+// as a duration such as "30m" or "90s". If the variable is not set, the default value is returned.
+func (c Config) getDurationEnvWithDefault(key string, defaultValue time.Duration) (time.Duration, error) {
+	value := c.getEnv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	return time.ParseDuration(value)
+}
+
+// DumpEnv returns the environment variables of the configuration, including the
+// variables of the detected CI provider, which are sent to Test Engine as the metadata of the test plan.
 func (c Config) DumpEnv() map[string]string {
 	keys := []string{
-		"BUILDKITE_BUILD_ID",
-		"BUILDKITE_JOB_ID",
 		"BUILDKITE_ORGANIZATION_SLUG",
-		"BUILDKITE_PARALLEL_JOB_COUNT",
-		"BUILDKITE_PARALLEL_JOB",
+		"BUILDKITE_TEST_ENGINE_ANNOTATE",
+		"BUILDKITE_TEST_ENGINE_BRANCH",
+		"BUILDKITE_TEST_ENGINE_BUILD_ID",
+		"BUILDKITE_TEST_ENGINE_CONFIG_FILE",
 		"BUILDKITE_TEST_ENGINE_DEBUG_ENABLED",
+		"BUILDKITE_TEST_ENGINE_FLAKY_POLICY",
+		"BUILDKITE_TEST_ENGINE_JSON_REPORT_PATH",
+		"BUILDKITE_TEST_ENGINE_JUNIT_REPORT_PATH",
+		"BUILDKITE_TEST_ENGINE_MODE",
+		"BUILDKITE_TEST_ENGINE_OUTPUT_TIMEOUT",
+		"BUILDKITE_TEST_ENGINE_NODE_INDEX",
+		"BUILDKITE_TEST_ENGINE_PARALLELISM",
+		"BUILDKITE_TEST_ENGINE_PLAN_CACHE_DIR",
+		"BUILDKITE_TEST_ENGINE_PLAN_FILE",
+		"BUILDKITE_TEST_ENGINE_QUARANTINE_FILE",
+		"BUILDKITE_TEST_ENGINE_QUARANTINE_FROM_API",
 		"BUILDKITE_TEST_ENGINE_RETRY_COUNT",
 		"BUILDKITE_TEST_ENGINE_RETRY_CMD",
 		"BUILDKITE_TEST_ENGINE_SPLIT_BY_EXAMPLE",
+		"BUILDKITE_TEST_ENGINE_STEP_ID",
 		"BUILDKITE_TEST_ENGINE_SUITE_SLUG",
+		"BUILDKITE_TEST_ENGINE_SUMMARY_DIR",
 		"BUILDKITE_TEST_ENGINE_TEST_CMD",
 		"BUILDKITE_TEST_ENGINE_TEST_FILE_EXCLUDE_PATTERN",
 		"BUILDKITE_TEST_ENGINE_TEST_FILE_PATTERN",
 		"BUILDKITE_TEST_ENGINE_TEST_RUNNER",
-		"BUILDKITE_STEP_ID",
-		"BUILDKITE_BRANCH",
+		"BUILDKITE_TEST_ENGINE_TIMEOUT",
+		"BUILDKITE_TEST_ENGINE_TIMEOUT_GRACE_PERIOD",
+		"BUILDKITE_TEST_ENGINE_UPLOAD_RESULTS",
+		"BUILDKITE_TEST_ENGINE_WORKERS",
 	}
 
+	provider := detectCIProvider()
+	keys = append(keys, provider.envs()...)
+
 	envs := make(map[string]string)
 	for _, key := range keys {
-		envs[key] = os.Getenv(key)
+		envs[key] = c.getEnv(key)
 	}
 
 	envs["BUILDKITE_TEST_ENGINE_IDENTIFIER"] = c.Identifier
@@ -51,7 +51,7 @@ func TestGetIntEnvWithDefault(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.key, func(t *testing.T) {
-			got, err := getIntEnvWithDefault(tt.key, tt.defaultValue)
+			got, err := Config{}.getIntEnvWithDefault(tt.key, tt.defaultValue)
 			if err != nil && !errors.Is(err, tt.err) {
 				t.Errorf("getIntEnvWithDefault(%q, %d) error = %v, want %v", tt.key, tt.defaultValue, err, tt.err)
 			}
@@ -101,7 +101,7 @@ func TestGetEnvWithDefault(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.key, func(t *testing.T) {
-			if got := getEnvWithDefault(tt.key, tt.defaultValue); got != tt.want {
+			if got := (Config{}).getEnvWithDefault(tt.key, tt.defaultValue); got != tt.want {
 				t.Errorf("getEnvWithDefault(%q, %q) = %q, want %q", tt.key, tt.defaultValue, got, tt.want)
 			}
 		})
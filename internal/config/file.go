@@ -0,0 +1,142 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultConfigFile is the config file read from the working directory when
+// BUILDKITE_TEST_ENGINE_CONFIG_FILE is not set.
+const defaultConfigFile = ".bktec.yml"
+
+// fileKeys maps the keys of the config file to the environment variables they set.
+var fileKeys = map[string]string{
+	"annotate":                  "BUILDKITE_TEST_ENGINE_ANNOTATE",
+	"base_url":                  "BUILDKITE_TEST_ENGINE_BASE_URL",
+	"flaky_policy":              "BUILDKITE_TEST_ENGINE_FLAKY_POLICY",
+	"json_report_path":          "BUILDKITE_TEST_ENGINE_JSON_REPORT_PATH",
+	"junit_report_path":         "BUILDKITE_TEST_ENGINE_JUNIT_REPORT_PATH",
+	"mode":                      "BUILDKITE_TEST_ENGINE_MODE",
+	"output_timeout":            "BUILDKITE_TEST_ENGINE_OUTPUT_TIMEOUT",
+	"organization_slug":         "BUILDKITE_ORGANIZATION_SLUG",
+	"plan_cache_dir":            "BUILDKITE_TEST_ENGINE_PLAN_CACHE_DIR",
+	"quarantine_file":           "BUILDKITE_TEST_ENGINE_QUARANTINE_FILE",
+	"quarantine_from_api":       "BUILDKITE_TEST_ENGINE_QUARANTINE_FROM_API",
+	"result_path":               "BUILDKITE_TEST_ENGINE_RESULT_PATH",
+	"retry_cmd":                 "BUILDKITE_TEST_ENGINE_RETRY_CMD",
+	"retry_count":               "BUILDKITE_TEST_ENGINE_RETRY_COUNT",
+	"split_by_example":          "BUILDKITE_TEST_ENGINE_SPLIT_BY_EXAMPLE",
+	"suite_slug":                "BUILDKITE_TEST_ENGINE_SUITE_SLUG",
+	"summary_dir":               "BUILDKITE_TEST_ENGINE_SUMMARY_DIR",
+	"test_cmd":                  "BUILDKITE_TEST_ENGINE_TEST_CMD",
+	"test_file_exclude_pattern": "BUILDKITE_TEST_ENGINE_TEST_FILE_EXCLUDE_PATTERN",
+	"test_file_pattern":         "BUILDKITE_TEST_ENGINE_TEST_FILE_PATTERN",
+	"test_runner":               "BUILDKITE_TEST_ENGINE_TEST_RUNNER",
+	"timeout":                   "BUILDKITE_TEST_ENGINE_TIMEOUT",
+	"timeout_grace_period":      "BUILDKITE_TEST_ENGINE_TIMEOUT_GRACE_PERIOD",
+	"upload_base_url":           "BUILDKITE_TEST_ENGINE_UPLOAD_BASE_URL",
+	"upload_results":            "BUILDKITE_TEST_ENGINE_UPLOAD_RESULTS",
+	"workers":                   "BUILDKITE_TEST_ENGINE_WORKERS",
+}
+
+// readFromFile reads the YAML config file and stores the values keyed by environment variable name,
+// so they can be used when the environment variable is not set. For example:
+//
+//	test_runner: rspec
+//	test_cmd: >
+//	  bundle exec rspec
+//	  --format json --out {{resultPath}}
+//	  {{testExamples}}
+//	retry_count: 2
+//
+// The file is read from BUILDKITE_TEST_ENGINE_CONFIG_FILE, or from .bktec.yml in the working directory.
+// It's not an error if .bktec.yml doesn't exist, but it is if BUILDKITE_TEST_ENGINE_CONFIG_FILE doesn't.
+func (c *Config) readFromFile() error {
+	path := c.getEnv("BUILDKITE_TEST_ENGINE_CONFIG_FILE")
+	required := path != ""
+	if !required {
+		path = defaultConfigFile
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) && !required {
+		return nil
+	}
+	if err != nil {
+		c.errs.appendFieldError("BUILDKITE_TEST_ENGINE_CONFIG_FILE", "was %q, %v", path, err)
+		return c.errs
+	}
+
+	var values map[string]any
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		c.errs.appendFieldError(path, "is not a valid YAML file: %v", err)
+		return c.errs
+	}
+
+	c.configFile = path
+	c.file = map[string]string{}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		envName, ok := fileKeys[key]
+		if !ok {
+			c.errs.appendFieldError(path, "has unknown key %q", key)
+			continue
+		}
+
+		switch value := values[key].(type) {
+		case string, int, bool:
+			c.file[envName] = fmt.Sprint(value)
+		case nil:
+			// an empty value is the same as not setting the key
+		default:
+			c.errs.appendFieldError(path, "has invalid value for key %q, must be a string, number or boolean", key)
+		}
+	}
+
+	if len(c.errs) > 0 {
+		return c.errs
+	}
+	return nil
+}
+
+// fileKey returns the config file key of the environment variable,
+// if the value of the environment variable is read from the config file.
+func (c Config) fileKey(envName string) (string, bool) {
+	if _, ok := c.file[envName]; !ok {
+		return "", false
+	}
+	if _, ok := c.overrides[envName]; ok {
+		return "", false
+	}
+	if os.Getenv(envName) != "" {
+		return "", false
+	}
+
+	for key, name := range fileKeys {
+		if name == envName {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// appendFieldError appends a validation error for the environment variable.
+// If the value is read from the config file, the error also points at the file and key.
+func (c *Config) appendFieldError(envName, format string, v ...any) {
+	if key, ok := c.fileKey(envName); ok {
+		format = "(%s in %s) " + format
+		v = append([]any{key, c.configFile}, v...)
+	}
+	c.errs.appendFieldError(envName, format, v...)
+}
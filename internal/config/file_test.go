@@ -0,0 +1,127 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), ".bktec.yml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestNewConfig_ConfigFile(t *testing.T) {
+	setEnv(t)
+	os.Unsetenv("BUILDKITE_TEST_ENGINE_TEST_CMD")
+	os.Unsetenv("BUILDKITE_TEST_ENGINE_TEST_RUNNER")
+	os.Setenv("BUILDKITE_TEST_ENGINE_RESULT_PATH", "tmp/env.json")
+	os.Setenv("BUILDKITE_TEST_ENGINE_CONFIG_FILE", writeConfigFile(t, `
+test_runner: rspec
+test_cmd: >
+  bin/rspec
+  --format json --out {{resultPath}}
+  {{testExamples}}
+test_file_pattern: "spec/**/*_spec.rb"
+result_path: tmp/file.json
+retry_count: 2
+split_by_example: true
+`))
+	defer os.Clearenv()
+
+	c, err := New(nil)
+	if err != nil {
+		t.Errorf("config.New() error = %v", err)
+	}
+
+	// environment variables take precedence over the config file
+	want := Config{
+		Parallelism:        60,
+		NodeIndex:          7,
+		Mode:               "static",
+		ServerBaseUrl:      "https://build.kite",
+		Identifier:         "123/456",
+		CIProvider:         "buildkite",
+		Workers:            1,
+		FlakyPolicy:        "pass",
+		UploadBaseUrl:      "https://analytics-api.buildkite.com",
+		TimeoutGracePeriod: 10 * time.Second,
+		TestCommand:        "bin/rspec --format json --out {{resultPath}} {{testExamples}}\n",
+		TestFilePattern:    "spec/**/*_spec.rb",
+		AccessToken:        "my_token",
+		OrganizationSlug:   "my_org",
+		ResultPath:         "tmp/env.json",
+		MaxRetries:         2,
+		SplitByExample:     true,
+		SuiteSlug:          "my_suite",
+		TestRunner:         "rspec",
+	}
+
+	if diff := cmp.Diff(c, want, cmpopts.IgnoreUnexported(Config{})); diff != "" {
+		t.Errorf("config.New() diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestNewConfig_ConfigFileInvalidValue(t *testing.T) {
+	setEnv(t)
+	path := writeConfigFile(t, `retry_count: many`)
+	os.Setenv("BUILDKITE_TEST_ENGINE_CONFIG_FILE", path)
+	defer os.Clearenv()
+
+	_, err := New(nil)
+
+	var invConfigError InvalidConfigError
+	if !errors.As(err, &invConfigError) {
+		t.Errorf("config.New() error = %v, want InvalidConfigError", err)
+	}
+
+	want := `BUILDKITE_TEST_ENGINE_RETRY_COUNT (retry_count in ` + path + `) was "many", must be a number`
+	if got := invConfigError.Error(); got != want {
+		t.Errorf("config.New() error = %q, want %q", got, want)
+	}
+}
+
+func TestNewConfig_ConfigFileUnknownKey(t *testing.T) {
+	setEnv(t)
+	path := writeConfigFile(t, `test_command: bin/rspec`)
+	os.Setenv("BUILDKITE_TEST_ENGINE_CONFIG_FILE", path)
+	defer os.Clearenv()
+
+	_, err := New(nil)
+
+	var invConfigError InvalidConfigError
+	if !errors.As(err, &invConfigError) {
+		t.Errorf("config.New() error = %v, want InvalidConfigError", err)
+	}
+
+	want := path + ` has unknown key "test_command"`
+	if got := invConfigError.Error(); got != want {
+		t.Errorf("config.New() error = %q, want %q", got, want)
+	}
+}
+
+func TestNewConfig_ConfigFileNotFound(t *testing.T) {
+	setEnv(t)
+	os.Setenv("BUILDKITE_TEST_ENGINE_CONFIG_FILE", "missing.yml")
+	defer os.Clearenv()
+
+	_, err := New(nil)
+
+	var invConfigError InvalidConfigError
+	if !errors.As(err, &invConfigError) {
+		t.Errorf("config.New() error = %v, want InvalidConfigError", err)
+	}
+
+	if len(invConfigError["BUILDKITE_TEST_ENGINE_CONFIG_FILE"]) != 1 {
+		t.Errorf("config.New() error = %v, want error for BUILDKITE_TEST_ENGINE_CONFIG_FILE", err)
+	}
+}
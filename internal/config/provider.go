@@ -0,0 +1,117 @@
+package config
+
+import (
+	"os"
+	"regexp"
+	"strings"
+)
+
+// ciProvider describes where the build identity and the parallelism are read from
+// in the environment of a CI system.
+type ciProvider struct {
+	// name is the name of the CI provider, set to Config.CIProvider.
+	name string
+	// detectEnv is the environment variable that the CI system sets to "true".
+	detectEnv string
+	// identifierEnvs are the environment variables that are joined with "/" to identify the test plan.
+	// All nodes running the same tests must have the same identifier.
+	identifierEnvs []string
+	// nodeIndexEnv is the environment variable of the index of the node, starting at nodeIndexBase.
+	// It's empty if the CI system doesn't provide one, in which case it must be supplied with
+	// BUILDKITE_TEST_ENGINE_NODE_INDEX.
+	nodeIndexEnv string
+	// parallelismEnv is the environment variable of the number of nodes.
+	// It's empty if the CI system doesn't provide one, in which case it must be supplied with
+	// BUILDKITE_TEST_ENGINE_PARALLELISM.
+	parallelismEnv string
+	// nodeIndexBase is the index of the first node in nodeIndexEnv, e.g. 1 when the index is one-based.
+	nodeIndexBase int
+	// missingNodeEnvReason explains why nodeIndexEnv and parallelismEnv may be missing, if they
+	// are only set for some jobs. It's part of the error reported when they are missing.
+	missingNodeEnvReason string
+	// identifierSuffix matches the part of the identifier values that differs between the nodes, if any,
+	// which is removed so that all nodes have the same identifier.
+	identifierSuffix *regexp.Regexp
+	// jobIdEnv is the environment variable of the ID of the job.
+	jobIdEnv string
+	// branchEnvs are the environment variables of the git branch, the first one that is set is used.
+	branchEnvs []string
+}
+
+// buildkite is the default CI provider.
+var buildkite = ciProvider{
+	name:           "buildkite",
+	detectEnv:      "BUILDKITE",
+	identifierEnvs: []string{"BUILDKITE_BUILD_ID", "BUILDKITE_STEP_ID"},
+	nodeIndexEnv:   "BUILDKITE_PARALLEL_JOB",
+	parallelismEnv: "BUILDKITE_PARALLEL_JOB_COUNT",
+	jobIdEnv:       "BUILDKITE_JOB_ID",
+	branchEnvs:     []string{"BUILDKITE_BRANCH"},
+}
+
+// githubActions doesn't provide the index and the total of a matrix job in the environment,
+// so they are supplied by the user, e.g. from ${{ strategy.job-index }} and ${{ strategy.job-total }}.
+// The run attempt is part of the identifier so that re-running the workflow creates a new test plan.
+var githubActions = ciProvider{
+	name:           "github_actions",
+	detectEnv:      "GITHUB_ACTIONS",
+	identifierEnvs: []string{"GITHUB_RUN_ID", "GITHUB_RUN_ATTEMPT", "GITHUB_JOB"},
+	jobIdEnv:       "GITHUB_JOB",
+	branchEnvs:     []string{"GITHUB_HEAD_REF", "GITHUB_REF_NAME"},
+}
+
+// gitlab names the parallel jobs after the job with the one-based index and total appended,
+// e.g. "rspec 1/4", so the suffix is removed from CI_JOB_NAME to identify the test plan.
+var gitlab = ciProvider{
+	name:             "gitlab",
+	detectEnv:        "GITLAB_CI",
+	identifierEnvs:   []string{"CI_PIPELINE_ID", "CI_JOB_NAME"},
+	nodeIndexEnv:     "CI_NODE_INDEX",
+	parallelismEnv:   "CI_NODE_TOTAL",
+	nodeIndexBase:    1,
+	identifierSuffix: regexp.MustCompile(` \d+/\d+$`),
+	jobIdEnv:         "CI_JOB_ID",
+	branchEnvs:       []string{"CI_MERGE_REQUEST_SOURCE_BRANCH_NAME", "CI_COMMIT_REF_NAME"},
+	// CI_NODE_INDEX and CI_NODE_TOTAL are only set for the jobs with the parallel keyword.
+	missingNodeEnvReason: "GitLab CI only sets it for jobs with `parallel:`",
+}
+
+var circleCI = ciProvider{
+	name:           "circleci",
+	detectEnv:      "CIRCLECI",
+	identifierEnvs: []string{"CIRCLE_WORKFLOW_ID", "CIRCLE_JOB"},
+	nodeIndexEnv:   "CIRCLE_NODE_INDEX",
+	parallelismEnv: "CIRCLE_NODE_TOTAL",
+	jobIdEnv:       "CIRCLE_WORKFLOW_JOB_ID",
+	branchEnvs:     []string{"CIRCLE_BRANCH"},
+}
+
+// ciProviders are the supported CI providers, in order of detection.
+var ciProviders = []ciProvider{
+	buildkite,
+	githubActions,
+	gitlab,
+	circleCI,
+}
+
+// detectCIProvider returns the CI provider whose detection environment variable is set,
+// or Buildkite if none is set.
+func detectCIProvider() ciProvider {
+	for _, p := range ciProviders {
+		if strings.ToLower(os.Getenv(p.detectEnv)) == "true" {
+			return p
+		}
+	}
+	return buildkite
+}
+
+// envs returns the environment variables the CI provider is read from.
+func (p ciProvider) envs() []string {
+	envs := append([]string{}, p.identifierEnvs...)
+	for _, env := range []string{p.nodeIndexEnv, p.parallelismEnv, p.jobIdEnv} {
+		if env != "" {
+			envs = append(envs, env)
+		}
+	}
+	return append(envs, p.branchEnvs...)
+}
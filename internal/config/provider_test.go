@@ -0,0 +1,331 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewConfig_GitHubActions(t *testing.T) {
+	os.Setenv("GITHUB_ACTIONS", "true")
+	os.Setenv("GITHUB_RUN_ID", "9876")
+	os.Setenv("GITHUB_RUN_ATTEMPT", "2")
+	os.Setenv("GITHUB_JOB", "rspec")
+	os.Setenv("GITHUB_HEAD_REF", "")
+	os.Setenv("GITHUB_REF_NAME", "main")
+	os.Setenv("BUILDKITE_TEST_ENGINE_NODE_INDEX", "1")
+	os.Setenv("BUILDKITE_TEST_ENGINE_PARALLELISM", "4")
+	os.Setenv("BUILDKITE_TEST_ENGINE_API_ACCESS_TOKEN", "my_token")
+	os.Setenv("BUILDKITE_ORGANIZATION_SLUG", "my_org")
+	os.Setenv("BUILDKITE_TEST_ENGINE_SUITE_SLUG", "my_suite")
+	os.Setenv("BUILDKITE_TEST_ENGINE_TEST_RUNNER", "rspec")
+	os.Setenv("BUILDKITE_TEST_ENGINE_RESULT_PATH", "tmp/rspec.json")
+	defer os.Clearenv()
+
+	c, err := New(nil)
+	if err != nil {
+		t.Fatalf("config.New() error = %v", err)
+	}
+
+	if c.CIProvider != "github_actions" {
+		t.Errorf("CIProvider = %q, want %q", c.CIProvider, "github_actions")
+	}
+
+	if c.Identifier != "9876/2/rspec" {
+		t.Errorf("Identifier = %q, want %q", c.Identifier, "9876/2/rspec")
+	}
+
+	if c.NodeIndex != 1 {
+		t.Errorf("NodeIndex = %d, want %d", c.NodeIndex, 1)
+	}
+
+	if c.Parallelism != 4 {
+		t.Errorf("Parallelism = %d, want %d", c.Parallelism, 4)
+	}
+
+	if c.JobId != "rspec" {
+		t.Errorf("JobId = %q, want %q", c.JobId, "rspec")
+	}
+
+	if c.Branch != "main" {
+		t.Errorf("Branch = %q, want %q", c.Branch, "main")
+	}
+
+	env := c.DumpEnv()
+	for _, key := range []string{"GITHUB_RUN_ID", "GITHUB_RUN_ATTEMPT", "BUILDKITE_TEST_ENGINE_NODE_INDEX"} {
+		if _, ok := env[key]; !ok {
+			t.Errorf("DumpEnv() is missing %s", key)
+		}
+	}
+	if _, ok := env["BUILDKITE_BUILD_ID"]; ok {
+		t.Errorf("DumpEnv() has BUILDKITE_BUILD_ID, want only the GitHub Actions variables")
+	}
+}
+
+func TestNewConfig_GitHubActionsBuildAndStepOverrides(t *testing.T) {
+	os.Setenv("GITHUB_ACTIONS", "true")
+	os.Setenv("GITHUB_RUN_ID", "9876")
+	os.Setenv("GITHUB_RUN_ATTEMPT", "2")
+	os.Setenv("GITHUB_JOB", "rspec")
+	os.Setenv("GITHUB_REF_NAME", "main")
+	os.Setenv("BUILDKITE_TEST_ENGINE_NODE_INDEX", "1")
+	os.Setenv("BUILDKITE_TEST_ENGINE_PARALLELISM", "4")
+	os.Setenv("BUILDKITE_TEST_ENGINE_API_ACCESS_TOKEN", "my_token")
+	os.Setenv("BUILDKITE_ORGANIZATION_SLUG", "my_org")
+	os.Setenv("BUILDKITE_TEST_ENGINE_SUITE_SLUG", "my_suite")
+	os.Setenv("BUILDKITE_TEST_ENGINE_TEST_RUNNER", "rspec")
+	os.Setenv("BUILDKITE_TEST_ENGINE_RESULT_PATH", "tmp/rspec.json")
+	defer os.Clearenv()
+
+	c, err := New(map[string]string{
+		"BUILDKITE_TEST_ENGINE_BUILD_ID": "build",
+		"BUILDKITE_TEST_ENGINE_STEP_ID":  "step",
+		"BUILDKITE_TEST_ENGINE_BRANCH":   "feature",
+	})
+	if err != nil {
+		t.Fatalf("config.New() error = %v", err)
+	}
+
+	if c.Identifier != "build/step" {
+		t.Errorf("Identifier = %q, want %q", c.Identifier, "build/step")
+	}
+
+	if c.Branch != "feature" {
+		t.Errorf("Branch = %q, want %q", c.Branch, "feature")
+	}
+}
+
+func TestNewConfig_BuildIdWithoutStepId(t *testing.T) {
+	os.Setenv("GITHUB_ACTIONS", "true")
+	os.Setenv("GITHUB_RUN_ID", "9876")
+	os.Setenv("GITHUB_RUN_ATTEMPT", "2")
+	os.Setenv("GITHUB_JOB", "rspec")
+	os.Setenv("BUILDKITE_TEST_ENGINE_NODE_INDEX", "1")
+	os.Setenv("BUILDKITE_TEST_ENGINE_PARALLELISM", "4")
+	os.Setenv("BUILDKITE_TEST_ENGINE_API_ACCESS_TOKEN", "my_token")
+	os.Setenv("BUILDKITE_ORGANIZATION_SLUG", "my_org")
+	os.Setenv("BUILDKITE_TEST_ENGINE_SUITE_SLUG", "my_suite")
+	os.Setenv("BUILDKITE_TEST_ENGINE_TEST_RUNNER", "rspec")
+	os.Setenv("BUILDKITE_TEST_ENGINE_RESULT_PATH", "tmp/rspec.json")
+	os.Setenv("BUILDKITE_TEST_ENGINE_BUILD_ID", "build")
+	defer os.Clearenv()
+
+	_, err := New(nil)
+
+	var invConfigError InvalidConfigError
+	if !errors.As(err, &invConfigError) {
+		t.Fatalf("config.New() error = %v, want InvalidConfigError", err)
+	}
+
+	if _, ok := invConfigError["BUILDKITE_TEST_ENGINE_STEP_ID"]; !ok || len(invConfigError) != 1 {
+		t.Errorf("config.New() error = %v, want an error for BUILDKITE_TEST_ENGINE_STEP_ID", err)
+	}
+}
+
+func TestNewConfig_GitHubActionsMissingMatrix(t *testing.T) {
+	os.Setenv("GITHUB_ACTIONS", "true")
+	os.Setenv("GITHUB_RUN_ID", "9876")
+	os.Setenv("GITHUB_RUN_ATTEMPT", "1")
+	os.Setenv("GITHUB_JOB", "rspec")
+	os.Setenv("BUILDKITE_TEST_ENGINE_API_ACCESS_TOKEN", "my_token")
+	os.Setenv("BUILDKITE_ORGANIZATION_SLUG", "my_org")
+	os.Setenv("BUILDKITE_TEST_ENGINE_SUITE_SLUG", "my_suite")
+	os.Setenv("BUILDKITE_TEST_ENGINE_TEST_RUNNER", "rspec")
+	os.Setenv("BUILDKITE_TEST_ENGINE_RESULT_PATH", "tmp/rspec.json")
+	defer os.Clearenv()
+
+	_, err := New(nil)
+
+	var invConfigError InvalidConfigError
+	if !errors.As(err, &invConfigError) {
+		t.Fatalf("config.New() error = %v, want InvalidConfigError", err)
+	}
+
+	want := `BUILDKITE_TEST_ENGINE_NODE_INDEX was "", must be a number
+BUILDKITE_TEST_ENGINE_PARALLELISM was "", must be a number`
+
+	if diff := cmp.Diff(invConfigError.Error(), want); diff != "" {
+		t.Errorf("config.New() error diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestNewConfig_NodeIndexOverridesBuildkite(t *testing.T) {
+	setEnv(t)
+	os.Setenv("BUILDKITE_TEST_ENGINE_NODE_INDEX", "70")
+	defer os.Clearenv()
+
+	_, err := New(nil)
+
+	var invConfigError InvalidConfigError
+	if !errors.As(err, &invConfigError) {
+		t.Fatalf("config.New() error = %v, want InvalidConfigError", err)
+	}
+
+	want := "BUILDKITE_TEST_ENGINE_NODE_INDEX was 70, must not be greater than 59"
+
+	if diff := cmp.Diff(invConfigError.Error(), want); diff != "" {
+		t.Errorf("config.New() error diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestNewConfig_GitLab(t *testing.T) {
+	os.Setenv("GITLAB_CI", "true")
+	os.Setenv("CI_PIPELINE_ID", "555")
+	os.Setenv("CI_JOB_NAME", "rspec 4/4")
+	os.Setenv("CI_JOB_ID", "777")
+	os.Setenv("CI_NODE_INDEX", "4")
+	os.Setenv("CI_NODE_TOTAL", "4")
+	os.Setenv("CI_COMMIT_REF_NAME", "main")
+	os.Setenv("BUILDKITE_TEST_ENGINE_API_ACCESS_TOKEN", "my_token")
+	os.Setenv("BUILDKITE_ORGANIZATION_SLUG", "my_org")
+	os.Setenv("BUILDKITE_TEST_ENGINE_SUITE_SLUG", "my_suite")
+	os.Setenv("BUILDKITE_TEST_ENGINE_TEST_RUNNER", "rspec")
+	os.Setenv("BUILDKITE_TEST_ENGINE_RESULT_PATH", "tmp/rspec.json")
+	defer os.Clearenv()
+
+	c, err := New(nil)
+	if err != nil {
+		t.Fatalf("config.New() error = %v", err)
+	}
+
+	if c.CIProvider != "gitlab" {
+		t.Errorf("CIProvider = %q, want %q", c.CIProvider, "gitlab")
+	}
+
+	if c.Identifier != "555/rspec" {
+		t.Errorf("Identifier = %q, want %q", c.Identifier, "555/rspec")
+	}
+
+	// CI_NODE_INDEX is one-based
+	if c.NodeIndex != 3 {
+		t.Errorf("NodeIndex = %d, want %d", c.NodeIndex, 3)
+	}
+
+	if c.Parallelism != 4 {
+		t.Errorf("Parallelism = %d, want %d", c.Parallelism, 4)
+	}
+
+	if c.JobId != "777" {
+		t.Errorf("JobId = %q, want %q", c.JobId, "777")
+	}
+
+	if c.Branch != "main" {
+		t.Errorf("Branch = %q, want %q", c.Branch, "main")
+	}
+}
+
+func TestNewConfig_GitLabNotParallel(t *testing.T) {
+	os.Setenv("GITLAB_CI", "true")
+	os.Setenv("CI_PIPELINE_ID", "555")
+	os.Setenv("CI_JOB_NAME", "rspec")
+	os.Setenv("BUILDKITE_TEST_ENGINE_API_ACCESS_TOKEN", "my_token")
+	os.Setenv("BUILDKITE_ORGANIZATION_SLUG", "my_org")
+	os.Setenv("BUILDKITE_TEST_ENGINE_SUITE_SLUG", "my_suite")
+	os.Setenv("BUILDKITE_TEST_ENGINE_TEST_RUNNER", "rspec")
+	os.Setenv("BUILDKITE_TEST_ENGINE_RESULT_PATH", "tmp/rspec.json")
+	defer os.Clearenv()
+
+	_, err := New(nil)
+
+	var invConfigError InvalidConfigError
+	if !errors.As(err, &invConfigError) {
+		t.Fatalf("config.New() error = %v, want InvalidConfigError", err)
+	}
+
+	want := "CI_NODE_INDEX is not set, GitLab CI only sets it for jobs with `parallel:`. Set BUILDKITE_TEST_ENGINE_NODE_INDEX to override it\n" +
+		"CI_NODE_TOTAL is not set, GitLab CI only sets it for jobs with `parallel:`. Set BUILDKITE_TEST_ENGINE_PARALLELISM to override it"
+
+	if diff := cmp.Diff(invConfigError.Error(), want); diff != "" {
+		t.Errorf("config.New() error diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestNewConfig_GitLabInvalidNodeIndex(t *testing.T) {
+	os.Setenv("GITLAB_CI", "true")
+	os.Setenv("CI_PIPELINE_ID", "555")
+	os.Setenv("CI_JOB_NAME", "rspec")
+	os.Setenv("CI_NODE_INDEX", "0")
+	os.Setenv("CI_NODE_TOTAL", "4")
+	os.Setenv("BUILDKITE_TEST_ENGINE_API_ACCESS_TOKEN", "my_token")
+	os.Setenv("BUILDKITE_ORGANIZATION_SLUG", "my_org")
+	os.Setenv("BUILDKITE_TEST_ENGINE_SUITE_SLUG", "my_suite")
+	os.Setenv("BUILDKITE_TEST_ENGINE_TEST_RUNNER", "rspec")
+	os.Setenv("BUILDKITE_TEST_ENGINE_RESULT_PATH", "tmp/rspec.json")
+	defer os.Clearenv()
+
+	_, err := New(nil)
+
+	var invConfigError InvalidConfigError
+	if !errors.As(err, &invConfigError) {
+		t.Fatalf("config.New() error = %v, want InvalidConfigError", err)
+	}
+
+	want := "CI_NODE_INDEX was 0, must be greater than or equal to 1"
+
+	if diff := cmp.Diff(invConfigError.Error(), want); diff != "" {
+		t.Errorf("config.New() error diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestNewConfig_CircleCI(t *testing.T) {
+	os.Setenv("CIRCLECI", "true")
+	os.Setenv("CIRCLE_WORKFLOW_ID", "abc-def")
+	os.Setenv("CIRCLE_JOB", "rspec")
+	os.Setenv("CIRCLE_NODE_INDEX", "0")
+	os.Setenv("CIRCLE_NODE_TOTAL", "2")
+	os.Setenv("BUILDKITE_TEST_ENGINE_API_ACCESS_TOKEN", "my_token")
+	os.Setenv("BUILDKITE_ORGANIZATION_SLUG", "my_org")
+	os.Setenv("BUILDKITE_TEST_ENGINE_SUITE_SLUG", "my_suite")
+	os.Setenv("BUILDKITE_TEST_ENGINE_TEST_RUNNER", "rspec")
+	os.Setenv("BUILDKITE_TEST_ENGINE_RESULT_PATH", "tmp/rspec.json")
+	defer os.Clearenv()
+
+	c, err := New(nil)
+	if err != nil {
+		t.Fatalf("config.New() error = %v", err)
+	}
+
+	if c.CIProvider != "circleci" {
+		t.Errorf("CIProvider = %q, want %q", c.CIProvider, "circleci")
+	}
+
+	if c.Identifier != "abc-def/rspec" {
+		t.Errorf("Identifier = %q, want %q", c.Identifier, "abc-def/rspec")
+	}
+
+	if c.NodeIndex != 0 {
+		t.Errorf("NodeIndex = %d, want %d", c.NodeIndex, 0)
+	}
+
+	if c.Parallelism != 2 {
+		t.Errorf("Parallelism = %d, want %d", c.Parallelism, 2)
+	}
+}
+
+func TestNewConfig_CircleCIMissingNodeTotal(t *testing.T) {
+	os.Setenv("CIRCLECI", "true")
+	os.Setenv("CIRCLE_WORKFLOW_ID", "abc-def")
+	os.Setenv("CIRCLE_JOB", "rspec")
+	os.Setenv("CIRCLE_NODE_INDEX", "0")
+	os.Setenv("BUILDKITE_TEST_ENGINE_API_ACCESS_TOKEN", "my_token")
+	os.Setenv("BUILDKITE_ORGANIZATION_SLUG", "my_org")
+	os.Setenv("BUILDKITE_TEST_ENGINE_SUITE_SLUG", "my_suite")
+	os.Setenv("BUILDKITE_TEST_ENGINE_TEST_RUNNER", "rspec")
+	os.Setenv("BUILDKITE_TEST_ENGINE_RESULT_PATH", "tmp/rspec.json")
+	defer os.Clearenv()
+
+	_, err := New(nil)
+
+	var invConfigError InvalidConfigError
+	if !errors.As(err, &invConfigError) {
+		t.Fatalf("config.New() error = %v, want InvalidConfigError", err)
+	}
+
+	want := `CIRCLE_NODE_TOTAL was "", must be a number`
+
+	if diff := cmp.Diff(invConfigError.Error(), want); diff != "" {
+		t.Errorf("config.New() error diff (-got +want):\n%s", diff)
+	}
+}
@@ -1,10 +1,9 @@
 package config
 
 import (
-	"fmt"
-	"os"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // readFromEnv reads the configuration from environment variables and sets it to the Config struct.
@@ -13,75 +12,173 @@ import (
 // value for ServerBaseUrl if they are not set.
 //
 // Currently, it reads the following environment variables:
+// - BUILDKITE_ANALYTICS_TOKEN (SuiteToken)
 // - BUILDKITE_ORGANIZATION_SLUG (OrganizationSlug)
-// - BUILDKITE_PARALLEL_JOB_COUNT (Parallelism)
-// - BUILDKITE_PARALLEL_JOB (NodeIndex)
+// - BUILDKITE_TEST_ENGINE_ANNOTATE (Annotate)
 // - BUILDKITE_TEST_ENGINE_API_ACCESS_TOKEN (AccessToken)
 // - BUILDKITE_TEST_ENGINE_BASE_URL (ServerBaseUrl)
+// - BUILDKITE_TEST_ENGINE_BRANCH (Branch)
+// - BUILDKITE_TEST_ENGINE_BUILD_ID (Identifier)
+// - BUILDKITE_TEST_ENGINE_FLAKY_POLICY (FlakyPolicy)
+// - BUILDKITE_TEST_ENGINE_JSON_REPORT_PATH (JSONReportPath)
+// - BUILDKITE_TEST_ENGINE_JUNIT_REPORT_PATH (JUnitReportPath)
+// - BUILDKITE_TEST_ENGINE_MODE (Mode)
+// - BUILDKITE_TEST_ENGINE_OUTPUT_TIMEOUT (OutputTimeout)
+// - BUILDKITE_TEST_ENGINE_NODE_INDEX (NodeIndex)
+// - BUILDKITE_TEST_ENGINE_PARALLELISM (Parallelism)
+// - BUILDKITE_TEST_ENGINE_PLAN_CACHE_DIR (PlanCacheDir)
+// - BUILDKITE_TEST_ENGINE_PLAN_FILE (PlanFile)
+// - BUILDKITE_TEST_ENGINE_QUARANTINE_FILE (QuarantineFile)
+// - BUILDKITE_TEST_ENGINE_QUARANTINE_FROM_API (QuarantineFromAPI)
 // - BUILDKITE_TEST_ENGINE_RETRY_COUNT (MaxRetries)
 // - BUILDKITE_TEST_ENGINE_RETRY_CMD (RetryCommand)
 // - BUILDKITE_TEST_ENGINE_SPLIT_BY_EXAMPLE (SplitByExample)
+// - BUILDKITE_TEST_ENGINE_STEP_ID (Identifier)
 // - BUILDKITE_TEST_ENGINE_SUITE_SLUG (SuiteSlug)
+// - BUILDKITE_TEST_ENGINE_SUMMARY_DIR (SummaryDir)
 // - BUILDKITE_TEST_ENGINE_TEST_CMD (TestCommand)
 // - BUILDKITE_TEST_ENGINE_TEST_FILE_PATTERN (TestFilePattern)
 // - BUILDKITE_TEST_ENGINE_TEST_FILE_EXCLUDE_PATTERN (TestFileExcludePattern)
-// - BUILDKITE_BRANCH (Branch)
+// - BUILDKITE_TEST_ENGINE_TIMEOUT (Timeout)
+// - BUILDKITE_TEST_ENGINE_TIMEOUT_GRACE_PERIOD (TimeoutGracePeriod)
+// - BUILDKITE_TEST_ENGINE_UPLOAD_BASE_URL (UploadBaseUrl)
+// - BUILDKITE_TEST_ENGINE_UPLOAD_RESULTS (UploadResults)
+// - BUILDKITE_TEST_ENGINE_WORKERS (Workers)
 //
-// If we are going to support other CI environment in the future,
-// we will need to change where we read the configuration from.
+// The Identifier, NodeIndex, Parallelism, JobId and Branch are read from the environment variables
+// of the detected CI provider, see ciProvider. BUILDKITE_TEST_ENGINE_NODE_INDEX,
+// BUILDKITE_TEST_ENGINE_PARALLELISM and BUILDKITE_TEST_ENGINE_BRANCH take precedence over
+// the CI provider's variables, and the Identifier is made of BUILDKITE_TEST_ENGINE_BUILD_ID
+// and BUILDKITE_TEST_ENGINE_STEP_ID instead when either of them is set.
 func (c *Config) readFromEnv() error {
 
-	c.AccessToken = os.Getenv("BUILDKITE_TEST_ENGINE_API_ACCESS_TOKEN")
-	c.OrganizationSlug = os.Getenv("BUILDKITE_ORGANIZATION_SLUG")
-	c.SuiteSlug = os.Getenv("BUILDKITE_TEST_ENGINE_SUITE_SLUG")
+	c.AccessToken = c.getEnv("BUILDKITE_TEST_ENGINE_API_ACCESS_TOKEN")
+	c.SuiteToken = c.getEnv("BUILDKITE_ANALYTICS_TOKEN")
+	c.OrganizationSlug = c.getEnv("BUILDKITE_ORGANIZATION_SLUG")
+	c.SuiteSlug = c.getEnv("BUILDKITE_TEST_ENGINE_SUITE_SLUG")
+	c.PlanFile = c.getEnv("BUILDKITE_TEST_ENGINE_PLAN_FILE")
 
-	buildId := os.Getenv("BUILDKITE_BUILD_ID")
-	if buildId == "" {
-		c.errs.appendFieldError("BUILDKITE_BUILD_ID", "must not be blank")
-	}
+	provider := detectCIProvider()
+	c.CIProvider = provider.name
 
-	stepId := os.Getenv("BUILDKITE_STEP_ID")
-	if stepId == "" {
-		c.errs.appendFieldError("BUILDKITE_STEP_ID", "must not be blank")
+	// The identifier is not needed when running a plan from a file,
+	// because the plan is not fetched from the API.
+	identifierEnvs, identifierSuffix := provider.identifierEnvs, provider.identifierSuffix
+	if c.getEnv("BUILDKITE_TEST_ENGINE_BUILD_ID") != "" || c.getEnv("BUILDKITE_TEST_ENGINE_STEP_ID") != "" {
+		identifierEnvs, identifierSuffix = []string{"BUILDKITE_TEST_ENGINE_BUILD_ID", "BUILDKITE_TEST_ENGINE_STEP_ID"}, nil
+	}
+	identifier := make([]string, 0, len(identifierEnvs))
+	for _, env := range identifierEnvs {
+		value := c.getEnv(env)
+		if identifierSuffix != nil {
+			value = identifierSuffix.ReplaceAllString(value, "")
+		}
+		if value == "" && c.PlanFile == "" {
+			c.appendFieldError(env, "must not be blank")
+		}
+		identifier = append(identifier, value)
 	}
+	c.Identifier = strings.Join(identifier, "/")
 
-	c.Identifier = fmt.Sprintf("%s/%s", buildId, stepId)
+	c.ServerBaseUrl = c.getEnvWithDefault("BUILDKITE_TEST_ENGINE_BASE_URL", "https://api.buildkite.com")
+	c.Mode = c.getEnvWithDefault("BUILDKITE_TEST_ENGINE_MODE", "static")
+	c.UploadBaseUrl = c.getEnvWithDefault("BUILDKITE_TEST_ENGINE_UPLOAD_BASE_URL", "https://analytics-api.buildkite.com")
+	c.UploadResults = strings.ToLower(c.getEnv("BUILDKITE_TEST_ENGINE_UPLOAD_RESULTS")) == "true"
+	c.TestCommand = c.getEnv("BUILDKITE_TEST_ENGINE_TEST_CMD")
+	c.TestFilePattern = c.getEnv("BUILDKITE_TEST_ENGINE_TEST_FILE_PATTERN")
+	c.TestFileExcludePattern = c.getEnv("BUILDKITE_TEST_ENGINE_TEST_FILE_EXCLUDE_PATTERN")
+	c.TestRunner = c.getEnv("BUILDKITE_TEST_ENGINE_TEST_RUNNER")
+	c.ResultPath = c.getEnv("BUILDKITE_TEST_ENGINE_RESULT_PATH")
+	c.PlanCacheDir = c.getEnv("BUILDKITE_TEST_ENGINE_PLAN_CACHE_DIR")
+	c.JUnitReportPath = c.getEnv("BUILDKITE_TEST_ENGINE_JUNIT_REPORT_PATH")
+	c.JSONReportPath = c.getEnv("BUILDKITE_TEST_ENGINE_JSON_REPORT_PATH")
+	c.SummaryDir = c.getEnv("BUILDKITE_TEST_ENGINE_SUMMARY_DIR")
+	c.Annotate = strings.ToLower(c.getEnv("BUILDKITE_TEST_ENGINE_ANNOTATE")) == "true"
 
-	c.ServerBaseUrl = getEnvWithDefault("BUILDKITE_TEST_ENGINE_BASE_URL", "https://api.buildkite.com")
-	c.TestCommand = os.Getenv("BUILDKITE_TEST_ENGINE_TEST_CMD")
-	c.TestFilePattern = os.Getenv("BUILDKITE_TEST_ENGINE_TEST_FILE_PATTERN")
-	c.TestFileExcludePattern = os.Getenv("BUILDKITE_TEST_ENGINE_TEST_FILE_EXCLUDE_PATTERN")
-	c.TestRunner = os.Getenv("BUILDKITE_TEST_ENGINE_TEST_RUNNER")
-	c.ResultPath = os.Getenv("BUILDKITE_TEST_ENGINE_RESULT_PATH")
+	c.SplitByExample = strings.ToLower(c.getEnv("BUILDKITE_TEST_ENGINE_SPLIT_BY_EXAMPLE")) == "true"
 
-	c.SplitByExample = strings.ToLower(os.Getenv("BUILDKITE_TEST_ENGINE_SPLIT_BY_EXAMPLE")) == "true"
+	c.QuarantineFile = c.getEnv("BUILDKITE_TEST_ENGINE_QUARANTINE_FILE")
+	c.QuarantineFromAPI = strings.ToLower(c.getEnv("BUILDKITE_TEST_ENGINE_QUARANTINE_FROM_API")) == "true"
 
-	// used by Buildkite only, for experimental plans
-	c.Branch = os.Getenv("BUILDKITE_BRANCH")
+	c.JobId = c.getEnv(provider.jobIdEnv)
+
+	// used for experimental plans
+	for _, env := range append([]string{"BUILDKITE_TEST_ENGINE_BRANCH"}, provider.branchEnvs...) {
+		if c.Branch = c.getEnv(env); c.Branch != "" {
+			break
+		}
+	}
 
-	MaxRetries, err := getIntEnvWithDefault("BUILDKITE_TEST_ENGINE_RETRY_COUNT", 0)
+	MaxRetries, err := c.getIntEnvWithDefault("BUILDKITE_TEST_ENGINE_RETRY_COUNT", 0)
 	c.MaxRetries = MaxRetries
 	if err != nil {
-		c.errs.appendFieldError("BUILDKITE_TEST_ENGINE_RETRY_COUNT", "was %q, must be a number", os.Getenv("BUILDKITE_TEST_ENGINE_RETRY_COUNT"))
+		c.appendFieldError("BUILDKITE_TEST_ENGINE_RETRY_COUNT", "was %q, must be a number", c.getEnv("BUILDKITE_TEST_ENGINE_RETRY_COUNT"))
 	}
-	c.RetryCommand = os.Getenv("BUILDKITE_TEST_ENGINE_RETRY_CMD")
+	c.RetryCommand = c.getEnv("BUILDKITE_TEST_ENGINE_RETRY_CMD")
+	c.FlakyPolicy = c.getEnvWithDefault("BUILDKITE_TEST_ENGINE_FLAKY_POLICY", "pass")
 
-	parallelism := os.Getenv("BUILDKITE_PARALLEL_JOB_COUNT")
-	parallelismInt, err := strconv.Atoi(parallelism)
-	if err != nil {
-		c.errs.appendFieldError("BUILDKITE_PARALLEL_JOB_COUNT", "was %q, must be a number", parallelism)
+	for _, d := range []struct {
+		env          string
+		field        *time.Duration
+		defaultValue time.Duration
+	}{
+		{"BUILDKITE_TEST_ENGINE_TIMEOUT", &c.Timeout, 0},
+		{"BUILDKITE_TEST_ENGINE_OUTPUT_TIMEOUT", &c.OutputTimeout, 0},
+		{"BUILDKITE_TEST_ENGINE_TIMEOUT_GRACE_PERIOD", &c.TimeoutGracePeriod, 10 * time.Second},
+	} {
+		value, err := c.getDurationEnvWithDefault(d.env, d.defaultValue)
+		*d.field = value
+		if err != nil {
+			c.appendFieldError(d.env, "was %q, must be a duration such as \"30m\"", c.getEnv(d.env))
+		}
 	}
-	c.Parallelism = parallelismInt
 
-	nodeIndex := os.Getenv("BUILDKITE_PARALLEL_JOB")
-	nodeIndexInt, err := strconv.Atoi(nodeIndex)
+	workers, err := c.getIntEnvWithDefault("BUILDKITE_TEST_ENGINE_WORKERS", 1)
+	c.Workers = workers
 	if err != nil {
-		c.errs.appendFieldError("BUILDKITE_PARALLEL_JOB", "was %q, must be a number", nodeIndex)
+		c.appendFieldError("BUILDKITE_TEST_ENGINE_WORKERS", "was %q, must be a number", c.getEnv("BUILDKITE_TEST_ENGINE_WORKERS"))
+	}
+
+	c.parallelismEnv = c.nodeEnv("BUILDKITE_TEST_ENGINE_PARALLELISM", provider.parallelismEnv)
+	c.Parallelism = c.readNodeInt(c.parallelismEnv, "BUILDKITE_TEST_ENGINE_PARALLELISM", provider)
+
+	c.nodeIndexEnv = c.nodeEnv("BUILDKITE_TEST_ENGINE_NODE_INDEX", provider.nodeIndexEnv)
+	c.NodeIndex = c.readNodeInt(c.nodeIndexEnv, "BUILDKITE_TEST_ENGINE_NODE_INDEX", provider)
+	if c.nodeIndexEnv == provider.nodeIndexEnv {
+		c.nodeIndexBase = provider.nodeIndexBase
+		c.NodeIndex -= c.nodeIndexBase
 	}
-	c.NodeIndex = nodeIndexInt
 
 	if len(c.errs) > 0 {
 		return c.errs
 	}
 	return nil
 }
+
+// readNodeInt reads the node index or parallelism from env, which is either overrideEnv
+// or the CI provider's variable. When the CI provider's variable is missing for a known reason,
+// e.g. the job isn't parallel, the error explains it and names overrideEnv.
+func (c *Config) readNodeInt(env, overrideEnv string, provider ciProvider) int {
+	value := c.getEnv(env)
+	n, err := strconv.Atoi(value)
+	if err == nil {
+		return n
+	}
+
+	if value == "" && env != overrideEnv && provider.missingNodeEnvReason != "" {
+		c.appendFieldError(env, "is not set, %s. Set %s to override it", provider.missingNodeEnvReason, overrideEnv)
+	} else {
+		c.appendFieldError(env, "was %q, must be a number", value)
+	}
+	return n
+}
+
+// nodeEnv returns the environment variable to read the node index or parallelism from.
+// It's the provider-independent variable if it's set or if the CI provider doesn't have one,
+// otherwise it's the CI provider's variable.
+func (c Config) nodeEnv(env, providerEnv string) string {
+	if providerEnv == "" || c.getEnv(env) != "" {
+		return env
+	}
+	return providerEnv
+}
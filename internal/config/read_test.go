@@ -4,6 +4,7 @@ import (
 	"errors"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
@@ -25,6 +26,10 @@ func TestConfigReadFromEnv(t *testing.T) {
 	os.Setenv("BUILDKITE_TEST_ENGINE_TEST_FILE_EXCLUDE_PATTERN", "spec/feature/**/*_spec.rb")
 	os.Setenv("BUILDKITE_TEST_ENGINE_RESULT_PATH", "result.json")
 	os.Setenv("BUILDKITE_TEST_ENGINE_TEST_RUNNER", "rspec")
+	os.Setenv("BUILDKITE_TEST_ENGINE_PLAN_CACHE_DIR", "tmp/plans")
+	os.Setenv("BUILDKITE_TEST_ENGINE_MODE", "dynamic")
+	os.Setenv("BUILDKITE_TEST_ENGINE_TIMEOUT", "30m")
+	os.Setenv("BUILDKITE_TEST_ENGINE_OUTPUT_TIMEOUT", "5m")
 	defer os.Clearenv()
 
 	c := Config{}
@@ -35,6 +40,11 @@ func TestConfigReadFromEnv(t *testing.T) {
 		NodeIndex:              0,
 		ServerBaseUrl:          "https://buildkite.localhost",
 		Identifier:             "123/456",
+		CIProvider:             "buildkite",
+		Workers:                1,
+		FlakyPolicy:            "pass",
+		UploadBaseUrl:          "https://analytics-api.buildkite.com",
+		TimeoutGracePeriod:     10 * time.Second,
 		TestCommand:            "bin/rspec {{testExamples}}",
 		AccessToken:            "my_token",
 		OrganizationSlug:       "my_org",
@@ -45,6 +55,10 @@ func TestConfigReadFromEnv(t *testing.T) {
 		TestFileExcludePattern: "spec/feature/**/*_spec.rb",
 		TestRunner:             "rspec",
 		ResultPath:             "result.json",
+		PlanCacheDir:           "tmp/plans",
+		Mode:                   "dynamic",
+		Timeout:                30 * time.Minute,
+		OutputTimeout:          5 * time.Minute,
 	}
 
 	if err != nil {
@@ -199,3 +213,26 @@ func TestConfigReadFromEnv_InvalidParallelJobCount(t *testing.T) {
 		t.Errorf("config.readFromEnv() got = %v, want = %v", got, want)
 	}
 }
+
+func TestConfigReadFromEnv_InvalidTimeout(t *testing.T) {
+	os.Setenv("BUILDKITE_BUILD_ID", "123")
+	os.Setenv("BUILDKITE_STEP_ID", "456")
+	os.Setenv("BUILDKITE_PARALLEL_JOB", "0")
+	os.Setenv("BUILDKITE_PARALLEL_JOB_COUNT", "10")
+	os.Setenv("BUILDKITE_TEST_ENGINE_TIMEOUT", "30")
+	defer os.Clearenv()
+
+	c := Config{errs: InvalidConfigError{}}
+	err := c.readFromEnv()
+
+	var invConfigError InvalidConfigError
+	if !errors.As(err, &invConfigError) {
+		t.Errorf("config.readFromEnv() error = %v, want InvalidConfigError", err)
+	}
+
+	want := `BUILDKITE_TEST_ENGINE_TIMEOUT was "30", must be a duration such as "30m"`
+
+	if got := invConfigError.Error(); got != want {
+		t.Errorf("config.readFromEnv() got = %v, want = %v", got, want)
+	}
+}
@@ -2,70 +2,153 @@ package config
 
 import (
 	"net/url"
+	"time"
 )
 
 // validate checks if the Config struct is valid and returns InvalidConfigError if it's invalid.
 func (c *Config) validate() error {
 
 	if c.MaxRetries < 0 {
-		c.errs.appendFieldError("BUILDKITE_TEST_ENGINE_RETRY_COUNT", "was %d, must be greater than or equal to 0", c.MaxRetries)
+		c.appendFieldError("BUILDKITE_TEST_ENGINE_RETRY_COUNT", "was %d, must be greater than or equal to 0", c.MaxRetries)
 	}
 
-	// We validate BUILDKITE_PARALLEL_JOB and BUILDKITE_PARALLEL_JOB_COUNT in two steps.
-	// 1. Validate the type and presence of BUILDKITE_PARALLEL_JOB and BUILDKITE_PARALLEL_JOB_COUNT when reading them from the environment. See readFromEnv() in ./read.go.
-	// 2. Validate the range of BUILDKITE_PARALLEL_JOB and BUILDKITE_PARALLEL_JOB_COUNT
+	// The node index and parallelism are read from the CI provider's environment variables,
+	// or from BUILDKITE_TEST_ENGINE_NODE_INDEX and BUILDKITE_TEST_ENGINE_PARALLELISM.
+	// The errors are reported on the variables they were read from.
+	nodeIndexEnv := c.nodeIndexEnv
+	if nodeIndexEnv == "" {
+		nodeIndexEnv = buildkite.nodeIndexEnv
+	}
+	parallelismEnv := c.parallelismEnv
+	if parallelismEnv == "" {
+		parallelismEnv = buildkite.parallelismEnv
+	}
+
+	// We validate the node index and parallelism in two steps.
+	// 1. Validate the type and presence of the node index and parallelism when reading them from the environment. See readFromEnv() in ./read.go.
+	// 2. Validate the range of the node index and parallelism.
 	//
-	// This is the second step. We don't validate the range of BUILDKITE_PARALLEL_JOB and BUILDKITE_PARALLEL_JOB_COUNT if the first validation step fails.
+	// This is the second step. We don't validate the range of the node index and parallelism if the first validation step fails.
 	//
 	// The order of the range validation matters.
-	// The range validation of BUILDKITE_PARALLEL_JOB depends on the result of BUILDKITE_PARALLEL_JOB_COUNT validation at the first step.
-	// We need to validate the range of BUILDKITE_PARALLEL_JOB first before we add the range validation error to BUILDKITE_PARALLEL_JOB_COUNT.
-	if c.errs["BUILDKITE_PARALLEL_JOB"] == nil {
-		if got, min := c.NodeIndex, 0; got < 0 {
-			c.errs.appendFieldError("BUILDKITE_PARALLEL_JOB", "was %d, must be greater than or equal to %d", got, min)
+	// The range validation of the node index depends on the result of the parallelism validation at the first step.
+	// We need to validate the range of the node index first before we add the range validation error to the parallelism.
+	//
+	// The node index is reported in the numbering of the variable, e.g. one-based for GitLab CI_NODE_INDEX.
+	if c.errs[nodeIndexEnv] == nil {
+		if got, min := c.NodeIndex+c.nodeIndexBase, c.nodeIndexBase; got < min {
+			c.appendFieldError(nodeIndexEnv, "was %d, must be greater than or equal to %d", got, min)
 		}
 
-		if c.errs["BUILDKITE_PARALLEL_JOB_COUNT"] == nil {
-			if got, max := c.NodeIndex, c.Parallelism-1; got > max {
-				c.errs.appendFieldError("BUILDKITE_PARALLEL_JOB", "was %d, must not be greater than %d", got, max)
+		if c.errs[parallelismEnv] == nil {
+			if got, max := c.NodeIndex+c.nodeIndexBase, c.Parallelism-1+c.nodeIndexBase; got > max {
+				c.appendFieldError(nodeIndexEnv, "was %d, must not be greater than %d", got, max)
 			}
 		}
 	}
 
-	if c.errs["BUILDKITE_PARALLEL_JOB_COUNT"] == nil {
+	if c.errs[parallelismEnv] == nil {
 		if got, min := c.Parallelism, 1; got < min {
-			c.errs.appendFieldError("BUILDKITE_PARALLEL_JOB_COUNT", "was %d, must be greater than or equal to %d", got, min)
+			c.appendFieldError(parallelismEnv, "was %d, must be greater than or equal to %d", got, min)
 		}
 
 		if got, max := c.Parallelism, 1000; got > max {
-			c.errs.appendFieldError("BUILDKITE_PARALLEL_JOB_COUNT", "was %d, must not be greater than %d", got, max)
+			c.appendFieldError(parallelismEnv, "was %d, must not be greater than %d", got, max)
+		}
+	}
+
+	if c.errs["BUILDKITE_TEST_ENGINE_WORKERS"] == nil {
+		if got, min := c.Workers, 1; got < min {
+			c.appendFieldError("BUILDKITE_TEST_ENGINE_WORKERS", "was %d, must be greater than or equal to %d", got, min)
+		}
+
+		if got, max := c.Workers, 256; got > max {
+			c.appendFieldError("BUILDKITE_TEST_ENGINE_WORKERS", "was %d, must not be greater than %d", got, max)
 		}
 	}
 
+	for _, d := range []struct {
+		env   string
+		value time.Duration
+	}{
+		{"BUILDKITE_TEST_ENGINE_TIMEOUT", c.Timeout},
+		{"BUILDKITE_TEST_ENGINE_OUTPUT_TIMEOUT", c.OutputTimeout},
+		{"BUILDKITE_TEST_ENGINE_TIMEOUT_GRACE_PERIOD", c.TimeoutGracePeriod},
+	} {
+		if c.errs[d.env] == nil && d.value < 0 {
+			c.appendFieldError(d.env, "was %s, must not be negative", d.value)
+		}
+	}
+
+	if c.Mode != "static" && c.Mode != "dynamic" {
+		c.appendFieldError("BUILDKITE_TEST_ENGINE_MODE", "was %q, must be either \"static\" or \"dynamic\"", c.Mode)
+	}
+
+	if c.FlakyPolicy != "pass" && c.FlakyPolicy != "fail" && c.FlakyPolicy != "soft-fail" {
+		c.appendFieldError("BUILDKITE_TEST_ENGINE_FLAKY_POLICY", "was %q, must be either \"pass\", \"fail\" or \"soft-fail\"", c.FlakyPolicy)
+	}
+
+	// A plan file is a static split, there is no queue to pull the tests from.
+	if c.Mode == "dynamic" && c.PlanFile != "" {
+		c.appendFieldError("BUILDKITE_TEST_ENGINE_PLAN_FILE", "must be blank when BUILDKITE_TEST_ENGINE_MODE is dynamic")
+	}
+
+	// The API is not used when running a plan from a file.
+	if c.QuarantineFromAPI && c.PlanFile != "" {
+		c.appendFieldError("BUILDKITE_TEST_ENGINE_QUARANTINE_FROM_API", "must not be true when BUILDKITE_TEST_ENGINE_PLAN_FILE is set")
+	}
+
 	if c.ServerBaseUrl != "" {
 		if _, err := url.ParseRequestURI(c.ServerBaseUrl); err != nil {
-			c.errs.appendFieldError("BUILDKITE_TEST_ENGINE_BASE_URL", "must be a valid URL")
+			c.appendFieldError("BUILDKITE_TEST_ENGINE_BASE_URL", "must be a valid URL")
 		}
 	}
 
-	if c.AccessToken == "" {
-		c.errs.appendFieldError("BUILDKITE_TEST_ENGINE_API_ACCESS_TOKEN", "must not be blank")
+	if c.UploadResults {
+		if c.SuiteToken == "" {
+			c.appendFieldError("BUILDKITE_ANALYTICS_TOKEN", "must not be blank when BUILDKITE_TEST_ENGINE_UPLOAD_RESULTS is true")
+		}
+		if _, err := url.ParseRequestURI(c.UploadBaseUrl); err != nil {
+			c.appendFieldError("BUILDKITE_TEST_ENGINE_UPLOAD_BASE_URL", "must be a valid URL")
+		}
 	}
 
-	if c.OrganizationSlug == "" {
-		c.errs.appendFieldError("BUILDKITE_ORGANIZATION_SLUG", "must not be blank")
-	}
+	// The API is not used when running a plan from a file.
+	if c.PlanFile == "" {
+		if c.AccessToken == "" {
+			c.appendFieldError("BUILDKITE_TEST_ENGINE_API_ACCESS_TOKEN", "must not be blank")
+		}
+
+		if c.OrganizationSlug == "" {
+			c.appendFieldError("BUILDKITE_ORGANIZATION_SLUG", "must not be blank")
+		}
 
-	if c.SuiteSlug == "" {
-		c.errs.appendFieldError("BUILDKITE_TEST_ENGINE_SUITE_SLUG", "must not be blank")
+		if c.SuiteSlug == "" {
+			c.appendFieldError("BUILDKITE_TEST_ENGINE_SUITE_SLUG", "must not be blank")
+		}
 	}
 
 	if c.ResultPath == "" {
-		c.errs.appendFieldError("BUILDKITE_TEST_ENGINE_RESULT_PATH", "must not be blank")
+		c.appendFieldError("BUILDKITE_TEST_ENGINE_RESULT_PATH", "must not be blank")
 	}
 
 	if c.TestRunner == "" {
-		c.errs.appendFieldError("BUILDKITE_TEST_ENGINE_TEST_RUNNER", "must not be blank")
+		c.appendFieldError("BUILDKITE_TEST_ENGINE_TEST_RUNNER", "must not be blank")
+	}
+
+	// The custom runner has no default commands nor test file pattern.
+	if c.TestRunner == "custom" {
+		if c.TestCommand == "" {
+			c.appendFieldError("BUILDKITE_TEST_ENGINE_TEST_CMD", "must not be blank when BUILDKITE_TEST_ENGINE_TEST_RUNNER is custom")
+		}
+
+		if c.TestFilePattern == "" {
+			c.appendFieldError("BUILDKITE_TEST_ENGINE_TEST_FILE_PATTERN", "must not be blank when BUILDKITE_TEST_ENGINE_TEST_RUNNER is custom")
+		}
+
+		if c.MaxRetries > 0 && c.RetryCommand == "" {
+			c.appendFieldError("BUILDKITE_TEST_ENGINE_RETRY_CMD", "must not be blank when BUILDKITE_TEST_ENGINE_TEST_RUNNER is custom and BUILDKITE_TEST_ENGINE_RETRY_COUNT is greater than 0")
+		}
 	}
 
 	if len(c.errs) > 0 {
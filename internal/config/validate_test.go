@@ -15,6 +15,9 @@ func createConfig() Config {
 		SuiteSlug:        "my_suite",
 		AccessToken:      "my_token",
 		MaxRetries:       3,
+		Mode:             "static",
+		FlakyPolicy:      "pass",
+		Workers:          1,
 		ResultPath:       "tmp/result-*.json",
 		errs:             InvalidConfigError{},
 		TestRunner:       "rspec",
@@ -83,6 +86,21 @@ func TestConfigValidate_Invalid(t *testing.T) {
 			name:  "BUILDKITE_TEST_ENGINE_TEST_RUNNER",
 			value: "",
 		},
+		// Mode is unknown
+		{
+			name:  "BUILDKITE_TEST_ENGINE_MODE",
+			value: "random",
+		},
+		// Flaky policy is unknown
+		{
+			name:  "BUILDKITE_TEST_ENGINE_FLAKY_POLICY",
+			value: "ignore",
+		},
+		// Workers < 1
+		{
+			name:  "BUILDKITE_TEST_ENGINE_WORKERS",
+			value: 0,
+		},
 	}
 
 	for _, s := range scenario {
@@ -103,6 +121,12 @@ func TestConfigValidate_Invalid(t *testing.T) {
 				c.AccessToken = s.value.(string)
 			case "BUILDKITE_TEST_ENGINE_TEST_RUNNER":
 				c.TestRunner = s.value.(string)
+			case "BUILDKITE_TEST_ENGINE_MODE":
+				c.Mode = s.value.(string)
+			case "BUILDKITE_TEST_ENGINE_FLAKY_POLICY":
+				c.FlakyPolicy = s.value.(string)
+			case "BUILDKITE_TEST_ENGINE_WORKERS":
+				c.Workers = s.value.(int)
 			}
 
 			err := c.validate()
@@ -155,4 +179,65 @@ func TestConfigValidate_Invalid(t *testing.T) {
 			t.Errorf("config.validate() error length = %d, want 1", len(invConfigError))
 		}
 	})
+
+	t.Run("Custom runner without test command", func(t *testing.T) {
+		c := createConfig()
+		c.TestRunner = "custom"
+		c.MaxRetries = 0
+		c.TestFilePattern = "test/**/*_test.rb"
+		err := c.validate()
+
+		var invConfigError InvalidConfigError
+		if !errors.As(err, &invConfigError) {
+			t.Errorf("config.validate() error = %v, want InvalidConfigError", err)
+			return
+		}
+
+		if len(invConfigError) != 1 {
+			t.Errorf("config.validate() error length = %d, want 1", len(invConfigError))
+		}
+
+		if len(invConfigError["BUILDKITE_TEST_ENGINE_TEST_CMD"]) != 1 {
+			t.Errorf("config.validate() error for BUILDKITE_TEST_ENGINE_TEST_CMD length = %d, want 1", len(invConfigError["BUILDKITE_TEST_ENGINE_TEST_CMD"]))
+		}
+	})
+
+	t.Run("Custom runner without test file pattern and retry command", func(t *testing.T) {
+		c := createConfig()
+		c.TestRunner = "custom"
+		c.TestCommand = "bin/rails test {{testExamples}}"
+		err := c.validate()
+
+		var invConfigError InvalidConfigError
+		if !errors.As(err, &invConfigError) {
+			t.Errorf("config.validate() error = %v, want InvalidConfigError", err)
+			return
+		}
+
+		// MaxRetries is 3, so the retry command is required.
+		if len(invConfigError) != 2 {
+			t.Errorf("config.validate() error length = %d, want 2", len(invConfigError))
+		}
+	})
+
+	t.Run("Upload results without suite token", func(t *testing.T) {
+		c := createConfig()
+		c.UploadResults = true
+		c.UploadBaseUrl = "https://analytics-api.buildkite.com"
+		err := c.validate()
+
+		var invConfigError InvalidConfigError
+		if !errors.As(err, &invConfigError) {
+			t.Errorf("config.validate() error = %v, want InvalidConfigError", err)
+			return
+		}
+
+		if len(invConfigError) != 1 {
+			t.Errorf("config.validate() error length = %d, want 1", len(invConfigError))
+		}
+
+		if len(invConfigError["BUILDKITE_ANALYTICS_TOKEN"]) != 1 {
+			t.Errorf("config.validate() error for BUILDKITE_ANALYTICS_TOKEN length = %d, want 1", len(invConfigError["BUILDKITE_ANALYTICS_TOKEN"]))
+		}
+	})
 }
@@ -0,0 +1,235 @@
+package plan
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// ErrCacheChecksumMismatch is returned when the cached plan doesn't match its checksum,
+// for example when the cache file was partially written or modified.
+var ErrCacheChecksumMismatch = errors.New("cached plan checksum mismatch")
+
+// cacheEntry is the structure of the plan cache file.
+type cacheEntry struct {
+	Identifier string          `json:"identifier"`
+	Checksum   string          `json:"checksum"`
+	Plan       json.RawMessage `json:"plan"`
+}
+
+// CachePath returns the path of the plan cache file for the given identifier in the cache directory.
+// The identifier is hashed because it contains characters that are not safe in file names.
+func CachePath(dir string, identifier string) string {
+	sum := sha256.Sum256([]byte(identifier))
+	return filepath.Join(dir, "plan-"+hex.EncodeToString(sum[:])+".json")
+}
+
+// ReadCache reads the test plan for the given identifier from the cache directory.
+// It returns nil without an error if the plan is not cached.
+//
+// Error is returned if the cache file cannot be read or parsed, or if the plan doesn't match its checksum.
+func ReadCache(dir string, identifier string) (*TestPlan, error) {
+	data, err := os.ReadFile(CachePath(dir, identifier))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cached plan: %w", err)
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("failed to parse cached plan: %w", err)
+	}
+
+	if entry.Identifier != identifier || entry.Checksum != checksum(entry.Plan) {
+		return nil, ErrCacheChecksumMismatch
+	}
+
+	var testPlan TestPlan
+	if err := json.Unmarshal(entry.Plan, &testPlan); err != nil {
+		return nil, fmt.Errorf("failed to parse cached plan: %w", err)
+	}
+
+	testPlan.Cached = true
+	return &testPlan, nil
+}
+
+// WriteCache writes the test plan for the given identifier to the cache directory,
+// and returns the plan that is in the cache afterwards.
+//
+// The first plan written for an identifier wins. If another node or job has already cached
+// a valid plan for the identifier, the cache is not modified and the cached plan is returned instead,
+// so that all nodes sharing the cache directory run the same plan.
+// The plan is written to a temporary file first, so a partially written plan is never read.
+func WriteCache(dir string, identifier string, testPlan TestPlan) (TestPlan, error) {
+	data, err := json.Marshal(testPlan)
+	if err != nil {
+		return testPlan, fmt.Errorf("failed to encode plan: %w", err)
+	}
+
+	entry, err := json.Marshal(cacheEntry{
+		Identifier: identifier,
+		Checksum:   checksum(data),
+		Plan:       data,
+	})
+	if err != nil {
+		return testPlan, fmt.Errorf("failed to encode plan: %w", err)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return testPlan, fmt.Errorf("failed to create plan cache directory: %w", err)
+	}
+
+	f, err := os.CreateTemp(dir, ".plan-*.json")
+	if err != nil {
+		return testPlan, fmt.Errorf("failed to write plan cache: %w", err)
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(entry); err != nil {
+		f.Close()
+		return testPlan, fmt.Errorf("failed to write plan cache: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return testPlan, fmt.Errorf("failed to write plan cache: %w", err)
+	}
+
+	path := CachePath(dir, identifier)
+
+	// Link fails if the cache file already exists, which makes the first writer win.
+	err = os.Link(f.Name(), path)
+	if err == nil {
+		return testPlan, nil
+	}
+	if !errors.Is(err, fs.ErrExist) {
+		return testPlan, fmt.Errorf("failed to write plan cache: %w", err)
+	}
+
+	cachedPlan, readErr := ReadCache(dir, identifier)
+	if readErr == nil && cachedPlan != nil {
+		return *cachedPlan, nil
+	}
+
+	// The existing cache file is invalid, replace it.
+	if err := os.Rename(f.Name(), path); err != nil {
+		return testPlan, fmt.Errorf("failed to write plan cache: %w", err)
+	}
+
+	return testPlan, nil
+}
+
+// CachedDurations returns the estimated durations of the given files from the most recently cached plan
+// created by Test Engine in the cache directory, typically the plan of a previous build.
+// The estimated durations of the examples of a file that was split by example are added up
+// to the duration of the file, whose path is followed by "[" or ":" in the path of the examples,
+// e.g. "./spec/apple_spec.rb[1:1]" or "src/apple.test.js::is red".
+//
+// Nil is returned if there is no such plan in the cache directory.
+func CachedDurations(dir string, files []string) map[string]time.Duration {
+	paths, err := filepath.Glob(filepath.Join(dir, "plan-*.json"))
+	if err != nil {
+		return nil
+	}
+
+	type cacheFile struct {
+		path    string
+		modTime time.Time
+	}
+	var cacheFiles []cacheFile
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil {
+			cacheFiles = append(cacheFiles, cacheFile{path, info.ModTime()})
+		}
+	}
+	// most recent first, by path when modified at the same time
+	slices.SortFunc(cacheFiles, func(a, b cacheFile) int {
+		if n := b.modTime.Compare(a.modTime); n != 0 {
+			return n
+		}
+		return cmp.Compare(a.path, b.path)
+	})
+
+	for _, f := range cacheFiles {
+		testPlan, err := readCacheFile(f.path)
+		if err != nil || testPlan.Fallback {
+			continue
+		}
+		return fileDurations(testPlan, files)
+	}
+
+	return nil
+}
+
+// fileDurations adds up the estimated durations of the test cases of the plan by file.
+func fileDurations(testPlan TestPlan, files []string) map[string]time.Duration {
+	isFile := make(map[string]bool, len(files))
+	for _, file := range files {
+		isFile[file] = true
+	}
+
+	durations := make(map[string]time.Duration)
+	for _, task := range testPlan.Tasks {
+		for _, testCase := range task.Tests {
+			if file, ok := testCaseFile(testCase, isFile); ok {
+				durations[file] += time.Duration(testCase.EstimatedDuration) * time.Millisecond
+			}
+		}
+	}
+	return durations
+}
+
+// testCaseFile returns the file of the test case, which is its path for a file, and the longest
+// file path followed by "[" or ":" in its path for an example.
+func testCaseFile(testCase TestCase, isFile map[string]bool) (string, bool) {
+	if isFile[testCase.Path] {
+		return testCase.Path, true
+	}
+	if testCase.Format != TestCaseFormatExample {
+		return "", false
+	}
+
+	for i := len(testCase.Path) - 1; i > 0; i-- {
+		if c := testCase.Path[i]; c != '[' && c != ':' {
+			continue
+		}
+		if file := testCase.Path[:i]; isFile[file] {
+			return file, true
+		}
+	}
+	return "", false
+}
+
+// readCacheFile reads the test plan from the cache file, for any identifier.
+func readCacheFile(path string) (TestPlan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return TestPlan{}, err
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return TestPlan{}, err
+	}
+
+	if entry.Checksum != checksum(entry.Plan) {
+		return TestPlan{}, ErrCacheChecksumMismatch
+	}
+
+	var testPlan TestPlan
+	err = json.Unmarshal(entry.Plan, &testPlan)
+	return testPlan, err
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,154 @@
+package plan
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWriteCache(t *testing.T) {
+	dir := t.TempDir()
+	testPlan := TestPlan{
+		Tasks: map[string]*Task{
+			"0": {NodeNumber: 0, Tests: []TestCase{{Path: "apple_spec.rb", EstimatedDuration: 1000}}},
+			"1": {NodeNumber: 1, Tests: []TestCase{{Path: "banana_spec.rb", EstimatedDuration: 1000}}},
+		},
+	}
+
+	got, err := WriteCache(dir, "build/step", testPlan)
+	if err != nil {
+		t.Errorf("WriteCache(%q, %q, %v) error = %v", dir, "build/step", testPlan, err)
+	}
+	if diff := cmp.Diff(got, testPlan); diff != "" {
+		t.Errorf("WriteCache(%q, %q, %v) diff (-got +want):\n%s", dir, "build/step", testPlan, diff)
+	}
+
+	cached, err := ReadCache(dir, "build/step")
+	if err != nil {
+		t.Errorf("ReadCache(%q, %q) error = %v", dir, "build/step", err)
+	}
+	testPlan.Cached = true
+	if diff := cmp.Diff(cached, &testPlan); diff != "" {
+		t.Errorf("ReadCache(%q, %q) diff (-got +want):\n%s", dir, "build/step", diff)
+	}
+}
+
+func TestWriteCache_AlreadyCached(t *testing.T) {
+	dir := t.TempDir()
+	first := TestPlan{
+		Tasks: map[string]*Task{
+			"0": {NodeNumber: 0, Tests: []TestCase{{Path: "apple_spec.rb"}}},
+		},
+	}
+	second := CreateFallbackPlan([]TestCase{{Path: "banana_spec.rb"}}, 1)
+
+	if _, err := WriteCache(dir, "build/step", first); err != nil {
+		t.Errorf("WriteCache(%q, %q, %v) error = %v", dir, "build/step", first, err)
+	}
+
+	// the plan cached first wins
+	got, err := WriteCache(dir, "build/step", second)
+	if err != nil {
+		t.Errorf("WriteCache(%q, %q, %v) error = %v", dir, "build/step", second, err)
+	}
+	first.Cached = true
+	if diff := cmp.Diff(got, first); diff != "" {
+		t.Errorf("WriteCache(%q, %q, %v) diff (-got +want):\n%s", dir, "build/step", second, diff)
+	}
+}
+
+func TestWriteCache_InvalidCache(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(CachePath(dir, "build/step"), []byte(`{"identifier": "build/step", "checksum": "sha256:abc", "plan": {}}`), 0644)
+
+	testPlan := CreateFallbackPlan([]TestCase{{Path: "apple_spec.rb"}}, 1)
+
+	got, err := WriteCache(dir, "build/step", testPlan)
+	if err != nil {
+		t.Errorf("WriteCache(%q, %q, %v) error = %v", dir, "build/step", testPlan, err)
+	}
+	if diff := cmp.Diff(got, testPlan); diff != "" {
+		t.Errorf("WriteCache(%q, %q, %v) diff (-got +want):\n%s", dir, "build/step", testPlan, diff)
+	}
+
+	cached, err := ReadCache(dir, "build/step")
+	if err != nil {
+		t.Errorf("ReadCache(%q, %q) error = %v", dir, "build/step", err)
+	}
+	testPlan.Cached = true
+	if diff := cmp.Diff(cached, &testPlan); diff != "" {
+		t.Errorf("ReadCache(%q, %q) diff (-got +want):\n%s", dir, "build/step", diff)
+	}
+}
+
+func TestReadCache_NotCached(t *testing.T) {
+	dir := t.TempDir()
+
+	got, err := ReadCache(dir, "build/step")
+	if err != nil {
+		t.Errorf("ReadCache(%q, %q) error = %v", dir, "build/step", err)
+	}
+	if got != nil {
+		t.Errorf("ReadCache(%q, %q) = %v, want nil", dir, "build/step", got)
+	}
+}
+
+func TestReadCache_ChecksumMismatch(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(CachePath(dir, "build/step"), []byte(`{"identifier": "build/step", "checksum": "sha256:abc", "plan": {"tasks": {}}}`), 0644)
+
+	_, err := ReadCache(dir, "build/step")
+	if !errors.Is(err, ErrCacheChecksumMismatch) {
+		t.Errorf("ReadCache(%q, %q) error = %v, want %v", dir, "build/step", err, ErrCacheChecksumMismatch)
+	}
+}
+
+func TestCachedDurations(t *testing.T) {
+	dir := t.TempDir()
+	serverPlan := TestPlan{
+		Tasks: map[string]*Task{
+			"0": {NodeNumber: 0, Tests: []TestCase{
+				{Path: "./spec/apple_spec.rb", EstimatedDuration: 3000},
+				{Path: "./spec/apple_spec.rb_old.rb", EstimatedDuration: 100},
+			}},
+			"1": {NodeNumber: 1, Tests: []TestCase{
+				{Path: "./spec/banana_spec.rb[1:1]", Format: TestCaseFormatExample, EstimatedDuration: 1500},
+				{Path: "./spec/banana_spec.rb[1:2]", Format: TestCaseFormatExample, EstimatedDuration: 500},
+				{Path: "./spec/deleted_spec.rb", EstimatedDuration: 1000},
+			}},
+		},
+	}
+	if _, err := WriteCache(dir, "build-1/step", serverPlan); err != nil {
+		t.Fatal(err)
+	}
+
+	// The fallback plan of a later build is ignored.
+	fallbackPlan := CreateFallbackPlan([]TestCase{{Path: "./spec/apple_spec.rb", EstimatedDuration: 9000}}, 1)
+	if _, err := WriteCache(dir, "build-2/step", fallbackPlan); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(CachePath(dir, "build-2/step"), later, later)
+
+	files := []string{"./spec/apple_spec.rb", "./spec/banana_spec.rb", "./spec/cherry_spec.rb"}
+	got := CachedDurations(dir, files)
+
+	want := map[string]time.Duration{
+		"./spec/apple_spec.rb":  3 * time.Second,
+		"./spec/banana_spec.rb": 2 * time.Second,
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("CachedDurations(%q, %v) diff (-got +want):\n%s", dir, files, diff)
+	}
+}
+
+func TestCachedDurations_NotCached(t *testing.T) {
+	dir := t.TempDir()
+
+	if got := CachedDurations(dir, []string{"./spec/apple_spec.rb"}); got != nil {
+		t.Errorf("CachedDurations(%q, ...) = %v, want nil", dir, got)
+	}
+}
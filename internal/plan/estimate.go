@@ -0,0 +1,53 @@
+package plan
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// EstimateDurations returns the test cases for the given files, with the estimated duration
+// set to the known duration of the file, if any, and the weight set to the size of the file in bytes.
+// The size is used as a proxy for the duration of the files whose duration is unknown,
+// see CreatePlan.
+//
+// If the path is a directory, e.g. a Go package, the size of all files directly in the
+// directory is used. If the path cannot be read, the weight is zero.
+func EstimateDurations(files []string, durations map[string]time.Duration) []TestCase {
+	testCases := make([]TestCase, 0, len(files))
+	for _, file := range files {
+		testCases = append(testCases, TestCase{
+			Path:              file,
+			EstimatedDuration: int(durations[file].Milliseconds()),
+			Weight:            int(fileSize(file)),
+		})
+	}
+	return testCases
+}
+
+func fileSize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+
+	if !info.IsDir() {
+		return info.Size()
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return 0
+	}
+
+	var size int64
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if info, err := os.Stat(filepath.Join(path, entry.Name())); err == nil {
+			size += info.Size()
+		}
+	}
+	return size
+}
@@ -2,19 +2,50 @@ package plan
 
 import (
 	"cmp"
+	"math"
 	"slices"
 	"strconv"
 )
 
-// CreateFallbackPlan creates a fallback test plan for the given tests and parallelism.
-// It distributes test cases evenly accross the tasks using deterministic algorithm.
-func CreateFallbackPlan(files []string, parallelism int) TestPlan {
-	// sort all test cases
-	slices.SortFunc(files, func(a, b string) int {
-		return cmp.Compare(a, b)
+// CreateFallbackPlan creates a fallback test plan for the given test cases and parallelism.
+// See CreatePlan for how the test cases are distributed.
+func CreateFallbackPlan(testCases []TestCase, parallelism int) TestPlan {
+	testPlan := CreatePlan(testCases, parallelism)
+	testPlan.Fallback = true
+	return testPlan
+}
+
+// CreatePlan creates a test plan for the given test cases and parallelism.
+// It distributes test cases accross the tasks using the longest processing time first algorithm:
+// test cases are sorted by their cost, longest first, and each one is assigned
+// to the task with the least cost so far. See planCosts for the cost of the test cases.
+//
+// The algorithm is deterministic, so every node creates the same plan given the same input.
+// Ties are broken by the path of the test case, then by the number of tests in the task,
+// then by the node number. When the costs are unknown (i.e. all zero),
+// the test cases are distributed evenly in alphabetical order.
+func CreatePlan(testCases []TestCase, parallelism int) TestPlan {
+	type costedTestCase struct {
+		TestCase
+		cost int
+	}
+
+	costs := planCosts(testCases)
+	costed := make([]costedTestCase, len(testCases))
+	for i, testCase := range testCases {
+		costed[i] = costedTestCase{testCase, costs[i]}
+	}
+
+	// sort all test cases, longest first
+	slices.SortStableFunc(costed, func(a, b costedTestCase) int {
+		if n := cmp.Compare(b.cost, a.cost); n != 0 {
+			return n
+		}
+		return cmp.Compare(a.Path, b.Path)
 	})
 
 	tasks := make(map[string]*Task)
+	durations := make([]int, parallelism)
 	for i := 0; i < parallelism; i++ {
 		tasks[strconv.Itoa(i)] = &Task{
 			NodeNumber: i,
@@ -22,17 +53,53 @@ func CreateFallbackPlan(files []string, parallelism int) TestPlan {
 		}
 	}
 
-	// distribute files to tasks
-	for i, file := range files {
-		nodeNumber := i % parallelism
+	// distribute test cases to tasks
+	for _, testCase := range costed {
+		nodeNumber := 0
+		for i := 1; i < parallelism; i++ {
+			if durations[i] < durations[nodeNumber] ||
+				(durations[i] == durations[nodeNumber] && len(tasks[strconv.Itoa(i)].Tests) < len(tasks[strconv.Itoa(nodeNumber)].Tests)) {
+				nodeNumber = i
+			}
+		}
+
 		task := tasks[strconv.Itoa(nodeNumber)]
-		task.Tests = append(task.Tests, TestCase{
-			Path: file,
-		})
+		task.Tests = append(task.Tests, testCase.TestCase)
+		durations[nodeNumber] += testCase.cost
 	}
 
 	return TestPlan{
-		Tasks:    tasks,
-		Fallback: true,
+		Tasks: tasks,
+	}
+}
+
+// planCosts returns the cost of each test case to balance the plan with, which is its estimated
+// duration when it's known. When no estimated duration is known, the weights are used instead.
+// Otherwise, the weights of the test cases without an estimated duration are converted to durations
+// by the ratio of durations to weights of the test cases that have both, so the costs are comparable.
+func planCosts(testCases []TestCase) []int {
+	var knownDurations, knownWeights int
+	anyDuration := false
+	for _, testCase := range testCases {
+		if testCase.EstimatedDuration > 0 {
+			anyDuration = true
+			if testCase.Weight > 0 {
+				knownDurations += testCase.EstimatedDuration
+				knownWeights += testCase.Weight
+			}
+		}
+	}
+
+	costs := make([]int, len(testCases))
+	for i, testCase := range testCases {
+		switch {
+		case testCase.EstimatedDuration > 0:
+			costs[i] = testCase.EstimatedDuration
+		case !anyDuration:
+			costs[i] = testCase.Weight
+		case knownWeights > 0:
+			costs[i] = int(math.Round(float64(testCase.Weight) * float64(knownDurations) / float64(knownWeights)))
+		}
 	}
+	return costs
 }
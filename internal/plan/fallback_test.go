@@ -1,7 +1,10 @@
 package plan
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 )
@@ -56,7 +59,12 @@ func TestCreateFallbackPlan(t *testing.T) {
 	}
 
 	for _, s := range scenarios {
-		plan := CreateFallbackPlan(s.files, s.parallelism)
+		testCases := []TestCase{}
+		for _, file := range s.files {
+			testCases = append(testCases, TestCase{Path: file})
+		}
+
+		plan := CreateFallbackPlan(testCases, s.parallelism)
 		got := make([][]TestCase, s.parallelism)
 		for _, task := range plan.Tasks {
 			got[task.NodeNumber] = task.Tests
@@ -71,3 +79,139 @@ func TestCreateFallbackPlan(t *testing.T) {
 		}
 	}
 }
+
+func TestCreateFallbackPlan_EstimatedDuration(t *testing.T) {
+	scenarios := []struct {
+		testCases   []TestCase
+		parallelism int
+		want        [][]TestCase
+	}{
+		{
+			testCases: []TestCase{
+				{Path: "a", EstimatedDuration: 1},
+				{Path: "b", EstimatedDuration: 2},
+				{Path: "c", EstimatedDuration: 10},
+				{Path: "d", EstimatedDuration: 3},
+				{Path: "e", EstimatedDuration: 4},
+			},
+			parallelism: 2,
+			want: [][]TestCase{
+				{{Path: "c", EstimatedDuration: 10}},
+				{{Path: "e", EstimatedDuration: 4}, {Path: "d", EstimatedDuration: 3}, {Path: "b", EstimatedDuration: 2}, {Path: "a", EstimatedDuration: 1}},
+			},
+		},
+		{
+			testCases: []TestCase{
+				{Path: "a", EstimatedDuration: 5},
+				{Path: "b", EstimatedDuration: 5},
+				{Path: "c", EstimatedDuration: 4},
+				{Path: "d", EstimatedDuration: 3},
+				{Path: "e", EstimatedDuration: 3},
+			},
+			parallelism: 3,
+			want: [][]TestCase{
+				{{Path: "a", EstimatedDuration: 5}, {Path: "e", EstimatedDuration: 3}},
+				{{Path: "b", EstimatedDuration: 5}},
+				{{Path: "c", EstimatedDuration: 4}, {Path: "d", EstimatedDuration: 3}},
+			},
+		},
+		// Ties in duration are broken by the number of tests in the task.
+		{
+			testCases: []TestCase{
+				{Path: "a", EstimatedDuration: 4},
+				{Path: "b", EstimatedDuration: 2},
+				{Path: "c", EstimatedDuration: 2},
+				{Path: "d", EstimatedDuration: 0},
+			},
+			parallelism: 2,
+			want: [][]TestCase{
+				{{Path: "a", EstimatedDuration: 4}, {Path: "d", EstimatedDuration: 0}},
+				{{Path: "b", EstimatedDuration: 2}, {Path: "c", EstimatedDuration: 2}},
+			},
+		},
+	}
+
+	for _, s := range scenarios {
+		plan := CreateFallbackPlan(s.testCases, s.parallelism)
+		got := make([][]TestCase, s.parallelism)
+		for _, task := range plan.Tasks {
+			got[task.NodeNumber] = task.Tests
+		}
+
+		if diff := cmp.Diff(got, s.want); diff != "" {
+			t.Errorf("CreateFallbackPlan(%v, %v) diff (-got +want):\n%s", s.testCases, s.parallelism, diff)
+		}
+	}
+}
+
+func TestCreateFallbackPlan_Weight(t *testing.T) {
+	scenarios := []struct {
+		name        string
+		testCases   []TestCase
+		parallelism int
+		want        [][]TestCase
+	}{
+		{
+			name: "no known durations",
+			testCases: []TestCase{
+				{Path: "a", Weight: 100},
+				{Path: "b", Weight: 300},
+				{Path: "c", Weight: 200},
+			},
+			parallelism: 2,
+			want: [][]TestCase{
+				{{Path: "b", Weight: 300}},
+				{{Path: "c", Weight: 200}, {Path: "a", Weight: 100}},
+			},
+		},
+		{
+			// "a" takes 1ms per byte, so "b" and "c" are estimated to take 300ms and 200ms.
+			name: "some known durations",
+			testCases: []TestCase{
+				{Path: "a", EstimatedDuration: 1000, Weight: 1000},
+				{Path: "b", Weight: 300},
+				{Path: "c", Weight: 200},
+				{Path: "d", EstimatedDuration: 600},
+			},
+			parallelism: 2,
+			want: [][]TestCase{
+				{{Path: "a", EstimatedDuration: 1000, Weight: 1000}},
+				{{Path: "d", EstimatedDuration: 600}, {Path: "b", Weight: 300}, {Path: "c", Weight: 200}},
+			},
+		},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			plan := CreateFallbackPlan(s.testCases, s.parallelism)
+			got := make([][]TestCase, s.parallelism)
+			for _, task := range plan.Tasks {
+				got[task.NodeNumber] = task.Tests
+			}
+
+			if diff := cmp.Diff(got, s.want); diff != "" {
+				t.Errorf("CreateFallbackPlan(%v, %v) diff (-got +want):\n%s", s.testCases, s.parallelism, diff)
+			}
+		})
+	}
+}
+
+func TestEstimateDurations(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a_test.go"), []byte("12345"), 0644)
+	os.WriteFile(filepath.Join(dir, "b_test.go"), []byte("123"), 0644)
+
+	files := []string{filepath.Join(dir, "a_test.go"), dir, filepath.Join(dir, "missing_test.go")}
+	durations := map[string]time.Duration{filepath.Join(dir, "a_test.go"): 1500 * time.Millisecond}
+	got := EstimateDurations(files, durations)
+
+	want := []TestCase{
+		{Path: filepath.Join(dir, "a_test.go"), EstimatedDuration: 1500, Weight: 5},
+		{Path: dir, Weight: 8},
+		{Path: filepath.Join(dir, "missing_test.go")},
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("EstimateDurations(%v, %v) diff (-got +want):\n%s", files, durations, diff)
+	}
+}
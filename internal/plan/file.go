@@ -0,0 +1,23 @@
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// ReadPlanFile reads a test plan from the JSON file at the given path,
+// such as the one written by "bktec plan --format json".
+func ReadPlanFile(path string) (TestPlan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return TestPlan{}, fmt.Errorf("failed to read plan file: %w", err)
+	}
+
+	var testPlan TestPlan
+	if err := json.Unmarshal(data, &testPlan); err != nil {
+		return TestPlan{}, fmt.Errorf("failed to parse plan file %s: %w", path, err)
+	}
+
+	return testPlan, nil
+}
@@ -0,0 +1,56 @@
+package plan
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestReadPlanFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plan.json")
+	os.WriteFile(path, []byte(`{
+  "experiment": "",
+  "tasks": {
+    "0": {
+      "node_number": 0,
+      "tests": [
+        {
+          "estimated_duration": 60000,
+          "format": "file",
+          "path": "spec/apple_spec.rb"
+        }
+      ]
+    }
+  },
+  "fallback": false
+}`), 0644)
+
+	got, err := ReadPlanFile(path)
+	if err != nil {
+		t.Errorf("ReadPlanFile(%q) error = %v", path, err)
+	}
+
+	want := TestPlan{
+		Tasks: map[string]*Task{
+			"0": {
+				NodeNumber: 0,
+				Tests:      []TestCase{{Path: "spec/apple_spec.rb", EstimatedDuration: 60000, Format: TestCaseFormatFile}},
+			},
+		},
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("ReadPlanFile(%q) diff (-got +want):\n%s", path, diff)
+	}
+}
+
+func TestReadPlanFile_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plan.json")
+	os.WriteFile(path, []byte(`not a plan`), 0644)
+
+	if _, err := ReadPlanFile(path); err == nil {
+		t.Errorf("ReadPlanFile(%q) error = nil, want error", path)
+	}
+}
@@ -15,6 +15,9 @@ type TestCase struct {
 	Name              string         `json:"name,omitempty"`
 	Path              string         `json:"path"`
 	Scope             string         `json:"scope,omitempty"`
+	// Weight is the relative cost of the test case when its estimated duration is unknown,
+	// e.g. the size of the file for a fallback plan. It's only used to create the plan, see CreatePlan.
+	Weight int `json:"-"`
 }
 
 // Task represents the task for the given node.
@@ -27,5 +30,10 @@ type Task struct {
 type TestPlan struct {
 	Experiment string           `json:"experiment"`
 	Tasks      map[string]*Task `json:"tasks"`
-	Fallback   bool
+	Fallback   bool             `json:"fallback"`
+	// FallbackReason is why the fallback plan was created instead of a plan from Test Engine.
+	// It's only used to report the plan, so it's not written to plan files.
+	FallbackReason string `json:"-"`
+	// Cached is true when the plan has been read from the plan cache, see ReadCache.
+	Cached bool `json:"-"`
 }
@@ -0,0 +1,3 @@
+// Package report writes the reports of the tests run by bktec, combining the results of all attempts,
+// and the summary of the run of a node.
+package report
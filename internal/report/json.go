@@ -0,0 +1,60 @@
+package report
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/buildkite/test-engine-client/internal/runner"
+)
+
+type jsonTest struct {
+	Id     string            `json:"id"`
+	Name   string            `json:"name"`
+	Scope  string            `json:"scope"`
+	Path   string            `json:"path,omitempty"`
+	Status runner.TestStatus `json:"status"`
+	// Duration is in seconds.
+	Duration float64 `json:"duration"`
+	Retries  int     `json:"retries"`
+}
+
+type jsonReport struct {
+	Tests []jsonTest `json:"tests"`
+}
+
+// WriteJSON writes the results of the tests as a JSON report to the path, for example:
+//
+//	{
+//	  "tests": [
+//	    {
+//	      "id": "./spec/fruits/apple_spec.rb[1:1]",
+//	      "name": "is red",
+//	      "scope": "Apple",
+//	      "path": "./spec/fruits/apple_spec.rb",
+//	      "status": "passed",
+//	      "duration": 0.5,
+//	      "retries": 1
+//	    }
+//	  ]
+//	}
+func WriteJSON(path string, tests []runner.TestResult) error {
+	report := jsonReport{Tests: []jsonTest{}}
+	for _, test := range tests {
+		report.Tests = append(report.Tests, jsonTest{
+			Id:       test.Id,
+			Name:     test.Name,
+			Scope:    test.Scope,
+			Path:     test.Path,
+			Status:   test.Status,
+			Duration: test.Duration.Seconds(),
+			Retries:  test.Retries,
+		})
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
@@ -0,0 +1,55 @@
+package report
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/buildkite/test-engine-client/internal/runner"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWriteJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.json")
+	tests := []runner.TestResult{
+		{Id: "Fruits apple is red", Name: "is red", Scope: "Fruits apple", Path: "/app/apple.spec.js", Status: runner.TestStatusPassed, Duration: 1500 * time.Millisecond, Retries: 1},
+		{Id: "example.com/fruits::TestIsCurved", Name: "TestIsCurved", Scope: "example.com/fruits", Status: runner.TestStatusFailed},
+	}
+
+	if err := WriteJSON(path, tests); err != nil {
+		t.Fatalf("WriteJSON(%q, ...) error = %v", path, err)
+	}
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{
+  "tests": [
+    {
+      "id": "Fruits apple is red",
+      "name": "is red",
+      "scope": "Fruits apple",
+      "path": "/app/apple.spec.js",
+      "status": "passed",
+      "duration": 1.5,
+      "retries": 1
+    },
+    {
+      "id": "example.com/fruits::TestIsCurved",
+      "name": "TestIsCurved",
+      "scope": "example.com/fruits",
+      "status": "failed",
+      "duration": 0,
+      "retries": 0
+    }
+  ]
+}
+`
+
+	if diff := cmp.Diff(string(got), want); diff != "" {
+		t.Errorf("WriteJSON(%q, ...) diff (-got +want):\n%s", path, diff)
+	}
+}
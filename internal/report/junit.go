@@ -0,0 +1,106 @@
+package report
+
+import (
+	"encoding/xml"
+	"os"
+	"strconv"
+
+	"github.com/buildkite/test-engine-client/internal/runner"
+)
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitProperties struct {
+	Properties []junitProperty `xml:"property"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+}
+
+type junitTestCase struct {
+	Name       string           `xml:"name,attr"`
+	Classname  string           `xml:"classname,attr"`
+	File       string           `xml:"file,attr,omitempty"`
+	Time       float64          `xml:"time,attr"`
+	Properties *junitProperties `xml:"properties"`
+	Failure    *junitFailure    `xml:"failure"`
+	Skipped    *struct{}        `xml:"skipped"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      float64         `xml:"time,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestSuites struct {
+	XMLName    xml.Name         `xml:"testsuites"`
+	Name       string           `xml:"name,attr"`
+	Tests      int              `xml:"tests,attr"`
+	Failures   int              `xml:"failures,attr"`
+	Skipped    int              `xml:"skipped,attr"`
+	Time       float64          `xml:"time,attr"`
+	TestSuites []junitTestSuite `xml:"testsuite"`
+}
+
+// WriteJUnit writes the results of the tests as a JUnit XML report to the path.
+// The tests are grouped into a test suite per scope, in the order they were run.
+// The number of times a test was retried is written as the "retries" property of the test case.
+func WriteJUnit(path string, tests []runner.TestResult) error {
+	report := junitTestSuites{Name: "bktec"}
+	suites := map[string]int{}
+
+	for _, test := range tests {
+		i, ok := suites[test.Scope]
+		if !ok {
+			i = len(report.TestSuites)
+			suites[test.Scope] = i
+			report.TestSuites = append(report.TestSuites, junitTestSuite{Name: test.Scope})
+		}
+		suite := &report.TestSuites[i]
+
+		testCase := junitTestCase{
+			Name:      test.Name,
+			Classname: test.Scope,
+			File:      test.Path,
+			Time:      test.Duration.Seconds(),
+		}
+
+		if test.Retries > 0 {
+			testCase.Properties = &junitProperties{
+				Properties: []junitProperty{{Name: "retries", Value: strconv.Itoa(test.Retries)}},
+			}
+		}
+
+		switch test.Status {
+		case runner.TestStatusFailed:
+			testCase.Failure = &junitFailure{Message: "failed"}
+			suite.Failures++
+			report.Failures++
+		case runner.TestStatusSkipped:
+			testCase.Skipped = &struct{}{}
+			suite.Skipped++
+			report.Skipped++
+		}
+
+		suite.Tests++
+		suite.Time += testCase.Time
+		suite.TestCases = append(suite.TestCases, testCase)
+		report.Tests++
+		report.Time += testCase.Time
+	}
+
+	data, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append([]byte(xml.Header), append(data, '\n')...), 0644)
+}
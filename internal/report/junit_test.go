@@ -0,0 +1,67 @@
+package report
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/buildkite/test-engine-client/internal/runner"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWriteJUnit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "junit.xml")
+	tests := []runner.TestResult{
+		{Id: "./spec/apple_spec.rb[1:1]", Name: "is red", Scope: "Apple", Path: "./spec/apple_spec.rb", Status: runner.TestStatusPassed, Duration: 500 * time.Millisecond},
+		{Id: "./spec/banana_spec.rb[1:1]", Name: "is yellow", Scope: "Banana", Path: "./spec/banana_spec.rb", Status: runner.TestStatusFailed, Duration: time.Second, Retries: 2},
+		{Id: "./spec/apple_spec.rb[1:2]", Name: "is sweet", Scope: "Apple", Path: "./spec/apple_spec.rb", Status: runner.TestStatusPassed, Duration: 250 * time.Millisecond, Retries: 1},
+		{Id: "./spec/banana_spec.rb[1:2]", Name: "is curved", Scope: "Banana", Path: "./spec/banana_spec.rb", Status: runner.TestStatusSkipped},
+	}
+
+	if err := WriteJUnit(path, tests); err != nil {
+		t.Fatalf("WriteJUnit(%q, ...) error = %v", path, err)
+	}
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites name="bktec" tests="4" failures="1" skipped="1" time="1.75">
+  <testsuite name="Apple" tests="2" failures="0" skipped="0" time="0.75">
+    <testcase name="is red" classname="Apple" file="./spec/apple_spec.rb" time="0.5"></testcase>
+    <testcase name="is sweet" classname="Apple" file="./spec/apple_spec.rb" time="0.25">
+      <properties>
+        <property name="retries" value="1"></property>
+      </properties>
+    </testcase>
+  </testsuite>
+  <testsuite name="Banana" tests="2" failures="1" skipped="1" time="1">
+    <testcase name="is yellow" classname="Banana" file="./spec/banana_spec.rb" time="1">
+      <properties>
+        <property name="retries" value="2"></property>
+      </properties>
+      <failure message="failed"></failure>
+    </testcase>
+    <testcase name="is curved" classname="Banana" file="./spec/banana_spec.rb" time="0">
+      <skipped></skipped>
+    </testcase>
+  </testsuite>
+</testsuites>
+`
+
+	if diff := cmp.Diff(string(got), want); diff != "" {
+		t.Errorf("WriteJUnit(%q, ...) diff (-got +want):\n%s", path, diff)
+	}
+
+	// The report can be read by the JUnit XML parser of the custom runner.
+	report, err := runner.ParseJUnitReport(path)
+	if err != nil {
+		t.Errorf("runner.ParseJUnitReport(%q) error = %v", path, err)
+	}
+	if got := len(report.TestCases()); got != len(tests) {
+		t.Errorf("runner.ParseJUnitReport(%q) test cases = %d, want %d", path, got, len(tests))
+	}
+}
@@ -0,0 +1,231 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/buildkite/test-engine-client/internal/runner"
+)
+
+// Summary is the summary of running the tests of a node, see WriteSummaryJSON and WriteSummaryMarkdown.
+type Summary struct {
+	NodeIndex int
+	// Runner is the name of the test runner, e.g. "RSpec".
+	Runner string
+	// Status is the status of the run after all retries.
+	Status runner.RunStatus
+	// PlanSource is where the test plan comes from, i.e. "server", "cache", "fallback", "file" or "queue".
+	PlanSource string
+	// FallbackReason is why the fallback plan was used instead of a plan from Test Engine.
+	FallbackReason string
+	// Files and Examples are the number of test files and examples assigned to the node,
+	// which are zero when the node pulls the tests from a queue.
+	Files    int
+	Examples int
+	// EstimatedDuration is the estimated duration of the tests of the node, zero when it's unknown.
+	// For a fallback plan, it only includes the files whose duration is known.
+	EstimatedDuration time.Duration
+	// Duration is how long running the tests took, including retries.
+	Duration time.Duration
+	// Attempts are the outcomes of the first run of the tests and of each retry.
+	Attempts         []runner.AttemptResult
+	FailedTests      []string
+	FlakyTests       []string
+	QuarantinedTests []string
+}
+
+type jsonSummaryPlan struct {
+	Source         string `json:"source"`
+	FallbackReason string `json:"fallback_reason,omitempty"`
+	Files          int    `json:"files"`
+	Examples       int    `json:"examples"`
+	// EstimatedDuration is in seconds.
+	EstimatedDuration float64 `json:"estimated_duration"`
+}
+
+type jsonSummaryAttempt struct {
+	Attempt int `json:"attempt"`
+	Passed  int `json:"passed"`
+	Failed  int `json:"failed"`
+	Skipped int `json:"skipped"`
+	// Duration is in seconds.
+	Duration float64 `json:"duration"`
+}
+
+type jsonSummary struct {
+	NodeIndex int              `json:"node_index"`
+	Runner    string           `json:"runner"`
+	Status    runner.RunStatus `json:"status"`
+	Plan      jsonSummaryPlan  `json:"plan"`
+	// Duration is in seconds.
+	Duration         float64              `json:"duration"`
+	Attempts         []jsonSummaryAttempt `json:"attempts"`
+	FailedTests      []string             `json:"failed_tests"`
+	FlakyTests       []string             `json:"flaky_tests"`
+	QuarantinedTests []string             `json:"quarantined_tests"`
+}
+
+// WriteSummaryJSON writes the summary as JSON to the path, for example:
+//
+//	{
+//	  "node_index": 0,
+//	  "runner": "RSpec",
+//	  "status": "failed",
+//	  "plan": {
+//	    "source": "server",
+//	    "files": 2,
+//	    "examples": 10,
+//	    "estimated_duration": 60
+//	  },
+//	  "duration": 75.5,
+//	  "attempts": [
+//	    {
+//	      "attempt": 0,
+//	      "passed": 10,
+//	      "failed": 2,
+//	      "skipped": 0,
+//	      "duration": 70
+//	    },
+//	    {
+//	      "attempt": 1,
+//	      "passed": 1,
+//	      "failed": 1,
+//	      "skipped": 0,
+//	      "duration": 5.5
+//	    }
+//	  ],
+//	  "failed_tests": [
+//	    "./spec/fruits/apple_spec.rb[1:2]"
+//	  ],
+//	  "flaky_tests": [
+//	    "./spec/fruits/banana_spec.rb[1:1]"
+//	  ],
+//	  "quarantined_tests": []
+//	}
+//
+// The attempt is zero for the first run of the tests, and the number of the retry otherwise.
+func WriteSummaryJSON(path string, s Summary) error {
+	summary := jsonSummary{
+		NodeIndex: s.NodeIndex,
+		Runner:    s.Runner,
+		Status:    s.Status,
+		Plan: jsonSummaryPlan{
+			Source:            s.PlanSource,
+			FallbackReason:    s.FallbackReason,
+			Files:             s.Files,
+			Examples:          s.Examples,
+			EstimatedDuration: s.EstimatedDuration.Seconds(),
+		},
+		Duration:         s.Duration.Seconds(),
+		Attempts:         []jsonSummaryAttempt{},
+		FailedTests:      append([]string{}, s.FailedTests...),
+		FlakyTests:       append([]string{}, s.FlakyTests...),
+		QuarantinedTests: append([]string{}, s.QuarantinedTests...),
+	}
+
+	for i, attempt := range s.Attempts {
+		summary.Attempts = append(summary.Attempts, jsonSummaryAttempt{
+			Attempt:  i,
+			Passed:   attempt.Passed,
+			Failed:   attempt.Failed,
+			Skipped:  attempt.Skipped,
+			Duration: attempt.Duration.Seconds(),
+		})
+	}
+
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// WriteSummaryMarkdown writes the summary as markdown to the path,
+// which can be used as the body of a Buildkite annotation.
+func WriteSummaryMarkdown(path string, s Summary) error {
+	return os.WriteFile(path, []byte(summaryMarkdown(s)), 0644)
+}
+
+func summaryMarkdown(s Summary) string {
+	var b strings.Builder
+
+	icon := "✅"
+	switch {
+	case s.Status != runner.RunStatusPassed:
+		icon = "❌"
+	case len(s.FlakyTests) > 0:
+		icon = "⚠️"
+	}
+	outcome := string(s.Status)
+	switch s.Status {
+	case runner.RunStatusError:
+		outcome = "errored"
+	case runner.RunStatusTimeout:
+		outcome = "timed out"
+	}
+	fmt.Fprintf(&b, "### %s %s %s on node %d\n\n", icon, s.Runner, outcome, s.NodeIndex)
+
+	plan := s.PlanSource
+	if s.FallbackReason != "" {
+		plan = fmt.Sprintf("%s (%s)", plan, html.EscapeString(s.FallbackReason))
+	}
+	// The tests assigned to the node are unknown when it pulls them from a queue,
+	// and the duration is only estimated by Test Engine.
+	assigned := "-"
+	if s.Files > 0 || s.Examples > 0 {
+		assigned = fmt.Sprintf("%d files, %d examples", s.Files, s.Examples)
+	}
+	estimated := "-"
+	if s.EstimatedDuration > 0 {
+		estimated = formatDuration(s.EstimatedDuration)
+	}
+
+	b.WriteString("| Test plan | Assigned | Estimated duration | Duration |\n")
+	b.WriteString("| --- | --- | --- | --- |\n")
+	fmt.Fprintf(&b, "| %s | %s | %s | %s |\n\n", plan, assigned, estimated, formatDuration(s.Duration))
+
+	if len(s.Attempts) > 0 {
+		b.WriteString("| Attempt | Passed | Failed | Skipped | Duration |\n")
+		b.WriteString("| --- | --- | --- | --- | --- |\n")
+		for i, attempt := range s.Attempts {
+			name := "First run"
+			if i > 0 {
+				name = fmt.Sprintf("Retry %d", i)
+			}
+			fmt.Fprintf(&b, "| %s | %d | %d | %d | %s |\n", name, attempt.Passed, attempt.Failed, attempt.Skipped, formatDuration(attempt.Duration))
+		}
+		b.WriteString("\n")
+	}
+
+	writeTestList(&b, "Failed tests", s.FailedTests)
+	writeTestList(&b, "Flaky tests that passed on retry", s.FlakyTests)
+	writeTestList(&b, "Quarantined tests that failed", s.QuarantinedTests)
+
+	return b.String()
+}
+
+// writeTestList writes the tests as a collapsed list, nothing if there are no tests.
+func writeTestList(b *strings.Builder, title string, tests []string) {
+	if len(tests) == 0 {
+		return
+	}
+
+	fmt.Fprintf(b, "<details>\n<summary>%s (%d)</summary>\n\n", title, len(tests))
+	for _, test := range tests {
+		fmt.Fprintf(b, "- <code>%s</code>\n", html.EscapeString(test))
+	}
+	b.WriteString("\n</details>\n\n")
+}
+
+// formatDuration rounds the duration to make it readable, e.g. "1m5s" or "350ms".
+func formatDuration(d time.Duration) string {
+	if d >= time.Second {
+		return d.Round(time.Second).String()
+	}
+	return d.Round(time.Millisecond).String()
+}
@@ -0,0 +1,164 @@
+package report
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/buildkite/test-engine-client/internal/runner"
+	"github.com/google/go-cmp/cmp"
+)
+
+var testSummary = Summary{
+	NodeIndex:         1,
+	Runner:            "RSpec",
+	Status:            runner.RunStatusFailed,
+	PlanSource:        "server",
+	Files:             2,
+	Examples:          3,
+	EstimatedDuration: 90 * time.Second,
+	Duration:          95500 * time.Millisecond,
+	Attempts: []runner.AttemptResult{
+		{Passed: 2, Failed: 2, Skipped: 1, Duration: 90 * time.Second},
+		{Passed: 1, Failed: 1, Duration: 5500 * time.Millisecond},
+	},
+	FailedTests: []string{"./spec/apple_spec.rb[1:2]"},
+	FlakyTests:  []string{"./spec/banana_spec.rb[1:1]"},
+}
+
+func TestWriteSummaryJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "summary.json")
+
+	if err := WriteSummaryJSON(path, testSummary); err != nil {
+		t.Fatalf("WriteSummaryJSON(%q, ...) error = %v", path, err)
+	}
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{
+  "node_index": 1,
+  "runner": "RSpec",
+  "status": "failed",
+  "plan": {
+    "source": "server",
+    "files": 2,
+    "examples": 3,
+    "estimated_duration": 90
+  },
+  "duration": 95.5,
+  "attempts": [
+    {
+      "attempt": 0,
+      "passed": 2,
+      "failed": 2,
+      "skipped": 1,
+      "duration": 90
+    },
+    {
+      "attempt": 1,
+      "passed": 1,
+      "failed": 1,
+      "skipped": 0,
+      "duration": 5.5
+    }
+  ],
+  "failed_tests": [
+    "./spec/apple_spec.rb[1:2]"
+  ],
+  "flaky_tests": [
+    "./spec/banana_spec.rb[1:1]"
+  ],
+  "quarantined_tests": []
+}
+`
+
+	if diff := cmp.Diff(string(got), want); diff != "" {
+		t.Errorf("WriteSummaryJSON(%q, ...) diff (-got +want):\n%s", path, diff)
+	}
+}
+
+func TestWriteSummaryMarkdown(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "summary.md")
+
+	if err := WriteSummaryMarkdown(path, testSummary); err != nil {
+		t.Fatalf("WriteSummaryMarkdown(%q, ...) error = %v", path, err)
+	}
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `### ❌ RSpec failed on node 1
+
+| Test plan | Assigned | Estimated duration | Duration |
+| --- | --- | --- | --- |
+| server | 2 files, 3 examples | 1m30s | 1m36s |
+
+| Attempt | Passed | Failed | Skipped | Duration |
+| --- | --- | --- | --- | --- |
+| First run | 2 | 2 | 1 | 1m30s |
+| Retry 1 | 1 | 1 | 0 | 6s |
+
+<details>
+<summary>Failed tests (1)</summary>
+
+- <code>./spec/apple_spec.rb[1:2]</code>
+
+</details>
+
+<details>
+<summary>Flaky tests that passed on retry (1)</summary>
+
+- <code>./spec/banana_spec.rb[1:1]</code>
+
+</details>
+
+`
+
+	if diff := cmp.Diff(string(got), want); diff != "" {
+		t.Errorf("WriteSummaryMarkdown(%q, ...) diff (-got +want):\n%s", path, diff)
+	}
+}
+
+func TestWriteSummaryMarkdown_Fallback(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "summary.md")
+	summary := Summary{
+		Runner:         "Jest",
+		Status:         runner.RunStatusPassed,
+		PlanSource:     "fallback",
+		FallbackReason: "Test Engine is unavailable",
+		Files:          1,
+		Duration:       350 * time.Millisecond,
+		Attempts:       []runner.AttemptResult{{Passed: 4, Duration: 350 * time.Millisecond}},
+	}
+
+	if err := WriteSummaryMarkdown(path, summary); err != nil {
+		t.Fatalf("WriteSummaryMarkdown(%q, ...) error = %v", path, err)
+	}
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `### ✅ Jest passed on node 0
+
+| Test plan | Assigned | Estimated duration | Duration |
+| --- | --- | --- | --- |
+| fallback (Test Engine is unavailable) | 1 files, 0 examples | - | 350ms |
+
+| Attempt | Passed | Failed | Skipped | Duration |
+| --- | --- | --- | --- | --- |
+| First run | 4 | 0 | 0 | 350ms |
+
+`
+
+	if diff := cmp.Diff(string(got), want); diff != "" {
+		t.Errorf("WriteSummaryMarkdown(%q, ...) diff (-got +want):\n%s", path, diff)
+	}
+}
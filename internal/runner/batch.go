@@ -0,0 +1,147 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/kballard/go-shellquote"
+)
+
+// testExamplesFilePlaceholder is replaced by the path of a file listing the test cases,
+// one per line, instead of passing the test cases as arguments of the test command.
+const testExamplesFilePlaceholder = "{{testExamplesFile}}"
+
+// maxArgsSize is the maximum size in bytes of the arguments and environment of a single
+// invocation of the test command. It's below the limit of the OS, which is 1 MiB on macOS
+// and 2 MiB on Linux, to leave room for the rest of the command.
+var maxArgsSize = 512 * 1024
+
+// minArgsSize is the size in bytes left for the test cases when the environment is larger than maxArgsSize.
+const minArgsSize = 64 * 1024
+
+// maxArgSize is the maximum size in bytes of a single argument of the test command, with its
+// terminating null byte. It's the limit of Linux (MAX_ARG_STRLEN), which applies on top of maxArgsSize.
+var maxArgSize = 128 * 1024
+
+// argSize returns the size of an argument or environment variable in the memory of a new process,
+// which is the string with its terminating null byte and a pointer to it.
+func argSize(arg string) int {
+	return len(arg) + 1 + 8
+}
+
+// batchTestCases splits the test cases into batches that fit in the arguments of a single
+// invocation of the test command, together with the environment. The order of the test cases
+// is kept, and there is always at least one batch, even if there are no test cases.
+func batchTestCases(testCases []string) [][]string {
+	limit := maxArgsSize
+	for _, env := range os.Environ() {
+		limit -= argSize(env)
+	}
+	limit = max(limit, minArgsSize)
+
+	batches := [][]string{{}}
+	size := 0
+	for _, testCase := range testCases {
+		last := len(batches) - 1
+		if size+argSize(testCase) > limit && len(batches[last]) > 0 {
+			batches = append(batches, []string{})
+			last++
+			size = 0
+		}
+		batches[last] = append(batches[last], testCase)
+		size += argSize(testCase)
+	}
+	return batches
+}
+
+// namePattern returns a regular expression matching exactly one of the names, e.g. "^(a|b)$".
+func namePattern(names []string) string {
+	patterns := make([]string, len(names))
+	for i, name := range names {
+		patterns[i] = regexp.QuoteMeta(name)
+	}
+	return fmt.Sprintf("^(%s)$", strings.Join(patterns, "|"))
+}
+
+// batchNames splits the names into groups whose namePattern fits in a single argument
+// of the test command, see maxArgSize. The order of the names is kept, and there is
+// always at least one group.
+func batchNames(names []string) [][]string {
+	// the anchors and parentheses of the pattern, and the terminating null byte,
+	// which takes the place of the separator of the first name
+	const overhead = len("^()$")
+
+	batches := [][]string{{}}
+	size := overhead
+	for _, name := range names {
+		last := len(batches) - 1
+		nameSize := len(regexp.QuoteMeta(name)) + len("|")
+		if size+nameSize > maxArgSize && len(batches[last]) > 0 {
+			batches = append(batches, []string{})
+			last++
+			size = overhead
+		}
+		batches[last] = append(batches[last], name)
+		size += nameSize
+	}
+	return batches
+}
+
+// runInBatches runs the test cases with the command, in several sequential invocations
+// when they don't fit in the arguments of a single one, and merges the results.
+// Like the invocations of Jest, it stops at the first invocation that returns an error,
+// and returns the error with the results of the invocations so far merged.
+// The run function is expected to write the result of each invocation to its own
+// result path, see invocationResultPath.
+//
+// When the command has the "{{testExamplesFile}}" placeholder, the test cases are written
+// to a temporary file, the placeholder is replaced by its path, and the command is run once
+// without the test cases as arguments.
+func runInBatches(command string, testCases []string, run func(command string, testCases []string) (RunResult, error)) (RunResult, error) {
+	if strings.Contains(command, testExamplesFilePlaceholder) {
+		path, err := writeTestExamplesFile(testCases)
+		if err != nil {
+			return RunResult{Status: RunStatusError}, fmt.Errorf("failed to write test examples file: %w", err)
+		}
+		defer os.Remove(path)
+
+		return run(strings.ReplaceAll(command, testExamplesFilePlaceholder, shellquote.Join(path)), nil)
+	}
+
+	batches := batchTestCases(testCases)
+	if len(batches) > 1 {
+		fmt.Printf("Buildkite Test Engine Client: Running %d tests in %d invocations to stay within the argument limit\n", len(testCases), len(batches))
+	}
+
+	var results []RunResult
+	for _, batch := range batches {
+		result, err := run(command, batch)
+		results = append(results, result)
+		if err != nil {
+			return MergeRunResults(results...), err
+		}
+	}
+
+	return MergeRunResults(results...), nil
+}
+
+// writeTestExamplesFile writes the test cases to a temporary file, one per line,
+// and returns the path of the file.
+func writeTestExamplesFile(testCases []string) (string, error) {
+	f, err := os.CreateTemp("", "bktec-test-examples-*.txt")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	for _, testCase := range testCases {
+		if _, err := fmt.Fprintln(f, testCase); err != nil {
+			os.Remove(f.Name())
+			return "", err
+		}
+	}
+
+	return f.Name(), nil
+}
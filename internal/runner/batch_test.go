@@ -0,0 +1,248 @@
+package runner
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+// setMaxArgsSize sets maxArgsSize so that the given number of bytes is left for the test cases
+// after the environment, and restores it after the test.
+func setMaxArgsSize(t *testing.T, size int) {
+	t.Helper()
+	original := maxArgsSize
+	t.Cleanup(func() {
+		maxArgsSize = original
+	})
+
+	maxArgsSize = size
+	for _, env := range os.Environ() {
+		maxArgsSize += argSize(env)
+	}
+}
+
+// setMaxArgSize sets maxArgSize, and restores it after the test.
+func setMaxArgSize(t *testing.T, size int) {
+	t.Helper()
+	original := maxArgSize
+	t.Cleanup(func() {
+		maxArgSize = original
+	})
+	maxArgSize = size
+}
+
+func TestBatchNames(t *testing.T) {
+	// the pattern of 3 names of 10 bytes, "^(aaaaaaaaaa|...)$", fits in 37 bytes with the null byte
+	setMaxArgSize(t, 37)
+
+	var names []string
+	for i := 0; i < 7; i++ {
+		names = append(names, strings.Repeat("a", 10))
+	}
+
+	got := batchNames(names)
+
+	var sizes []int
+	for _, batch := range got {
+		sizes = append(sizes, len(batch))
+		if size := len(namePattern(batch)) + 1; size > maxArgSize {
+			t.Errorf("namePattern(%q) size = %d, want at most %d", batch, size, maxArgSize)
+		}
+	}
+
+	if diff := cmp.Diff(sizes, []int{3, 3, 1}); diff != "" {
+		t.Errorf("batchNames() batch sizes diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestBatchNames_Small(t *testing.T) {
+	names := []string{"Billing charges (in dollars) the card", "Billing refunds"}
+	got := batchNames(names)
+
+	if diff := cmp.Diff(got, [][]string{names}); diff != "" {
+		t.Errorf("batchNames() diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestNamePattern(t *testing.T) {
+	got := namePattern([]string{"Billing charges (in dollars) the card", "Billing refunds"})
+	want := `^(Billing charges \(in dollars\) the card|Billing refunds)$`
+
+	if got != want {
+		t.Errorf("namePattern() = %q, want %q", got, want)
+	}
+}
+
+func TestBatchTestCases(t *testing.T) {
+	// 100 test cases of 1000 bytes fit in a batch
+	setMaxArgsSize(t, 100*argSize(strings.Repeat("a", 1000)))
+
+	var testCases []string
+	for i := 0; i < 250; i++ {
+		testCases = append(testCases, strings.Repeat("a", 1000))
+	}
+
+	got := batchTestCases(testCases)
+
+	var sizes []int
+	for _, batch := range got {
+		sizes = append(sizes, len(batch))
+	}
+
+	if diff := cmp.Diff(sizes, []int{100, 100, 50}); diff != "" {
+		t.Errorf("batchTestCases() batch sizes diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestBatchTestCases_Small(t *testing.T) {
+	testCases := []string{"spec/apple_spec.rb", "spec/banana_spec.rb"}
+	got := batchTestCases(testCases)
+
+	if diff := cmp.Diff(got, [][]string{testCases}); diff != "" {
+		t.Errorf("batchTestCases() diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestBatchTestCases_Empty(t *testing.T) {
+	got := batchTestCases(nil)
+
+	if diff := cmp.Diff(got, [][]string{{}}); diff != "" {
+		t.Errorf("batchTestCases() diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestCustomRun_Batches(t *testing.T) {
+	setMaxArgsSize(t, minArgsSize)
+
+	custom := NewCustom(RunnerConfig{
+		TestCommand: "../../test/support/junit.sh {{resultPath}} {{testExamples}}",
+		ResultPath:  filepath.Join(t.TempDir(), "custom.xml"),
+	})
+
+	// each test case fills half of a batch, so they are run in 3 invocations
+	long := strings.Repeat("a", minArgsSize/2)
+	testCases := []string{"first fail " + long, "second " + long, "third fail " + long}
+
+	got, err := custom.Run(testCases, 0)
+	if err != nil {
+		t.Errorf("Custom.Run() error = %v", err)
+	}
+
+	want := RunResult{
+		Status:      RunStatusFailed,
+		FailedTests: []string{"Fake::first fail " + long, "Fake::third fail " + long},
+	}
+
+	if diff := cmp.Diff(got, want, cmpopts.IgnoreFields(RunResult{}, "Tests")); diff != "" {
+		t.Errorf("Custom.Run() diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestCustomRun_BatchError(t *testing.T) {
+	setMaxArgsSize(t, minArgsSize)
+
+	// The test command crashes when it's given a test case containing "crash".
+	dir := t.TempDir()
+	script := filepath.Join(dir, "crash.sh")
+	content := "#!/usr/bin/env bash\ncase \"$*\" in *crash*) kill -SEGV $$;; esac\nexec ../../test/support/junit.sh \"$@\"\n"
+	if err := os.WriteFile(script, []byte(content), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	custom := NewCustom(RunnerConfig{
+		TestCommand: script + " {{resultPath}} {{testExamples}}",
+		ResultPath:  filepath.Join(dir, "custom.xml"),
+	})
+
+	// each test case fills half of a batch, so they are run in 2 invocations
+	long := strings.Repeat("a", minArgsSize/2)
+	testCases := []string{"first fail " + long, "second crash " + long}
+
+	got, err := custom.Run(testCases, 0)
+
+	signalError := new(ProcessSignaledError)
+	if !errors.As(err, &signalError) {
+		t.Errorf("Custom.Run() error = %v, want ProcessSignaledError", err)
+	}
+
+	// The failures of the invocations before the error are kept.
+	want := RunResult{
+		Status:      RunStatusError,
+		FailedTests: []string{"Fake::first fail " + long},
+	}
+
+	if diff := cmp.Diff(got, want, cmpopts.IgnoreFields(RunResult{}, "Tests")); diff != "" {
+		t.Errorf("Custom.Run() diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestCustomRun_BatchResultPaths(t *testing.T) {
+	setMaxArgsSize(t, minArgsSize)
+
+	dir := t.TempDir()
+	custom := NewCustom(RunnerConfig{
+		TestCommand:      "../../test/support/junit.sh {{resultPath}} {{testExamples}}",
+		RetryTestCommand: "../../test/support/junit.sh {{resultPath}} {{failedTests}}",
+		ResultPath:       filepath.Join(dir, "custom-{{attempt}}.xml"),
+	})
+
+	// each test case fills half of a batch, so they are run in 2 invocations
+	long := strings.Repeat("a", minArgsSize/2)
+	if _, err := custom.Run([]string{"first " + long, "second fail " + long}, 0); err != nil {
+		t.Errorf("Custom.Run() error = %v", err)
+	}
+
+	// The first run of another batch of a test queue.
+	if _, err := custom.Run([]string{"third"}, 0); err != nil {
+		t.Errorf("Custom.Run() error = %v", err)
+	}
+
+	if _, err := custom.Run([]string{"Fake::second fail " + long}, 1); err != nil {
+		t.Errorf("Custom.Run() error = %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, entry := range entries {
+		got = append(got, entry.Name())
+	}
+
+	want := []string{"custom-0-1.xml", "custom-0-2.xml", "custom-0.xml", "custom-1.xml"}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("result files diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestCustomRun_TestExamplesFile(t *testing.T) {
+	copyPath := filepath.Join(t.TempDir(), "examples.txt")
+	custom := NewCustom(RunnerConfig{
+		TestCommand: "cp {{testExamplesFile}} " + copyPath,
+	})
+
+	testCases := []string{"apple is red", "banana is yellow"}
+	got, err := custom.Run(testCases, 0)
+	if err != nil {
+		t.Errorf("Custom.Run(%q) error = %v", testCases, err)
+	}
+
+	if diff := cmp.Diff(got, RunResult{Status: RunStatusPassed}); diff != "" {
+		t.Errorf("Custom.Run(%q) diff (-got +want):\n%s", testCases, diff)
+	}
+
+	examples, err := os.ReadFile(copyPath)
+	if err != nil {
+		t.Fatalf("os.ReadFile(%q) error = %v", copyPath, err)
+	}
+
+	if diff := cmp.Diff(string(examples), "apple is red\nbanana is yellow\n"); diff != "" {
+		t.Errorf("test examples file diff (-got +want):\n%s", diff)
+	}
+}
@@ -1,16 +1,82 @@
 package runner
 
 import (
+	"errors"
+	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"os/signal"
+	"sync/atomic"
 	"syscall"
+	"time"
 )
 
-// runAndForwardSignal runs the command and forwards any signals received to the command.
-func runAndForwardSignal(cmd *exec.Cmd) error {
-	cmd.Stderr = os.Stderr
-	cmd.Stdout = os.Stdout
+// Timeout configures when a test command is considered to hang and is terminated.
+// The zero value never terminates the command.
+type Timeout struct {
+	// Run is the maximum duration of a test command, zero means no limit.
+	Run time.Duration
+	// Output is the maximum duration without any output from the test command, zero means no limit.
+	Output time.Duration
+	// GracePeriod is how long to wait for the command to exit after SIGTERM before sending SIGKILL.
+	GracePeriod time.Duration
+}
+
+func (t Timeout) enabled() bool {
+	return t.Run > 0 || t.Output > 0
+}
+
+// checkInterval returns how often the watchdog checks the timeouts,
+// which is often enough to terminate the command shortly after a timeout.
+func (t Timeout) checkInterval() time.Duration {
+	interval := time.Second
+	for _, d := range []time.Duration{t.Run, t.Output} {
+		if d > 0 && d/4 < interval {
+			interval = max(d/4, time.Millisecond)
+		}
+	}
+	return interval
+}
+
+// orphanWaitDelay is how long to wait for the output of the processes left behind by the command
+// after it exits, before they are killed.
+const orphanWaitDelay = time.Second
+
+// runAndForwardSignal runs the command in its own process group and forwards any signals
+// received to the process group, so that the processes started by the command, such as
+// the workers of the test runner, receive them too.
+// The command's stdout and stderr are forwarded to os.Stdout and os.Stderr,
+// unless they have already been set by the caller.
+//
+// If the command runs longer than timeout.Run, or has no output for longer than timeout.Output,
+// the process group of the command is sent SIGTERM, then SIGKILL after timeout.GracePeriod,
+// and a TimeoutError is returned.
+//
+// Any process left in the process group after the command exits is killed,
+// so that nothing is left behind after bktec exits.
+func runAndForwardSignal(cmd *exec.Cmd, timeout Timeout) error {
+	if cmd.Stderr == nil {
+		cmd.Stderr = os.Stderr
+	}
+	if cmd.Stdout == nil {
+		cmd.Stdout = os.Stdout
+	}
+
+	// lastOutput is the time of the last output in Unix nanoseconds, used by the watchdog.
+	var lastOutput atomic.Int64
+	if timeout.Output > 0 {
+		cmd.Stdout = &activityWriter{w: cmd.Stdout, lastOutput: &lastOutput}
+		cmd.Stderr = &activityWriter{w: cmd.Stderr, lastOutput: &lastOutput}
+	}
+
+	// The command is run in its own process group, so that the processes it starts
+	// can be signaled and killed together with it.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	// A process left behind can keep the output pipes open, which would block cmd.Wait,
+	// so the pipes are closed shortly after the command exits.
+	cmd.WaitDelay = orphanWaitDelay
 
 	// Create a channel that will be closed when the command finishes.
 	finishCh := make(chan struct{})
@@ -19,6 +85,7 @@ func runAndForwardSignal(cmd *exec.Cmd) error {
 	if err := cmd.Start(); err != nil {
 		return err
 	}
+	lastOutput.Store(time.Now().UnixNano())
 
 	// Start a goroutine that waits for a signal or the command to finish.
 	go func() {
@@ -39,8 +106,10 @@ func runAndForwardSignal(cmd *exec.Cmd) error {
 				if sig == syscall.SIGCHLD {
 					continue
 				}
-				// Ignore the error when sending the signal to the command.
-				_ = cmd.Process.Signal(sig)
+				// Ignore the error when sending the signal to the process group.
+				if sig, ok := sig.(syscall.Signal); ok {
+					_ = syscall.Kill(-cmd.Process.Pid, sig)
+				}
 			case <-finishCh:
 				// When the the command finishes, we stop listening for signals and return.
 				signal.Stop(sigCh)
@@ -49,9 +118,31 @@ func runAndForwardSignal(cmd *exec.Cmd) error {
 		}
 	}()
 
+	// Start a watchdog goroutine that terminates the command when it times out.
+	// The timeout error is sent before the command is terminated, so it's received after cmd.Wait returns.
+	timeoutCh := make(chan *TimeoutError, 1)
+	if timeout.enabled() {
+		go watchdog(cmd, timeout, &lastOutput, finishCh, timeoutCh)
+	}
+
 	// Wait for the command to finish.
 	err := cmd.Wait()
 
+	// Kill the processes left in the process group. A negative pid signals the whole process group,
+	// and the error is ignored because the process group no longer exists when nothing is left.
+	_ = syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+
+	// The command exited successfully, but a process left behind kept the output open.
+	if errors.Is(err, exec.ErrWaitDelay) {
+		err = nil
+	}
+
+	select {
+	case timeoutErr := <-timeoutCh:
+		return timeoutErr
+	default:
+	}
+
 	if err != nil {
 		// If the command was signaled, return a ProcessProcessSignaledError.
 		if exitError, ok := err.(*exec.ExitError); ok {
@@ -64,3 +155,55 @@ func runAndForwardSignal(cmd *exec.Cmd) error {
 
 	return nil
 }
+
+// watchdog checks the timeouts until the command finishes. When a timeout is reached,
+// it sends the TimeoutError to timeoutCh and terminates the process group of the command.
+func watchdog(cmd *exec.Cmd, timeout Timeout, lastOutput *atomic.Int64, finishCh <-chan struct{}, timeoutCh chan<- *TimeoutError) {
+	start := time.Now()
+	ticker := time.NewTicker(timeout.checkInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-finishCh:
+			return
+		case now := <-ticker.C:
+			var timeoutErr *TimeoutError
+			if timeout.Run > 0 && now.Sub(start) >= timeout.Run {
+				timeoutErr = &TimeoutError{Reason: fmt.Sprintf("ran for more than %s", timeout.Run)}
+			} else if timeout.Output > 0 && now.Sub(time.Unix(0, lastOutput.Load())) >= timeout.Output {
+				timeoutErr = &TimeoutError{Reason: fmt.Sprintf("had no output for %s", timeout.Output)}
+			}
+
+			if timeoutErr == nil {
+				continue
+			}
+
+			timeoutCh <- timeoutErr
+			fmt.Fprintf(os.Stderr, "Buildkite Test Engine Client: Command %s, terminating it\n", timeoutErr.Reason)
+
+			// A negative pid signals the whole process group.
+			pgid := -cmd.Process.Pid
+			_ = syscall.Kill(pgid, syscall.SIGTERM)
+
+			select {
+			case <-finishCh:
+			case <-time.After(timeout.GracePeriod):
+				fmt.Fprintf(os.Stderr, "Buildkite Test Engine Client: Command didn't exit within %s, killing it\n", timeout.GracePeriod)
+				_ = syscall.Kill(pgid, syscall.SIGKILL)
+			}
+			return
+		}
+	}
+}
+
+// activityWriter records the time of each write, so the watchdog can detect a command without output.
+type activityWriter struct {
+	w          io.Writer
+	lastOutput *atomic.Int64
+}
+
+func (a *activityWriter) Write(p []byte) (int, error) {
+	a.lastOutput.Store(time.Now().UnixNano())
+	return a.w.Write(p)
+}
@@ -4,6 +4,9 @@ import (
 	"errors"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"syscall"
 	"testing"
 	"time"
@@ -12,7 +15,7 @@ import (
 func TestRunAndForwardSignal(t *testing.T) {
 	cmd := exec.Command("echo", "hello world")
 
-	err := runAndForwardSignal(cmd)
+	err := runAndForwardSignal(cmd, Timeout{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -21,7 +24,7 @@ func TestRunAndForwardSignal(t *testing.T) {
 func TestRunAndForwardSignal_CommandExitsWithNonZero(t *testing.T) {
 	cmd := exec.Command("false")
 
-	err := runAndForwardSignal(cmd)
+	err := runAndForwardSignal(cmd, Timeout{})
 	exitError := new(exec.ExitError)
 	if !errors.As(err, &exitError) {
 		t.Fatalf("runAndForwardSignal(cmd) error type = %T (%v), want  *exec.ExitError", err, err)
@@ -45,7 +48,7 @@ func TestRunAndForwardSignal_SignalReceivedInMainProcess(t *testing.T) {
 		process.Signal(syscall.SIGTERM)
 	}()
 
-	err := runAndForwardSignal(cmd)
+	err := runAndForwardSignal(cmd, Timeout{})
 
 	signalError := new(ProcessSignaledError)
 	if !errors.As(err, &signalError) {
@@ -59,7 +62,7 @@ func TestRunAndForwardSignal_SignalReceivedInMainProcess(t *testing.T) {
 func TestRunAndForwardSignal_SignalReceivedInSubProcess(t *testing.T) {
 	cmd := exec.Command("../../test/support/segv.sh")
 
-	err := runAndForwardSignal(cmd)
+	err := runAndForwardSignal(cmd, Timeout{})
 
 	signalError := new(ProcessSignaledError)
 	if !errors.As(err, &signalError) {
@@ -69,3 +72,155 @@ func TestRunAndForwardSignal_SignalReceivedInSubProcess(t *testing.T) {
 		t.Errorf("runAndForwardSignal(cmd) signal = %d, want  %d", syscall.SIGSEGV, signalError.Signal)
 	}
 }
+
+func TestRunAndForwardSignal_Timeout(t *testing.T) {
+	cmd := exec.Command("sleep", "10")
+
+	start := time.Now()
+	err := runAndForwardSignal(cmd, Timeout{Run: 200 * time.Millisecond, GracePeriod: time.Second})
+
+	timeoutError := new(TimeoutError)
+	if !errors.As(err, &timeoutError) {
+		t.Fatalf("runAndForwardSignal(cmd) error type = %T (%v), want *TimeoutError", err, err)
+	}
+	if got, want := timeoutError.Error(), "command ran for more than 200ms"; got != want {
+		t.Errorf("runAndForwardSignal(cmd) error = %q, want %q", got, want)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("runAndForwardSignal(cmd) took %s, want the command to be terminated", elapsed)
+	}
+}
+
+func TestRunAndForwardSignal_OutputTimeout(t *testing.T) {
+	cmd := exec.Command("sh", "-c", "echo started; sleep 0.1; echo still running; sleep 10")
+
+	err := runAndForwardSignal(cmd, Timeout{Output: 500 * time.Millisecond, GracePeriod: time.Second})
+
+	timeoutError := new(TimeoutError)
+	if !errors.As(err, &timeoutError) {
+		t.Fatalf("runAndForwardSignal(cmd) error type = %T (%v), want *TimeoutError", err, err)
+	}
+	if got, want := timeoutError.Error(), "command had no output for 500ms"; got != want {
+		t.Errorf("runAndForwardSignal(cmd) error = %q, want %q", got, want)
+	}
+}
+
+func TestRunAndForwardSignal_OutputTimeoutNotReached(t *testing.T) {
+	cmd := exec.Command("sh", "-c", "for i in 1 2 3 4 5; do echo $i; sleep 0.1; done")
+
+	err := runAndForwardSignal(cmd, Timeout{Output: 500 * time.Millisecond, GracePeriod: time.Second})
+	if err != nil {
+		t.Errorf("runAndForwardSignal(cmd) error = %v", err)
+	}
+}
+
+func TestRunAndForwardSignal_TimeoutKillsAfterGracePeriod(t *testing.T) {
+	// The shell and its children ignore SIGTERM, so they have to be killed.
+	cmd := exec.Command("sh", "-c", `trap "" TERM; sleep 10`)
+
+	start := time.Now()
+	err := runAndForwardSignal(cmd, Timeout{Run: 200 * time.Millisecond, GracePeriod: 300 * time.Millisecond})
+
+	timeoutError := new(TimeoutError)
+	if !errors.As(err, &timeoutError) {
+		t.Fatalf("runAndForwardSignal(cmd) error type = %T (%v), want *TimeoutError", err, err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("runAndForwardSignal(cmd) took %s, want the command to be killed after the grace period", elapsed)
+	}
+}
+
+// readChildPid waits for the fixture script to write the pid of its child.
+func readChildPid(t *testing.T, path string) int {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		data, err := os.ReadFile(path)
+		if err == nil && strings.HasSuffix(string(data), "\n") {
+			pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+			if err != nil {
+				t.Fatalf("invalid pid %q: %v", data, err)
+			}
+			return pid
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("%s was not written", path)
+	return 0
+}
+
+// waitForProcessExit returns whether the process exits within a few seconds.
+// A zombie process is considered exited, because it's only waiting to be reaped.
+func waitForProcessExit(pid int) bool {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		output, err := exec.Command("ps", "-o", "stat=", "-p", strconv.Itoa(pid)).Output()
+		if err != nil || strings.HasPrefix(strings.TrimSpace(string(output)), "Z") {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return false
+}
+
+func TestRunAndForwardSignal_SignalForwardedToChildren(t *testing.T) {
+	pidPath := filepath.Join(t.TempDir(), "child.pid")
+	cmd := exec.Command("../../test/support/spawn_children.sh", pidPath, "wait")
+
+	// Send a SIGTERM signal to the main process once the child has started.
+	childPid := make(chan int, 1)
+	go func() {
+		pid := readChildPid(t, pidPath)
+		childPid <- pid
+		syscall.Kill(os.Getpid(), syscall.SIGTERM)
+	}()
+
+	err := runAndForwardSignal(cmd, Timeout{})
+
+	signalError := new(ProcessSignaledError)
+	if !errors.As(err, &signalError) {
+		t.Errorf("runAndForwardSignal(cmd) error type = %T (%v), want *ProcessSignaledError", err, err)
+	}
+
+	if pid := <-childPid; !waitForProcessExit(pid) {
+		syscall.Kill(pid, syscall.SIGKILL)
+		t.Errorf("child process %d is still running after runAndForwardSignal(cmd) returned", pid)
+	}
+}
+
+func TestRunAndForwardSignal_ChildrenLeftBehindAreKilled(t *testing.T) {
+	pidPath := filepath.Join(t.TempDir(), "child.pid")
+	cmd := exec.Command("../../test/support/spawn_children.sh", pidPath, "exit")
+
+	start := time.Now()
+	err := runAndForwardSignal(cmd, Timeout{})
+	if err != nil {
+		t.Errorf("runAndForwardSignal(cmd) error = %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("runAndForwardSignal(cmd) took %s, want it to return shortly after the command exits", elapsed)
+	}
+
+	if pid := readChildPid(t, pidPath); !waitForProcessExit(pid) {
+		syscall.Kill(pid, syscall.SIGKILL)
+		t.Errorf("child process %d is still running after runAndForwardSignal(cmd) returned", pid)
+	}
+}
+
+func TestRunAndForwardSignal_TimeoutKillsChildren(t *testing.T) {
+	pidPath := filepath.Join(t.TempDir(), "child.pid")
+	cmd := exec.Command("../../test/support/spawn_children.sh", pidPath, "wait")
+
+	err := runAndForwardSignal(cmd, Timeout{Run: 300 * time.Millisecond, GracePeriod: time.Second})
+
+	timeoutError := new(TimeoutError)
+	if !errors.As(err, &timeoutError) {
+		t.Errorf("runAndForwardSignal(cmd) error type = %T (%v), want *TimeoutError", err, err)
+	}
+
+	if pid := readChildPid(t, pidPath); !waitForProcessExit(pid) {
+		syscall.Kill(pid, syscall.SIGKILL)
+		t.Errorf("child process %d is still running after runAndForwardSignal(cmd) returned", pid)
+	}
+}
@@ -0,0 +1,185 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"github.com/buildkite/test-engine-client/internal/debug"
+	"github.com/buildkite/test-engine-client/internal/plan"
+	"github.com/kballard/go-shellquote"
+)
+
+var _ = TestRunner(Custom{})
+
+// Custom is a generic runner for any test framework that can write a JUnit XML report,
+// such as Minitest, PHPUnit or Mocha.
+//
+// There is no default test command nor test file pattern, they have to be configured.
+// Failed tests are identified by the class name and the name of the JUnit test case,
+// e.g. "AppleTest::test_is_red", and are filled in the "{{failedTests}}" placeholder of the retry command.
+type Custom struct {
+	RunnerConfig
+}
+
+func NewCustom(c RunnerConfig) Custom {
+	c.invocations = newInvocationCounter()
+	return Custom{c}
+}
+
+func (c Custom) Name() string {
+	return "Custom runner"
+}
+
+// GetFiles returns an array of file names using the discovery pattern.
+func (c Custom) GetFiles() ([]string, error) {
+	debug.Println("Discovering test files with include pattern:", c.TestFilePattern, "exclude pattern:", c.TestFileExcludePattern)
+	files, err := discoverTestFiles(c.TestFilePattern, c.TestFileExcludePattern)
+	debug.Println("Discovered", len(files), "files")
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no files found with pattern %q and exclude pattern %q", c.TestFilePattern, c.TestFileExcludePattern)
+	}
+
+	return files, nil
+}
+
+// Run executes the test command with the given test cases.
+// If attempt is greater than zero, it will run the retry test command with the "{{failedTests}}"
+// placeholder replaced by the given test cases, otherwise it will run the test command
+// with the "{{testExamples}}" placeholder replaced by the given test cases.
+//
+// Error is returned if the command fails to run, exits prematurely, or if the
+// JUnit XML report cannot be parsed.
+//
+// Test failure is not considered an error, and is instead returned as a RunResult.
+//
+// The test cases are run in several invocations if they don't fit in the arguments
+// of a single one, see runInBatches.
+func (c Custom) Run(testCases []string, attempt int) (RunResult, error) {
+	command := c.TestCommand
+	placeholder := "{{testExamples}}"
+
+	if attempt > 0 {
+		command = c.RetryTestCommand
+		placeholder = "{{failedTests}}"
+	}
+
+	return runInBatches(command, testCases, func(command string, testCases []string) (RunResult, error) {
+		invocation := c
+		invocation.ResultPath = c.invocationResultPath(attempt)
+		return invocation.run(command, placeholder, testCases)
+	})
+}
+
+// run executes the command with the given test cases and reads the result from the result path.
+func (c Custom) run(command string, placeholder string, testCases []string) (RunResult, error) {
+	commandName, commandArgs, err := c.commandNameAndArgs(command, placeholder, testCases)
+	if err != nil {
+		return RunResult{Status: RunStatusError}, fmt.Errorf("failed to build command: %w", err)
+	}
+
+	fmt.Printf("%s %s\n", commandName, strings.Join(commandArgs, " "))
+	cmd := exec.Command(commandName, commandArgs...)
+	cmd.Env = c.commandEnv()
+
+	err = runAndForwardSignal(cmd, c.Timeout)
+
+	if err == nil { // note: returning success early
+		return RunResult{Status: RunStatusPassed, Tests: readTestResults(c.ResultPath, ParseJUnitReport, customTestResults)}, nil
+	}
+
+	if ProcessSignaledError := new(ProcessSignaledError); errors.As(err, &ProcessSignaledError) {
+		return RunResult{Status: RunStatusError}, err
+	}
+
+	if timeoutError := new(TimeoutError); errors.As(err, &timeoutError) {
+		return RunResult{Status: RunStatusTimeout}, err
+	}
+
+	if exitError := new(exec.ExitError); errors.As(err, &exitError) {
+		report, parseErr := ParseJUnitReport(c.ResultPath)
+		if parseErr != nil {
+			fmt.Println("Buildkite Test Engine Client: Failed to read JUnit XML output, tests will not be retried.")
+			return RunResult{Status: RunStatusError}, err
+		}
+
+		tests := customTestResults(report)
+
+		var failedTests []string
+		for _, test := range tests {
+			if test.Status == TestStatusFailed {
+				failedTests = append(failedTests, test.Id)
+			}
+		}
+
+		if len(failedTests) > 0 {
+			return RunResult{Status: RunStatusFailed, FailedTests: failedTests, Tests: tests}, nil
+		}
+	}
+
+	return RunResult{Status: RunStatusError}, err
+}
+
+// customTestResults returns the results of the test cases in the report, see customTestID.
+func customTestResults(report JUnitReport) []TestResult {
+	var tests []TestResult
+	for _, testCase := range report.TestCases() {
+		tests = append(tests, testCase.testResult(customTestID(testCase)))
+	}
+	return tests
+}
+
+// customTestID identifies the test case by its class name and name, e.g. "AppleTest::test_is_red",
+// because test cases of different classes often have the same name.
+// The file is used instead of the class name when the report doesn't have it,
+// and the name alone when it has neither.
+func customTestID(testCase JUnitTestCase) string {
+	switch {
+	case testCase.Classname != "":
+		return testCase.Classname + "::" + testCase.Name
+	case testCase.File != "":
+		return testCase.File + "::" + testCase.Name
+	default:
+		return testCase.Name
+	}
+}
+
+// commandNameAndArgs replaces the given placeholder in the command with the test cases,
+// or appends them to the command if there is no placeholder.
+// It also replaces the "{{resultPath}}" placeholder with the result path.
+// It returns the command name and arguments to run the tests.
+func (c Custom) commandNameAndArgs(cmd string, placeholder string, testCases []string) (string, []string, error) {
+	words, err := shellquote.Split(cmd)
+	if err != nil {
+		return "", []string{}, err
+	}
+
+	if len(words) == 0 {
+		return "", []string{}, errors.New("command is empty")
+	}
+
+	idx := slices.Index(words, placeholder)
+	if idx < 0 {
+		words = append(words, testCases...)
+	} else {
+		words = slices.Replace(words, idx, idx+1, testCases...)
+	}
+
+	idx = slices.Index(words, "{{resultPath}}")
+	if idx >= 0 {
+		words = slices.Replace(words, idx, idx+1, c.ResultPath)
+	}
+
+	return words[0], words[1:], nil
+}
+
+func (c Custom) GetExamples(files []string) ([]plan.TestCase, error) {
+	return nil, fmt.Errorf("not supported in custom runner")
+}
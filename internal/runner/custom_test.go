@@ -0,0 +1,297 @@
+package runner
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCustomGetFiles(t *testing.T) {
+	custom := NewCustom(RunnerConfig{
+		TestFilePattern:        "fixtures/**/*_test",
+		TestFileExcludePattern: "fixtures/animals",
+	})
+
+	got, err := custom.GetFiles()
+	if err != nil {
+		t.Errorf("Custom.GetFiles() error = %v", err)
+	}
+
+	want := []string{
+		"fixtures/fruits/apple_test",
+		"fixtures/fruits/banana_test",
+		"fixtures/vegetable_test",
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("Custom.GetFiles() diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestCustomRun(t *testing.T) {
+	custom := NewCustom(RunnerConfig{
+		TestCommand: "../../test/support/junit.sh {{resultPath}} {{testExamples}}",
+		ResultPath:  "custom.xml",
+	})
+
+	t.Cleanup(func() {
+		os.Remove(custom.ResultPath)
+	})
+
+	testCases := []string{"apple is red", "banana is yellow"}
+	got, err := custom.Run(testCases, 0)
+
+	want := RunResult{
+		Status: RunStatusPassed,
+		Tests: []TestResult{
+			{Id: "Fake::apple is red", Name: "apple is red", Scope: "Fake", Status: TestStatusPassed},
+			{Id: "Fake::banana is yellow", Name: "banana is yellow", Scope: "Fake", Status: TestStatusPassed},
+		},
+	}
+
+	if err != nil {
+		t.Errorf("Custom.Run(%q) error = %v", testCases, err)
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("Custom.Run(%q) diff (-got +want):\n%s", testCases, diff)
+	}
+}
+
+func TestCustomRun_TestFailed(t *testing.T) {
+	custom := NewCustom(RunnerConfig{
+		TestCommand: "../../test/support/junit.sh {{resultPath}} {{testExamples}}",
+		ResultPath:  "custom.xml",
+	})
+
+	t.Cleanup(func() {
+		os.Remove(custom.ResultPath)
+	})
+
+	testCases := []string{"apple is red", "banana will fail", "cherry will fail"}
+	got, err := custom.Run(testCases, 0)
+
+	want := RunResult{
+		Status:      RunStatusFailed,
+		FailedTests: []string{"Fake::banana will fail", "Fake::cherry will fail"},
+		Tests: []TestResult{
+			{Id: "Fake::apple is red", Name: "apple is red", Scope: "Fake", Status: TestStatusPassed},
+			{Id: "Fake::banana will fail", Name: "banana will fail", Scope: "Fake", Status: TestStatusFailed},
+			{Id: "Fake::cherry will fail", Name: "cherry will fail", Scope: "Fake", Status: TestStatusFailed},
+		},
+	}
+
+	if err != nil {
+		t.Errorf("Custom.Run(%q) error = %v", testCases, err)
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("Custom.Run(%q) diff (-got +want):\n%s", testCases, diff)
+	}
+}
+
+func TestCustomRun_Retry(t *testing.T) {
+	custom := NewCustom(RunnerConfig{
+		TestCommand:      "false",
+		RetryTestCommand: "../../test/support/junit.sh {{resultPath}} {{failedTests}} durian",
+		ResultPath:       "custom.xml",
+	})
+
+	t.Cleanup(func() {
+		os.Remove(custom.ResultPath)
+	})
+
+	testCases := []string{"Fake::banana will fail"}
+	got, err := custom.Run(testCases, 1)
+
+	want := RunResult{
+		Status:      RunStatusFailed,
+		FailedTests: []string{"Fake::banana will fail"},
+		Tests: []TestResult{
+			{Id: "Fake::banana will fail", Name: "banana will fail", Scope: "Fake", Status: TestStatusFailed},
+			{Id: "Fake::durian", Name: "durian", Scope: "Fake", Status: TestStatusPassed},
+		},
+	}
+
+	if err != nil {
+		t.Errorf("Custom.Run(%q) error = %v", testCases, err)
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("Custom.Run(%q) diff (-got +want):\n%s", testCases, diff)
+	}
+}
+
+func TestCustomRun_AttemptResultPath(t *testing.T) {
+	dir := t.TempDir()
+	custom := NewCustom(RunnerConfig{
+		TestCommand:      "../../test/support/junit.sh {{resultPath}} {{testExamples}}",
+		RetryTestCommand: "../../test/support/junit.sh {{resultPath}} {{failedTests}}",
+		ResultPath:       filepath.Join(dir, "custom-{{attempt}}.xml"),
+	})
+
+	testCases := []string{"apple is red", "banana will fail"}
+	if _, err := custom.Run(testCases, 0); err != nil {
+		t.Errorf("Custom.Run(%q, 0) error = %v", testCases, err)
+	}
+
+	retryCases := []string{"Fake::banana will fail"}
+	got, err := custom.Run(retryCases, 1)
+	if err != nil {
+		t.Errorf("Custom.Run(%q, 1) error = %v", retryCases, err)
+	}
+
+	want := RunResult{
+		Status:      RunStatusFailed,
+		FailedTests: []string{"Fake::banana will fail"},
+		Tests: []TestResult{
+			{Id: "Fake::banana will fail", Name: "banana will fail", Scope: "Fake", Status: TestStatusFailed},
+		},
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("Custom.Run(%q, 1) diff (-got +want):\n%s", retryCases, diff)
+	}
+
+	for _, name := range []string{"custom-0.xml", "custom-1.xml"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("os.Stat(%q) error = %v", name, err)
+		}
+	}
+}
+
+func TestCustomRun_CommandFailed(t *testing.T) {
+	custom := NewCustom(RunnerConfig{
+		TestCommand: "false",
+		ResultPath:  "custom.xml",
+	})
+
+	testCases := []string{"apple is red"}
+	got, err := custom.Run(testCases, 0)
+
+	want := RunResult{
+		Status: RunStatusError,
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("Custom.Run(%q) diff (-got +want):\n%s", testCases, diff)
+	}
+
+	exitError := new(exec.ExitError)
+	if !errors.As(err, &exitError) {
+		t.Errorf("Custom.Run(%q) error type = %T (%v), want *exec.ExitError", testCases, err, err)
+	}
+}
+
+func TestCustomRun_SignaledError(t *testing.T) {
+	custom := NewCustom(RunnerConfig{
+		TestCommand: "../../test/support/segv.sh",
+	})
+	testCases := []string{"apple is red"}
+
+	got, err := custom.Run(testCases, 0)
+
+	want := RunResult{
+		Status: RunStatusError,
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("Custom.Run(%q) diff (-got +want):\n%s", testCases, diff)
+	}
+
+	signalError := new(ProcessSignaledError)
+	if !errors.As(err, &signalError) {
+		t.Errorf("Custom.Run(%q) error type = %T (%v), want *ErrProcessSignaled", testCases, err, err)
+	}
+	if signalError.Signal != syscall.SIGSEGV {
+		t.Errorf("Custom.Run(%q) signal = %d, want %d", testCases, syscall.SIGSEGV, signalError.Signal)
+	}
+}
+
+func TestCustomCommandNameAndArgs(t *testing.T) {
+	cases := []struct {
+		command     string
+		placeholder string
+		wantArgs    []string
+	}{
+		{
+			command:     "phpunit --log-junit {{resultPath}} {{testExamples}}",
+			placeholder: "{{testExamples}}",
+			wantArgs:    []string{"--log-junit", "junit.xml", "testA", "testB"},
+		},
+		{
+			command:     "phpunit --log-junit {{resultPath}} --filter {{failedTests}}",
+			placeholder: "{{failedTests}}",
+			wantArgs:    []string{"--log-junit", "junit.xml", "--filter", "testA", "testB"},
+		},
+		// without placeholder
+		{
+			command:     "phpunit --log-junit {{resultPath}}",
+			placeholder: "{{failedTests}}",
+			wantArgs:    []string{"--log-junit", "junit.xml", "testA", "testB"},
+		},
+	}
+
+	custom := NewCustom(RunnerConfig{
+		ResultPath: "junit.xml",
+	})
+	testCases := []string{"testA", "testB"}
+
+	for _, c := range cases {
+		gotName, gotArgs, err := custom.commandNameAndArgs(c.command, c.placeholder, testCases)
+		if err != nil {
+			t.Errorf("commandNameAndArgs(%q, %q, %q) error = %v", c.command, c.placeholder, testCases, err)
+		}
+
+		if diff := cmp.Diff(gotName, "phpunit"); diff != "" {
+			t.Errorf("commandNameAndArgs(%q, %q, %q) diff (-got +want):\n%s", c.command, c.placeholder, testCases, diff)
+		}
+		if diff := cmp.Diff(gotArgs, c.wantArgs); diff != "" {
+			t.Errorf("commandNameAndArgs(%q, %q, %q) diff (-got +want):\n%s", c.command, c.placeholder, testCases, diff)
+		}
+	}
+}
+
+func TestCustomCommandNameAndArgs_EmptyCommand(t *testing.T) {
+	custom := NewCustom(RunnerConfig{})
+
+	_, _, err := custom.commandNameAndArgs("", "{{testExamples}}", []string{"testA"})
+	if err == nil {
+		t.Errorf("commandNameAndArgs(%q) error = nil, want error", "")
+	}
+}
+
+func TestCustomTestID(t *testing.T) {
+	cases := []struct {
+		testCase JUnitTestCase
+		want     string
+	}{
+		{
+			testCase: JUnitTestCase{Classname: "AppleTest", Name: "test_is_red", File: "test/apple_test.rb"},
+			want:     "AppleTest::test_is_red",
+		},
+		{
+			testCase: JUnitTestCase{Classname: "BananaTest", Name: "test_is_red", File: "test/banana_test.rb"},
+			want:     "BananaTest::test_is_red",
+		},
+		{
+			testCase: JUnitTestCase{Name: "is red", File: "test/cherry.test.js"},
+			want:     "test/cherry.test.js::is red",
+		},
+		{
+			testCase: JUnitTestCase{Name: "is red"},
+			want:     "is red",
+		},
+	}
+
+	for _, c := range cases {
+		if got := customTestID(c.testCase); got != c.want {
+			t.Errorf("customTestID(%+v) = %q, want %q", c.testCase, got, c.want)
+		}
+	}
+}
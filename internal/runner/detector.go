@@ -2,6 +2,10 @@ package runner
 
 import (
 	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
 
 	"github.com/buildkite/test-engine-client/internal/config"
 	"github.com/buildkite/test-engine-client/internal/plan"
@@ -14,15 +18,86 @@ type RunnerConfig struct {
 	TestFileExcludePattern string
 	RetryTestCommand       string
 	ResultPath             string
+	// Env is the additional environment variables of the test command, in the form "key=value".
+	Env []string
+	// Timeout is when the test command is considered to hang and is terminated.
+	Timeout Timeout
+	// invocations counts the invocations of the test command of each attempt, see invocationResultPath.
+	// It's shared by the copies of the config, so the count carries over multiple runs of the same attempt,
+	// e.g. a batch of a test queue.
+	invocations *invocationCounter
+}
+
+// commandEnv returns the environment of the test command, which is the environment
+// of bktec with the additional environment variables.
+// It returns nil when there are no additional variables, so that the environment is inherited.
+func (r RunnerConfig) commandEnv() []string {
+	if len(r.Env) == 0 {
+		return nil
+	}
+	return append(os.Environ(), r.Env...)
+}
+
+// attemptPlaceholder is replaced by the attempt number in the result path,
+// so that each attempt keeps its own result file, e.g. "tmp/rspec-{{attempt}}.json".
+const attemptPlaceholder = "{{attempt}}"
+
+// attemptResultPath returns the result path of the attempt, which is zero for the first run
+// of the tests and the number of the retry otherwise.
+func (r RunnerConfig) attemptResultPath(attempt int) string {
+	return r.invocationPath(attempt, 0)
+}
+
+// invocationResultPath returns the result path of the next invocation of the test command in the attempt.
+// The first invocation of an attempt writes to the result path of the attempt, e.g. "tmp/rspec-0.json",
+// and the following ones add the number of the invocation, e.g. "tmp/rspec-0-1.json", so that an attempt
+// run in several invocations keeps the result of each one.
+func (r RunnerConfig) invocationResultPath(attempt int) string {
+	if r.invocations == nil {
+		return r.attemptResultPath(attempt)
+	}
+	return r.invocationPath(attempt, r.invocations.next(attempt))
+}
+
+// invocationPath returns the result path of the invocation of the test command in the attempt,
+// see invocationResultPath.
+func (r RunnerConfig) invocationPath(attempt int, invocation int) string {
+	id := strconv.Itoa(attempt)
+	if invocation > 0 {
+		id = fmt.Sprintf("%d-%d", attempt, invocation)
+	}
+	return strings.ReplaceAll(r.ResultPath, attemptPlaceholder, id)
+}
+
+// invocationCounter counts the invocations of the test command of each attempt.
+// It's not safe for concurrent use, the invocations of a runner are sequential.
+type invocationCounter struct {
+	counts map[int]int
+}
+
+func newInvocationCounter() *invocationCounter {
+	return &invocationCounter{counts: map[int]int{}}
+}
+
+// next returns the zero-based number of the next invocation of the attempt.
+func (c *invocationCounter) next(attempt int) int {
+	n := c.counts[attempt]
+	c.counts[attempt]++
+	return n
 }
 
 type TestRunner interface {
-	Run(testCases []string, retry bool) (RunResult, error)
+	// Run runs the test cases. The attempt is zero for the first run of the tests,
+	// and the number of the retry when the failed tests are retried.
+	Run(testCases []string, attempt int) (RunResult, error)
 	GetExamples(files []string) ([]plan.TestCase, error)
 	GetFiles() ([]string, error)
 	Name() string
 }
 
+// DetectRunner returns the test runner of the configuration.
+// When more than one worker is configured, it returns a Parallel runner
+// with a test runner for each worker.
 func DetectRunner(cfg config.Config) (TestRunner, error) {
 	var runnerConfig = RunnerConfig{
 		TestRunner:             cfg.TestRunner,
@@ -31,14 +106,42 @@ func DetectRunner(cfg config.Config) (TestRunner, error) {
 		TestFileExcludePattern: cfg.TestFileExcludePattern,
 		RetryTestCommand:       cfg.RetryCommand,
 		ResultPath:             cfg.ResultPath,
+		Timeout: Timeout{
+			Run:         cfg.Timeout,
+			Output:      cfg.OutputTimeout,
+			GracePeriod: cfg.TimeoutGracePeriod,
+		},
 	}
 
-	switch cfg.TestRunner {
+	if cfg.Workers <= 1 {
+		return newRunner(runnerConfig)
+	}
+
+	workers := make([]TestRunner, 0, cfg.Workers)
+	for i := 0; i < cfg.Workers; i++ {
+		workerRunner, err := newRunner(workerRunnerConfig(runnerConfig, i))
+		if err != nil {
+			return nil, err
+		}
+		workers = append(workers, workerRunner)
+	}
+
+	return NewParallel(workers), nil
+}
+
+func newRunner(runnerConfig RunnerConfig) (TestRunner, error) {
+	switch runnerConfig.TestRunner {
 	case "rspec":
 		return NewRspec(runnerConfig), nil
 	case "jest":
 		return NewJest(runnerConfig), nil
+	case "pytest":
+		return NewPytest(runnerConfig), nil
+	case "gotest":
+		return NewGoTest(runnerConfig), nil
+	case "custom":
+		return NewCustom(runnerConfig), nil
 	default:
-		return nil, errors.New("runner value is invalid, possible values are 'rspec', 'jest'")
+		return nil, errors.New("runner value is invalid, possible values are 'rspec', 'jest', 'pytest', 'gotest', 'custom'")
 	}
 }
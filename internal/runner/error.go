@@ -12,3 +12,13 @@ type ProcessSignaledError struct {
 func (e *ProcessSignaledError) Error() string {
 	return fmt.Sprintf("process was signaled with signal %d", e.Signal)
 }
+
+// TimeoutError is returned when the test command is terminated because it ran for too long
+// or had no output for too long, see Timeout.
+type TimeoutError struct {
+	Reason string
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("command %s", e.Reason)
+}
@@ -0,0 +1,11 @@
+package apple
+
+import "testing"
+
+func TestIsRed(t *testing.T) {
+	t.Run("when ripe", func(t *testing.T) {})
+}
+
+func TestIsFruit(t *testing.T) {}
+
+func BenchmarkEat(b *testing.B) {}
@@ -0,0 +1,12 @@
+package banana
+
+import "testing"
+
+func TestIsYellow(t *testing.T) {
+	t.Run("when ripe", func(t *testing.T) {})
+	t.Run("when not ripe", func(t *testing.T) {
+		t.Error("banana is green")
+	})
+}
+
+func TestIsCurved(t *testing.T) {}
@@ -0,0 +1,3 @@
+module example.com/fruits
+
+go 1.21
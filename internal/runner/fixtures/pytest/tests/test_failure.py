@@ -0,0 +1,2 @@
+def test_this_will_fail_for_sure():
+    assert 1 == 2
@@ -0,0 +1,6 @@
+class TestExpelliarmus:
+    def test_disarms_the_opponent(self):
+        assert True
+
+    def test_knocks_the_wand_out_of_the_opponents_hand(self):
+        assert True
@@ -0,0 +1,460 @@
+package runner
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/buildkite/test-engine-client/internal/debug"
+	"github.com/buildkite/test-engine-client/internal/plan"
+	"github.com/kballard/go-shellquote"
+)
+
+var _ = TestRunner(GoTest{})
+
+// GoTest runs Go tests using `go test -json`.
+//
+// Packages are the unit of splitting, so GetFiles returns package directories
+// rather than _test.go files. Individual tests are identified as
+// "<package>::<TestName>", e.g. "example.com/fruits/apple::TestIsRed",
+// which is used for split by example and for retrying failed tests.
+type GoTest struct {
+	RunnerConfig
+}
+
+// goTestSeparator separates the package from the test name in a test identifier.
+const goTestSeparator = "::"
+
+func NewGoTest(g RunnerConfig) GoTest {
+	if g.TestCommand == "" {
+		g.TestCommand = "go test -json {{testExamples}}"
+	}
+
+	if g.TestFilePattern == "" {
+		g.TestFilePattern = "**/*_test.go"
+	}
+
+	if g.TestFileExcludePattern == "" {
+		g.TestFileExcludePattern = "vendor"
+	}
+
+	if g.RetryTestCommand == "" {
+		g.RetryTestCommand = g.TestCommand
+	}
+
+	g.invocations = newInvocationCounter()
+	return GoTest{g}
+}
+
+func (g GoTest) Name() string {
+	return "go test"
+}
+
+// GetFiles returns an array of package directories that contain test files matching the discovery pattern.
+func (g GoTest) GetFiles() ([]string, error) {
+	debug.Println("Discovering test files with include pattern:", g.TestFilePattern, "exclude pattern:", g.TestFileExcludePattern)
+	files, err := discoverTestFiles(g.TestFilePattern, g.TestFileExcludePattern)
+	debug.Println("Discovered", len(files), "files")
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no files found with pattern %q and exclude pattern %q", g.TestFilePattern, g.TestFileExcludePattern)
+	}
+
+	// go test requires relative package paths to start with "./",
+	// otherwise they are treated as import paths.
+	packages := []string{}
+	for _, file := range files {
+		pkg := "./" + filepath.ToSlash(filepath.Dir(file))
+		if pkg == "./." {
+			pkg = "."
+		}
+		if !slices.Contains(packages, pkg) {
+			packages = append(packages, pkg)
+		}
+	}
+
+	debug.Println("Discovered", len(packages), "packages")
+
+	return packages, nil
+}
+
+// Run executes the test command with the given test cases.
+// If attempt is greater than zero, it will run the command using the retry test command,
+// otherwise it will use the test command.
+//
+// Test cases can be packages or individual tests in the form of "<package>::<TestName>".
+// Packages are run together in a single invocation, while individual tests are
+// grouped by package and run with `-run '^(TestA|TestB)$'`, one invocation per package.
+//
+// The `go test -json` output is written to the result path, and the output of the
+// tests is printed to stdout.
+//
+// Error is returned if the command fails to run, exits prematurely, or if the
+// output cannot be parsed.
+//
+// Test failure is not considered an error, and is instead returned as a RunResult.
+//
+// The test cases are run in several batches if they don't fit in the arguments
+// of a single invocation, see runInBatches, and the -run patterns that don't fit
+// in a single argument are split, see batchNames.
+func (g GoTest) Run(testCases []string, attempt int) (RunResult, error) {
+	command := g.TestCommand
+
+	if attempt > 0 {
+		command = g.RetryTestCommand
+	}
+
+	return runInBatches(command, testCases, func(command string, testCases []string) (RunResult, error) {
+		invocation := g
+		invocation.ResultPath = g.invocationResultPath(attempt)
+		return invocation.run(command, testCases)
+	})
+}
+
+// run runs the invocations of the test cases with the command, and reads the result from the result path.
+// The invocations of the packages and tests all write to the same result file.
+func (g GoTest) run(command string, testCases []string) (RunResult, error) {
+	resultFile, err := os.Create(g.ResultPath)
+	if err != nil {
+		return RunResult{Status: RunStatusError}, fmt.Errorf("failed to create result file: %w", err)
+	}
+	defer resultFile.Close()
+
+	var exitErr error
+	for _, args := range goTestInvocations(testCases) {
+		commandName, commandArgs, err := g.commandNameAndArgs(command, args)
+		if err != nil {
+			return RunResult{Status: RunStatusError}, fmt.Errorf("failed to build command: %w", err)
+		}
+
+		fmt.Printf("%s %s\n", commandName, strings.Join(commandArgs, " "))
+		cmd := exec.Command(commandName, commandArgs...)
+		cmd.Env = g.commandEnv()
+
+		output := newGoTestOutputWriter(resultFile, os.Stdout)
+		cmd.Stdout = output
+
+		err = runAndForwardSignal(cmd, g.Timeout)
+		output.Flush()
+
+		if err == nil {
+			continue
+		}
+
+		// A non-zero exit can be caused by test failures,
+		// so we carry on with the other invocations and check the output afterwards.
+		if exitError := new(exec.ExitError); errors.As(err, &exitError) {
+			exitErr = err
+			continue
+		}
+
+		if timeoutError := new(TimeoutError); errors.As(err, &timeoutError) {
+			return RunResult{Status: RunStatusTimeout}, err
+		}
+
+		return RunResult{Status: RunStatusError}, err
+	}
+
+	if exitErr == nil { // note: returning success early
+		return RunResult{Status: RunStatusPassed, Tests: readTestResults(g.ResultPath, g.ParseReport, GoTestReport.testResults)}, nil
+	}
+
+	report, parseErr := g.ParseReport(g.ResultPath)
+	if parseErr != nil {
+		fmt.Println("Buildkite Test Engine Client: Failed to read go test output, tests will not be retried.")
+		return RunResult{Status: RunStatusError}, exitErr
+	}
+
+	// If a package failed without any failing test, e.g. it failed to build or
+	// TestMain exited with an error, retrying the failed tests won't help.
+	if len(report.FailedPackages) > 0 {
+		fmt.Printf("Buildkite Test Engine Client: %s failed without a failing test, tests will not be retried.\n", strings.Join(report.FailedPackages, ", "))
+		return RunResult{Status: RunStatusError}, exitErr
+	}
+
+	if len(report.FailedTests) > 0 {
+		return RunResult{Status: RunStatusFailed, FailedTests: report.FailedTests, Tests: report.Tests}, nil
+	}
+
+	return RunResult{Status: RunStatusError}, exitErr
+}
+
+// goTestInvocations groups the test cases into the arguments of each `go test` invocation.
+// Packages are run in a single invocation, and individual tests are run with a -run pattern,
+// one invocation per package, or several if the pattern doesn't fit in a single argument.
+func goTestInvocations(testCases []string) [][]string {
+	packages := []string{}
+	var testPackages []string
+	tests := map[string][]string{}
+
+	for _, testCase := range testCases {
+		pkg, test, found := strings.Cut(testCase, goTestSeparator)
+		if !found {
+			packages = append(packages, testCase)
+			continue
+		}
+
+		if _, ok := tests[pkg]; !ok {
+			testPackages = append(testPackages, pkg)
+		}
+		tests[pkg] = append(tests[pkg], test)
+	}
+
+	var invocations [][]string
+	// Always run the packages when there are no tests, so that the command is run even
+	// if there are no test cases, e.g. when they are read from the test examples file.
+	if len(packages) > 0 || len(testPackages) == 0 {
+		invocations = append(invocations, packages)
+	}
+
+	for _, pkg := range testPackages {
+		for _, names := range batchNames(tests[pkg]) {
+			invocations = append(invocations, []string{"-run", namePattern(names), pkg})
+		}
+	}
+
+	return invocations
+}
+
+// GoTestEvent is a single event in the `go test -json` output.
+// See `go doc test2json` for the details of each field.
+type GoTestEvent struct {
+	Action  string  `json:"Action"`
+	Package string  `json:"Package"`
+	Test    string  `json:"Test"`
+	Elapsed float64 `json:"Elapsed"`
+	Output  string  `json:"Output"`
+}
+
+// GoTestReport is the summary of the events in the `go test -json` output.
+type GoTestReport struct {
+	Events []GoTestEvent
+	// FailedTests are the failed top-level tests in the form of "<package>::<TestName>".
+	FailedTests []string
+	// FailedPackages are the packages that failed without a failing test.
+	FailedPackages []string
+	// Tests are the results of the top-level tests, identified like FailedTests.
+	Tests []TestResult
+}
+
+// testResults returns the results of the tests in the report.
+func (r GoTestReport) testResults() []TestResult {
+	return r.Tests
+}
+
+func (g GoTest) ParseReport(path string) (GoTestReport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return GoTestReport{}, fmt.Errorf("failed to read go test output: %v", err)
+	}
+
+	var report GoTestReport
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		var event GoTestEvent
+		if err := json.Unmarshal(line, &event); err != nil {
+			return GoTestReport{}, fmt.Errorf("failed to parse go test output: %s", err)
+		}
+		report.Events = append(report.Events, event)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return GoTestReport{}, fmt.Errorf("failed to read go test output: %v", err)
+	}
+
+	for _, event := range report.Events {
+		if event.Test == "" || strings.Contains(event.Test, "/") {
+			continue
+		}
+
+		var status TestStatus
+		switch event.Action {
+		case "pass":
+			status = TestStatusPassed
+		case "fail":
+			status = TestStatusFailed
+		case "skip":
+			status = TestStatusSkipped
+		default:
+			continue
+		}
+
+		report.Tests = append(report.Tests, TestResult{
+			Id:       event.Package + goTestSeparator + event.Test,
+			Name:     event.Test,
+			Scope:    event.Package,
+			Status:   status,
+			Duration: secondsToDuration(event.Elapsed),
+		})
+	}
+
+	packagesWithFailedTests := map[string]bool{}
+	for _, event := range report.Events {
+		if event.Action != "fail" {
+			continue
+		}
+
+		// Failing subtests also fail their parent test,
+		// so we only need to keep track of the top-level tests.
+		if event.Test != "" && !strings.Contains(event.Test, "/") {
+			id := event.Package + goTestSeparator + event.Test
+			if !slices.Contains(report.FailedTests, id) {
+				report.FailedTests = append(report.FailedTests, id)
+			}
+			packagesWithFailedTests[event.Package] = true
+		}
+	}
+
+	for _, event := range report.Events {
+		if event.Action == "fail" && event.Test == "" && !packagesWithFailedTests[event.Package] {
+			if !slices.Contains(report.FailedPackages, event.Package) {
+				report.FailedPackages = append(report.FailedPackages, event.Package)
+			}
+		}
+	}
+
+	return report, nil
+}
+
+// commandNameAndArgs replaces the "{{testExamples}}" placeholder in the test command with the test cases.
+// It returns the command name and arguments to run the tests.
+func (g GoTest) commandNameAndArgs(cmd string, testCases []string) (string, []string, error) {
+	words, err := shellquote.Split(cmd)
+	if err != nil {
+		return "", []string{}, err
+	}
+
+	idx := slices.Index(words, "{{testExamples}}")
+	if idx < 0 {
+		words = append(words, testCases...)
+	} else {
+		words = slices.Replace(words, idx, idx+1, testCases...)
+	}
+
+	idx = slices.Index(words, "{{resultPath}}")
+	if idx >= 0 {
+		words = slices.Replace(words, idx, idx+1, g.ResultPath)
+	}
+
+	return words[0], words[1:], nil
+}
+
+// goTestNamePattern matches the names printed by `go test -list`, excluding benchmarks.
+var goTestNamePattern = regexp.MustCompile(`^(Test|Example|Fuzz)\S*$`)
+
+// GetExamples returns an array of top-level tests within the given packages,
+// listed using `go test -list`.
+func (g GoTest) GetExamples(files []string) ([]plan.TestCase, error) {
+	cmdName, cmdArgs, err := g.commandNameAndArgs(g.TestCommand, append([]string{"-list", "."}, files...))
+	if err != nil {
+		return nil, err
+	}
+
+	debug.Printf("Running `%s %s` to list tests", cmdName, strings.Join(cmdArgs, " "))
+
+	output, err := exec.Command(cmdName, cmdArgs...).Output()
+	if err != nil {
+		if exitError := new(exec.ExitError); errors.As(err, &exitError) {
+			return []plan.TestCase{}, fmt.Errorf("failed to list go tests: %s", exitError.Stderr)
+		}
+		return []plan.TestCase{}, fmt.Errorf("failed to list go tests: %v", err)
+	}
+
+	var testCases []plan.TestCase
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		var event GoTestEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return []plan.TestCase{}, fmt.Errorf("failed to parse go test output: %s", err)
+		}
+
+		name := strings.TrimSpace(event.Output)
+		if event.Action != "output" || !goTestNamePattern.MatchString(name) {
+			continue
+		}
+
+		id := event.Package + goTestSeparator + name
+		testCases = append(testCases, plan.TestCase{
+			Identifier: id,
+			Name:       name,
+			Path:       id,
+			Scope:      event.Package,
+		})
+	}
+
+	return testCases, nil
+}
+
+// goTestOutputWriter writes the `go test -json` output to the result file as is,
+// and prints the human readable test output to out.
+// Lines that are not JSON events, e.g. when the command is not `go test -json`,
+// are printed as is.
+type goTestOutputWriter struct {
+	result io.Writer
+	out    io.Writer
+	buf    []byte
+}
+
+func newGoTestOutputWriter(result io.Writer, out io.Writer) *goTestOutputWriter {
+	return &goTestOutputWriter{result: result, out: out}
+}
+
+func (w *goTestOutputWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		idx := bytes.IndexByte(w.buf, '\n')
+		if idx < 0 {
+			break
+		}
+		if err := w.writeLine(w.buf[:idx+1]); err != nil {
+			return 0, err
+		}
+		w.buf = w.buf[idx+1:]
+	}
+	return len(p), nil
+}
+
+// Flush writes any remaining partial line.
+func (w *goTestOutputWriter) Flush() {
+	if len(w.buf) > 0 {
+		_ = w.writeLine(append(w.buf, '\n'))
+		w.buf = nil
+	}
+}
+
+func (w *goTestOutputWriter) writeLine(line []byte) error {
+	var event GoTestEvent
+	if err := json.Unmarshal(line, &event); err != nil {
+		_, err := w.out.Write(line)
+		return err
+	}
+
+	if _, err := w.result.Write(line); err != nil {
+		return err
+	}
+
+	if event.Output != "" {
+		_, err := io.WriteString(w.out, event.Output)
+		return err
+	}
+	return nil
+}
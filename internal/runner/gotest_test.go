@@ -0,0 +1,309 @@
+package runner
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/buildkite/test-engine-client/internal/plan"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestNewGoTest(t *testing.T) {
+	cases := []struct {
+		input RunnerConfig
+		want  RunnerConfig
+	}{
+		//default
+		{
+			input: RunnerConfig{},
+			want: RunnerConfig{
+				TestCommand:            "go test -json {{testExamples}}",
+				TestFilePattern:        "**/*_test.go",
+				TestFileExcludePattern: "vendor",
+				RetryTestCommand:       "go test -json {{testExamples}}",
+			},
+		},
+		// custom
+		{
+			input: RunnerConfig{
+				TestCommand:            "go test -json -race {{testExamples}}",
+				TestFilePattern:        "internal/**/*_test.go",
+				TestFileExcludePattern: "internal/e2e",
+				RetryTestCommand:       "go test -json -count=1 {{testExamples}}",
+			},
+			want: RunnerConfig{
+				TestCommand:            "go test -json -race {{testExamples}}",
+				TestFilePattern:        "internal/**/*_test.go",
+				TestFileExcludePattern: "internal/e2e",
+				RetryTestCommand:       "go test -json -count=1 {{testExamples}}",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		got := NewGoTest(c.input)
+		if diff := cmp.Diff(got.RunnerConfig, c.want, cmpopts.IgnoreUnexported(RunnerConfig{})); diff != "" {
+			t.Errorf("NewGoTest(%v) diff (-got +want):\n%s", c.input, diff)
+		}
+	}
+}
+
+func TestGoTestGetFiles(t *testing.T) {
+	gotest := NewGoTest(RunnerConfig{
+		TestFilePattern:        "fixtures/gotest/**/*_test.go",
+		TestFileExcludePattern: "fixtures/gotest/broken",
+	})
+
+	got, err := gotest.GetFiles()
+	if err != nil {
+		t.Errorf("GoTest.GetFiles() error = %v", err)
+	}
+
+	want := []string{"./fixtures/gotest/apple", "./fixtures/gotest/banana"}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("GoTest.GetFiles() diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestGoTestRun(t *testing.T) {
+	gotest := NewGoTest(RunnerConfig{
+		TestCommand: "go -C fixtures/gotest test -json {{testExamples}}",
+		ResultPath:  "gotest.json",
+	})
+
+	t.Cleanup(func() {
+		os.Remove(gotest.ResultPath)
+	})
+
+	testCases := []string{"./apple", "example.com/fruits/banana::TestIsCurved"}
+	got, err := gotest.Run(testCases, 0)
+
+	want := RunResult{
+		Status: RunStatusPassed,
+		Tests: []TestResult{
+			{Id: "example.com/fruits/apple::TestIsRed", Name: "TestIsRed", Scope: "example.com/fruits/apple", Status: TestStatusPassed},
+			{Id: "example.com/fruits/apple::TestIsFruit", Name: "TestIsFruit", Scope: "example.com/fruits/apple", Status: TestStatusPassed},
+			{Id: "example.com/fruits/banana::TestIsCurved", Name: "TestIsCurved", Scope: "example.com/fruits/banana", Status: TestStatusPassed},
+		},
+	}
+
+	if err != nil {
+		t.Errorf("GoTest.Run(%q) error = %v", testCases, err)
+	}
+
+	if diff := cmp.Diff(got, want, cmpopts.IgnoreFields(TestResult{}, "Duration")); diff != "" {
+		t.Errorf("GoTest.Run(%q) diff (-got +want):\n%s", testCases, diff)
+	}
+}
+
+func TestGoTestRun_TestFailed(t *testing.T) {
+	gotest := NewGoTest(RunnerConfig{
+		TestCommand: "go -C fixtures/gotest test -json {{testExamples}}",
+		ResultPath:  "gotest.json",
+	})
+
+	t.Cleanup(func() {
+		os.Remove(gotest.ResultPath)
+	})
+
+	testCases := []string{"./apple", "./banana"}
+	got, err := gotest.Run(testCases, 0)
+
+	want := RunResult{
+		Status:      RunStatusFailed,
+		FailedTests: []string{"example.com/fruits/banana::TestIsYellow"},
+		Tests: []TestResult{
+			{Id: "example.com/fruits/apple::TestIsRed", Name: "TestIsRed", Scope: "example.com/fruits/apple", Status: TestStatusPassed},
+			{Id: "example.com/fruits/apple::TestIsFruit", Name: "TestIsFruit", Scope: "example.com/fruits/apple", Status: TestStatusPassed},
+			{Id: "example.com/fruits/banana::TestIsYellow", Name: "TestIsYellow", Scope: "example.com/fruits/banana", Status: TestStatusFailed},
+			{Id: "example.com/fruits/banana::TestIsCurved", Name: "TestIsCurved", Scope: "example.com/fruits/banana", Status: TestStatusPassed},
+		},
+	}
+
+	if err != nil {
+		t.Errorf("GoTest.Run(%q) error = %v", testCases, err)
+	}
+
+	if diff := cmp.Diff(got, want, cmpopts.IgnoreFields(TestResult{}, "Duration")); diff != "" {
+		t.Errorf("GoTest.Run(%q) diff (-got +want):\n%s", testCases, diff)
+	}
+}
+
+func TestGoTestRun_Retry(t *testing.T) {
+	gotest := NewGoTest(RunnerConfig{
+		TestCommand:      "go -C fixtures/gotest test -json -invalid-option {{testExamples}}",
+		RetryTestCommand: "go -C fixtures/gotest test -json {{testExamples}}",
+		ResultPath:       "gotest.json",
+	})
+
+	t.Cleanup(func() {
+		os.Remove(gotest.ResultPath)
+	})
+
+	testCases := []string{"example.com/fruits/banana::TestIsYellow", "example.com/fruits/banana::TestIsCurved"}
+	got, err := gotest.Run(testCases, 1)
+
+	want := RunResult{
+		Status:      RunStatusFailed,
+		FailedTests: []string{"example.com/fruits/banana::TestIsYellow"},
+		Tests: []TestResult{
+			{Id: "example.com/fruits/banana::TestIsYellow", Name: "TestIsYellow", Scope: "example.com/fruits/banana", Status: TestStatusFailed},
+			{Id: "example.com/fruits/banana::TestIsCurved", Name: "TestIsCurved", Scope: "example.com/fruits/banana", Status: TestStatusPassed},
+		},
+	}
+
+	if err != nil {
+		t.Errorf("GoTest.Run(%q) error = %v", testCases, err)
+	}
+
+	if diff := cmp.Diff(got, want, cmpopts.IgnoreFields(TestResult{}, "Duration")); diff != "" {
+		t.Errorf("GoTest.Run(%q) diff (-got +want):\n%s", testCases, diff)
+	}
+}
+
+func TestGoTestRun_BuildFailed(t *testing.T) {
+	gotest := NewGoTest(RunnerConfig{
+		TestCommand: "go -C fixtures/gotest test -json {{testExamples}}",
+		ResultPath:  "gotest.json",
+	})
+
+	t.Cleanup(func() {
+		os.Remove(gotest.ResultPath)
+	})
+
+	testCases := []string{"./banana", "./broken"}
+	got, err := gotest.Run(testCases, 0)
+
+	want := RunResult{
+		Status: RunStatusError,
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("GoTest.Run(%q) diff (-got +want):\n%s", testCases, diff)
+	}
+
+	exitError := new(exec.ExitError)
+	if !errors.As(err, &exitError) {
+		t.Errorf("GoTest.Run(%q) error type = %T (%v), want *exec.ExitError", testCases, err, err)
+	}
+}
+
+func TestGoTestInvocations(t *testing.T) {
+	testCases := []string{
+		"./apple",
+		"example.com/fruits/banana::TestIsYellow",
+		"./cherry",
+		"example.com/fruits/durian::TestIsSmelly",
+		"example.com/fruits/banana::TestIsCurved",
+	}
+
+	got := goTestInvocations(testCases)
+
+	want := [][]string{
+		{"./apple", "./cherry"},
+		{"-run", "^(TestIsYellow|TestIsCurved)$", "example.com/fruits/banana"},
+		{"-run", "^(TestIsSmelly)$", "example.com/fruits/durian"},
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("goTestInvocations(%q) diff (-got +want):\n%s", testCases, diff)
+	}
+}
+
+func TestGoTestInvocations_LargePattern(t *testing.T) {
+	// the pattern of 2 test names, "^(TestIsYellow|TestIsCurved)$", fits in 30 bytes with the null byte
+	setMaxArgSize(t, 30)
+
+	testCases := []string{
+		"example.com/fruits/banana::TestIsYellow",
+		"example.com/fruits/banana::TestIsCurved",
+		"example.com/fruits/banana::TestIsSweet",
+	}
+
+	got := goTestInvocations(testCases)
+
+	want := [][]string{
+		{"-run", "^(TestIsYellow|TestIsCurved)$", "example.com/fruits/banana"},
+		{"-run", "^(TestIsSweet)$", "example.com/fruits/banana"},
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("goTestInvocations(%q) diff (-got +want):\n%s", testCases, diff)
+	}
+}
+
+func TestGoTestInvocations_NoTestCases(t *testing.T) {
+	got := goTestInvocations([]string{})
+
+	want := [][]string{{}}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("goTestInvocations([]) diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestGoTestGetExamples(t *testing.T) {
+	gotest := NewGoTest(RunnerConfig{
+		TestCommand: "go -C fixtures/gotest test -json {{testExamples}}",
+	})
+
+	files := []string{"./apple"}
+	got, err := gotest.GetExamples(files)
+
+	want := []plan.TestCase{
+		{
+			Identifier: "example.com/fruits/apple::TestIsRed",
+			Name:       "TestIsRed",
+			Path:       "example.com/fruits/apple::TestIsRed",
+			Scope:      "example.com/fruits/apple",
+		},
+		{
+			Identifier: "example.com/fruits/apple::TestIsFruit",
+			Name:       "TestIsFruit",
+			Path:       "example.com/fruits/apple::TestIsFruit",
+			Scope:      "example.com/fruits/apple",
+		},
+	}
+
+	if err != nil {
+		t.Errorf("GoTest.GetExamples(%q) error = %v", files, err)
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("GoTest.GetExamples(%q) diff (-got +want):\n%s", files, diff)
+	}
+}
+
+func TestGoTestOutputWriter(t *testing.T) {
+	var result, out bytes.Buffer
+	w := newGoTestOutputWriter(&result, &out)
+
+	input := `{"Action":"run","Package":"example.com/fruits/apple","Test":"TestIsRed"}
+{"Action":"output","Package":"example.com/fruits/apple","Test":"TestIsRed","Output":"=== RUN   TestIsRed\n"}
+go: downloading example.com/seeds v1.0.0
+{"Action":"pass","Package":"example.com/fruits/apple","Test":"TestIsRed"`
+
+	// write in two chunks to simulate a partial line
+	w.Write([]byte(input[:50]))
+	w.Write([]byte(input[50:]))
+	w.Flush()
+
+	wantResult := `{"Action":"run","Package":"example.com/fruits/apple","Test":"TestIsRed"}
+{"Action":"output","Package":"example.com/fruits/apple","Test":"TestIsRed","Output":"=== RUN   TestIsRed\n"}
+`
+	if diff := cmp.Diff(result.String(), wantResult); diff != "" {
+		t.Errorf("goTestOutputWriter result diff (-got +want):\n%s", diff)
+	}
+
+	wantOut := `=== RUN   TestIsRed
+go: downloading example.com/seeds v1.0.0
+{"Action":"pass","Package":"example.com/fruits/apple","Test":"TestIsRed"
+`
+	if diff := cmp.Diff(out.String(), wantOut); diff != "" {
+		t.Errorf("goTestOutputWriter output diff (-got +want):\n%s", diff)
+	}
+}
@@ -6,8 +6,10 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/buildkite/test-engine-client/internal/debug"
 	"github.com/buildkite/test-engine-client/internal/plan"
@@ -16,6 +18,10 @@ import (
 
 var _ = TestRunner(Jest{})
 
+// jestExampleSeparator separates the test file from the full name of an example,
+// e.g. "src/sum.spec.js::sum adds two numbers".
+const jestExampleSeparator = "::"
+
 type Jest struct {
 	RunnerConfig
 }
@@ -37,6 +43,7 @@ func NewJest(j RunnerConfig) Jest {
 		j.RetryTestCommand = "yarn test --testNamePattern '{{testNamePattern}}' --json --testLocationInResults --outputFile {{resultPath}}"
 	}
 
+	j.invocations = newInvocationCounter()
 	return Jest{j}
 }
 
@@ -61,36 +68,135 @@ func (j Jest) GetFiles() ([]string, error) {
 	return files, nil
 }
 
-func (j Jest) Run(testCases []string, retry bool) (RunResult, error) {
-	var cmd *exec.Cmd
-	var err error
+// Run executes the test command with the given test cases.
+// If attempt is greater than zero, it will run the retry test command with a test name pattern
+// matching the given test cases, otherwise it will use the test command.
+//
+// Test cases can be a mix of test files and individual examples in the form of
+// "<file>::<full name>", as returned by GetExamples. Test files are run together in a
+// single invocation, while examples are grouped by file and run with `--testNamePattern`,
+// one invocation per file. Retried tests are run with the retry test command in a single
+// invocation. Test name patterns too large for a single argument are split between several
+// invocations, see batchNames.
+//
+// Error is returned if the command fails to run, exits prematurely, or if the
+// output cannot be parsed. The invocations stop at the first error, which is returned
+// with the results of the invocations so far merged.
+//
+// Test failure is not considered an error, and is instead returned as a RunResult.
+func (j Jest) Run(testCases []string, attempt int) (RunResult, error) {
+	command := j.TestCommand
+	commandNameAndArgs := Jest.commandNameAndArgs
+	invocations := jestInvocations(testCases)
+
+	if attempt > 0 {
+		command = j.RetryTestCommand
+		commandNameAndArgs = Jest.retryCommandNameAndArgs
+		invocations = jestRetryInvocations(testCases)
+	}
 
-	if !retry {
-		commandName, commandArgs, err := j.commandNameAndArgs(j.TestCommand, testCases)
+	var results []RunResult
+	for _, invocation := range invocations {
+		invocationRunner := j
+		invocationRunner.ResultPath = j.invocationResultPath(attempt)
+		commandName, commandArgs, err := commandNameAndArgs(invocationRunner, command, invocation.args)
 		if err != nil {
 			return RunResult{Status: RunStatusError}, fmt.Errorf("failed to build command: %w", err)
 		}
 
-		cmd = exec.Command(commandName, commandArgs...)
-	} else {
-		commandName, commandArgs, err := j.retryCommandNameAndArgs(j.RetryTestCommand, testCases)
+		result, err := invocationRunner.run(exec.Command(commandName, commandArgs...))
+		if invocation.examples != nil {
+			result.Tests = RequestedTests(result.Tests, invocation.examples)
+		}
+		results = append(results, result)
 		if err != nil {
-			return RunResult{Status: RunStatusError}, fmt.Errorf("failed to build command: %w", err)
+			return MergeRunResults(results...), err
+		}
+	}
+
+	return MergeRunResults(results...), nil
+}
+
+// jestInvocation is a single invocation of the Jest test command.
+type jestInvocation struct {
+	// args are the test cases of the test command, or the names of the tests
+	// matched by the test name pattern of the retry test command.
+	args []string
+	// examples are the full names of the examples run with a test name pattern, if any.
+	// Jest reports the other tests of the file as skipped, so only the results of these are kept.
+	examples []string
+}
+
+// jestInvocations groups the test cases into the invocations of Jest.
+// Test files are run in a single invocation, or several if they don't fit in the arguments
+// of a single one, and examples are run with a test name pattern matching their full names,
+// one invocation per file, or several if the pattern doesn't fit in a single argument.
+func jestInvocations(testCases []string) []jestInvocation {
+	files := []string{}
+	var exampleFiles []string
+	examples := map[string][]string{}
+
+	for _, testCase := range testCases {
+		file, name, found := strings.Cut(testCase, jestExampleSeparator)
+		if !found {
+			files = append(files, testCase)
+			continue
+		}
+
+		if _, ok := examples[file]; !ok {
+			exampleFiles = append(exampleFiles, file)
+		}
+		examples[file] = append(examples[file], name)
+	}
+
+	var invocations []jestInvocation
+	// Always run the test files when there are no examples,
+	// so that the command is run even if there are no test cases.
+	if len(files) > 0 || len(exampleFiles) == 0 {
+		for _, args := range batchTestCases(files) {
+			invocations = append(invocations, jestInvocation{args: args})
+		}
+	}
+
+	for _, file := range exampleFiles {
+		for _, names := range batchNames(examples[file]) {
+			invocations = append(invocations, jestInvocation{
+				args:     []string{"--testNamePattern", namePattern(names), file},
+				examples: names,
+			})
 		}
+	}
+
+	return invocations
+}
 
-		cmd = exec.Command(commandName, commandArgs...)
+// jestRetryInvocations groups the retried tests into the invocations of the retry test command,
+// a single one unless their test name pattern doesn't fit in a single argument.
+func jestRetryInvocations(testCases []string) []jestInvocation {
+	var invocations []jestInvocation
+	for _, names := range batchNames(testCases) {
+		invocations = append(invocations, jestInvocation{args: names, examples: names})
 	}
+	return invocations
+}
 
-	err = runAndForwardSignal(cmd)
+// run runs the given Jest command and reads the result from the result path.
+func (j Jest) run(cmd *exec.Cmd) (RunResult, error) {
+	cmd.Env = j.commandEnv()
+	err := runAndForwardSignal(cmd, j.Timeout)
 
 	if err == nil { // note: returning success early
-		return RunResult{Status: RunStatusPassed}, nil
+		return RunResult{Status: RunStatusPassed, Tests: readTestResults(j.ResultPath, j.ParseReport, JestReport.testResults)}, nil
 	}
 
 	if ProcessSignaledError := new(ProcessSignaledError); errors.As(err, &ProcessSignaledError) {
 		return RunResult{Status: RunStatusError}, err
 	}
 
+	if timeoutError := new(TimeoutError); errors.As(err, &timeoutError) {
+		return RunResult{Status: RunStatusTimeout}, err
+	}
+
 	if exitError := new(exec.ExitError); errors.As(err, &exitError) {
 		report, parseErr := j.ParseReport(j.ResultPath)
 		if parseErr != nil {
@@ -108,7 +214,7 @@ func (j Jest) Run(testCases []string, retry bool) (RunResult, error) {
 				}
 			}
 
-			return RunResult{Status: RunStatusFailed, FailedTests: failedTests}, nil
+			return RunResult{Status: RunStatusFailed, FailedTests: failedTests, Tests: report.testResults()}, nil
 		}
 	}
 
@@ -116,17 +222,65 @@ func (j Jest) Run(testCases []string, retry bool) (RunResult, error) {
 }
 
 type JestExample struct {
-	Name   string `json:"fullName"`
-	Status string `json:"status"`
+	Name           string   `json:"fullName"`
+	Status         string   `json:"status"`
+	Title          string   `json:"title"`
+	AncestorTitles []string `json:"ancestorTitles"`
+	// Duration is in milliseconds, it's null when the test is not run.
+	Duration *float64 `json:"duration"`
+	// Location is only reported with the --testLocationInResults option.
+	Location *struct {
+		Line int `json:"line"`
+	} `json:"location"`
 }
 
 type JestReport struct {
 	NumFailedTests int
 	TestResults    []struct {
+		// Name is the absolute path of the test file.
+		Name             string `json:"name"`
 		AssertionResults []JestExample
 	}
 }
 
+// testResults returns the results of the tests in the report, identified by their full names.
+// Pending, skipped and todo tests are reported as skipped.
+func (r JestReport) testResults() []TestResult {
+	var tests []TestResult
+	for _, testResult := range r.TestResults {
+		for _, example := range testResult.AssertionResults {
+			status := TestStatusSkipped
+			switch example.Status {
+			case "passed":
+				status = TestStatusPassed
+			case "failed":
+				status = TestStatusFailed
+			}
+
+			var duration time.Duration
+			if example.Duration != nil {
+				duration = time.Duration(*example.Duration * float64(time.Millisecond))
+			}
+
+			var line int
+			if example.Location != nil {
+				line = example.Location.Line
+			}
+
+			tests = append(tests, TestResult{
+				Id:       example.Name,
+				Name:     example.Title,
+				Scope:    strings.Join(example.AncestorTitles, " "),
+				Path:     testResult.Name,
+				Line:     line,
+				Status:   status,
+				Duration: duration,
+			})
+		}
+	}
+	return tests
+}
+
 func (j Jest) ParseReport(path string) (JestReport, error) {
 	var report JestReport
 	data, err := os.ReadFile(path)
@@ -175,9 +329,7 @@ func (j Jest) retryCommandNameAndArgs(cmd string, testCases []string) (string, [
 		return "", []string{}, err
 	}
 
-	testNamePattern := fmt.Sprintf("(%s)", strings.Join(testCases, "|"))
-
-	words = slices.Replace(words, idx, idx+1, testNamePattern)
+	words = slices.Replace(words, idx, idx+1, namePattern(testCases))
 
 	outputIdx := slices.Index(words, "{{resultPath}}")
 	if outputIdx < 0 {
@@ -189,6 +341,67 @@ func (j Jest) retryCommandNameAndArgs(cmd string, testCases []string) (string, [
 	return words[0], words[1:], err
 }
 
+// GetExamples returns an array of test examples within the given files.
+//
+// Jest has no dry run, so the test command is run with a test name pattern that
+// doesn't match any test. Jest then reports every test as skipped without running
+// them, nor their hooks, which gives us the name of all the tests in the files.
 func (j Jest) GetExamples(files []string) ([]plan.TestCase, error) {
-	return nil, fmt.Errorf("not supported in Jest")
+	// Create a temporary file to store the JSON output of the Jest run.
+	f, err := os.CreateTemp("", "list-tests-*.json")
+	if err != nil {
+		return []plan.TestCase{}, fmt.Errorf("failed to create temporary file for listing Jest tests: %v", err)
+	}
+
+	defer func() {
+		f.Close()
+		os.Remove(f.Name())
+	}()
+
+	listRunner := j
+	listRunner.ResultPath = f.Name()
+
+	cmdName, cmdArgs, err := listRunner.commandNameAndArgs(j.TestCommand, files)
+	if err != nil {
+		return nil, err
+	}
+
+	// "(?!)" is an empty negative lookahead, which never matches.
+	cmdArgs = append(cmdArgs, "--testNamePattern", "(?!)")
+
+	debug.Printf("Running `%s %s` to list tests", cmdName, strings.Join(cmdArgs, " "))
+
+	output, err := exec.Command(cmdName, cmdArgs...).CombinedOutput()
+	if err != nil {
+		return []plan.TestCase{}, fmt.Errorf("failed to list Jest tests: %s", output)
+	}
+
+	report, err := j.ParseReport(f.Name())
+	if err != nil {
+		return []plan.TestCase{}, err
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return []plan.TestCase{}, fmt.Errorf("failed to get working directory: %v", err)
+	}
+
+	var testCases []plan.TestCase
+	for _, testResult := range report.TestResults {
+		file, err := filepath.Rel(wd, testResult.Name)
+		if err != nil {
+			file = testResult.Name
+		}
+
+		for _, example := range testResult.AssertionResults {
+			testCases = append(testCases, plan.TestCase{
+				Identifier: example.Name,
+				Name:       example.Title,
+				Path:       file + jestExampleSeparator + example.Name,
+				Scope:      strings.Join(example.AncestorTitles, " "),
+			})
+		}
+	}
+
+	return testCases, nil
 }
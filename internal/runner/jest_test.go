@@ -1,14 +1,18 @@
 package runner
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"syscall"
 	"testing"
+	"time"
 
+	"github.com/buildkite/test-engine-client/internal/plan"
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/kballard/go-shellquote"
 )
 
@@ -46,7 +50,7 @@ func TestNewJest(t *testing.T) {
 
 	for _, c := range cases {
 		got := NewJest(c.input)
-		if diff := cmp.Diff(got.RunnerConfig, c.want); diff != "" {
+		if diff := cmp.Diff(got.RunnerConfig, c.want, cmpopts.IgnoreUnexported(RunnerConfig{})); diff != "" {
 			t.Errorf("NewJest(%v) diff (-got +want):\n%s", c.input, diff)
 		}
 	}
@@ -63,7 +67,7 @@ func TestJestRun(t *testing.T) {
 	})
 
 	files := []string{"./fixtures/jest/spells/expelliarmus.spec.js"}
-	got, err := jest.Run(files, false)
+	got, err := jest.Run(files, 0)
 
 	want := RunResult{
 		Status: RunStatusPassed,
@@ -73,7 +77,7 @@ func TestJestRun(t *testing.T) {
 		t.Errorf("Jest.Run(%q) error = %v", files, err)
 	}
 
-	if diff := cmp.Diff(got, want); diff != "" {
+	if diff := cmp.Diff(got, want, cmpopts.IgnoreFields(RunResult{}, "Tests")); diff != "" {
 		t.Errorf("Jest.Run(%q) diff (-got +want):\n%s", files, diff)
 	}
 }
@@ -91,7 +95,7 @@ func TestJestRun_Retry(t *testing.T) {
 	})
 
 	files := []string{"fixtures/jest/spells/expelliarmus.spec.js"}
-	got, err := jest.Run(files, true)
+	got, err := jest.Run(files, 1)
 
 	want := RunResult{
 		Status: RunStatusPassed,
@@ -101,7 +105,7 @@ func TestJestRun_Retry(t *testing.T) {
 		t.Errorf("Jest.Run(%q) error = %v", files, err)
 	}
 
-	if diff := cmp.Diff(got, want); diff != "" {
+	if diff := cmp.Diff(got, want, cmpopts.IgnoreFields(RunResult{}, "Tests")); diff != "" {
 		t.Errorf("Jest.Run(%q) diff (-got +want):\n%s", files, diff)
 	}
 }
@@ -117,7 +121,7 @@ func TestJestRun_TestFailed(t *testing.T) {
 	})
 
 	files := []string{"./fixtures/jest/failure.spec.js"}
-	got, err := jest.Run(files, false)
+	got, err := jest.Run(files, 0)
 
 	want := RunResult{
 		Status:      RunStatusFailed,
@@ -128,11 +132,41 @@ func TestJestRun_TestFailed(t *testing.T) {
 		t.Errorf("Jest.Run(%q) error = %v", files, err)
 	}
 
-	if diff := cmp.Diff(got, want); diff != "" {
+	if diff := cmp.Diff(got, want, cmpopts.IgnoreFields(RunResult{}, "Tests")); diff != "" {
 		t.Errorf("Jest.Run(%q) diff (-got +want):\n%s", files, diff)
 	}
 }
 
+func TestJestRun_WithExamples(t *testing.T) {
+	jest := NewJest(RunnerConfig{
+		TestCommand: "jest --json --outputFile {{resultPath}}",
+		ResultPath:  "jest.json",
+	})
+
+	t.Cleanup(func() {
+		os.Remove(jest.ResultPath)
+	})
+
+	testCases := []string{
+		"fixtures/jest/failure.spec.js::this will fail for sure",
+		"./fixtures/jest/spells/expelliarmus.spec.js",
+	}
+	got, err := jest.Run(testCases, 0)
+
+	want := RunResult{
+		Status:      RunStatusFailed,
+		FailedTests: []string{"this will fail for sure"},
+	}
+
+	if err != nil {
+		t.Errorf("Jest.Run(%q) error = %v", testCases, err)
+	}
+
+	if diff := cmp.Diff(got, want, cmpopts.IgnoreFields(RunResult{}, "Tests")); diff != "" {
+		t.Errorf("Jest.Run(%q) diff (-got +want):\n%s", testCases, diff)
+	}
+}
+
 func TestJestRun_CommandFailed(t *testing.T) {
 	jest := Jest{
 		RunnerConfig{
@@ -145,7 +179,7 @@ func TestJestRun_CommandFailed(t *testing.T) {
 	})
 
 	files := []string{}
-	got, err := jest.Run(files, false)
+	got, err := jest.Run(files, 0)
 
 	want := RunResult{
 		Status: RunStatusError,
@@ -167,7 +201,7 @@ func TestJestRun_SignaledError(t *testing.T) {
 	})
 	files := []string{"./doesnt-matter.spec.js"}
 
-	got, err := jest.Run(files, false)
+	got, err := jest.Run(files, 0)
 
 	want := RunResult{
 		Status: RunStatusError,
@@ -283,7 +317,7 @@ func TestJestRetryCommandNameAndArgs_HappyPath(t *testing.T) {
 	}
 
 	wantName := "jest"
-	wantArgs := []string{"--testNamePattern", "(this will fail|this other one will fail)", "--json", "--testLocationInResults", "--outputFile", "jest.json"}
+	wantArgs := []string{"--testNamePattern", "^(this will fail|this other one will fail)$", "--json", "--testLocationInResults", "--outputFile", "jest.json"}
 
 	if diff := cmp.Diff(gotName, wantName); diff != "" {
 		t.Errorf("retryCommandNameAndArgs(%q, %q) diff (-got +want):\n%s", testCases, retryTestCommand, diff)
@@ -322,3 +356,141 @@ func TestJestRetryCommandNameAndArgs_WithoutInterpolationPlaceholder(t *testing.
 		t.Errorf("retryCommandNameAndArgs() error = %v, want %v", err, desiredString)
 	}
 }
+
+func TestJestInvocations(t *testing.T) {
+	testCases := []string{
+		"spec/user.spec.js",
+		"spec/billing.spec.js::Billing charges (in dollars) the card",
+		"spec/cart.spec.js",
+		"spec/billing.spec.js::Billing refunds",
+		"spec/search.spec.js::Search finds things",
+	}
+
+	got := jestInvocations(testCases)
+
+	want := []jestInvocation{
+		{args: []string{"spec/user.spec.js", "spec/cart.spec.js"}},
+		{
+			args:     []string{"--testNamePattern", `^(Billing charges \(in dollars\) the card|Billing refunds)$`, "spec/billing.spec.js"},
+			examples: []string{"Billing charges (in dollars) the card", "Billing refunds"},
+		},
+		{
+			args:     []string{"--testNamePattern", `^(Search finds things)$`, "spec/search.spec.js"},
+			examples: []string{"Search finds things"},
+		},
+	}
+
+	if diff := cmp.Diff(got, want, cmp.AllowUnexported(jestInvocation{})); diff != "" {
+		t.Errorf("jestInvocations(%q) diff (-got +want):\n%s", testCases, diff)
+	}
+}
+
+func TestJestInvocations_NoTestCases(t *testing.T) {
+	got := jestInvocations([]string{})
+
+	want := []jestInvocation{{args: []string{}}}
+
+	if diff := cmp.Diff(got, want, cmp.AllowUnexported(jestInvocation{})); diff != "" {
+		t.Errorf("jestInvocations([]) diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestJestInvocations_LargePattern(t *testing.T) {
+	// the pattern of 2 names, "^(Billing charges|Billing refunds)$", fits in 36 bytes with the null byte
+	setMaxArgSize(t, 36)
+
+	testCases := []string{
+		"spec/billing.spec.js::Billing charges",
+		"spec/billing.spec.js::Billing refunds",
+		"spec/billing.spec.js::Billing invoices",
+	}
+
+	got := jestInvocations(testCases)
+
+	want := []jestInvocation{
+		{
+			args:     []string{"--testNamePattern", `^(Billing charges|Billing refunds)$`, "spec/billing.spec.js"},
+			examples: []string{"Billing charges", "Billing refunds"},
+		},
+		{
+			args:     []string{"--testNamePattern", `^(Billing invoices)$`, "spec/billing.spec.js"},
+			examples: []string{"Billing invoices"},
+		},
+	}
+
+	if diff := cmp.Diff(got, want, cmp.AllowUnexported(jestInvocation{})); diff != "" {
+		t.Errorf("jestInvocations(%q) diff (-got +want):\n%s", testCases, diff)
+	}
+}
+
+func TestJestRetryInvocations(t *testing.T) {
+	setMaxArgSize(t, 36)
+
+	testCases := []string{"Billing charges", "Billing refunds", "Billing invoices"}
+
+	got := jestRetryInvocations(testCases)
+
+	want := []jestInvocation{
+		{args: []string{"Billing charges", "Billing refunds"}, examples: []string{"Billing charges", "Billing refunds"}},
+		{args: []string{"Billing invoices"}, examples: []string{"Billing invoices"}},
+	}
+
+	if diff := cmp.Diff(got, want, cmp.AllowUnexported(jestInvocation{})); diff != "" {
+		t.Errorf("jestRetryInvocations(%q) diff (-got +want):\n%s", testCases, diff)
+	}
+}
+
+func TestJestGetExamples(t *testing.T) {
+	jest := NewJest(RunnerConfig{
+		TestCommand: "jest --json --outputFile {{resultPath}}",
+	})
+	files := []string{"./fixtures/jest/spells/expelliarmus.spec.js"}
+	got, err := jest.GetExamples(files)
+
+	want := []plan.TestCase{
+		{
+			Identifier: "expelliarmus disarms the opponent",
+			Name:       "disarms the opponent",
+			Path:       "fixtures/jest/spells/expelliarmus.spec.js::expelliarmus disarms the opponent",
+			Scope:      "expelliarmus",
+		},
+	}
+
+	if err != nil {
+		t.Errorf("Jest.GetExamples(%q) error = %v", files, err)
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("Jest.GetExamples(%q) diff (-got +want):\n%s", files, diff)
+	}
+}
+
+func TestJestReportTestResults(t *testing.T) {
+	var report JestReport
+	data := `{
+		"numFailedTests": 1,
+		"testResults": [{
+			"name": "/app/spells/expelliarmus.spec.js",
+			"assertionResults": [
+				{"fullName": "expelliarmus disarms the opponent", "title": "disarms the opponent", "ancestorTitles": ["expelliarmus"], "status": "passed", "duration": 12, "location": {"line": 4, "column": 3}},
+				{"fullName": "expelliarmus knocks the opponent out", "title": "knocks the opponent out", "ancestorTitles": ["expelliarmus"], "status": "failed", "duration": 3},
+				{"fullName": "expelliarmus works underwater", "title": "works underwater", "ancestorTitles": ["expelliarmus"], "status": "todo", "duration": null}
+			]
+		}]
+	}`
+	if err := json.Unmarshal([]byte(data), &report); err != nil {
+		t.Fatal(err)
+	}
+
+	got := report.testResults()
+
+	want := []TestResult{
+		{Id: "expelliarmus disarms the opponent", Name: "disarms the opponent", Scope: "expelliarmus", Path: "/app/spells/expelliarmus.spec.js", Line: 4, Status: TestStatusPassed, Duration: 12 * time.Millisecond},
+		{Id: "expelliarmus knocks the opponent out", Name: "knocks the opponent out", Scope: "expelliarmus", Path: "/app/spells/expelliarmus.spec.js", Status: TestStatusFailed, Duration: 3 * time.Millisecond},
+		{Id: "expelliarmus works underwater", Name: "works underwater", Scope: "expelliarmus", Path: "/app/spells/expelliarmus.spec.js", Status: TestStatusSkipped},
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("JestReport.testResults() diff (-got +want):\n%s", diff)
+	}
+}
@@ -0,0 +1,128 @@
+package runner
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"time"
+)
+
+// JUnitFailure represents a <failure> or <error> element of a JUnit test case.
+type JUnitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Body    string `xml:",chardata"`
+}
+
+// JUnitTestCase represents a single <testcase> element in a JUnit XML report.
+type JUnitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	File      string        `xml:"file,attr"`
+	Line      int           `xml:"line,attr"`
+	Time      float64       `xml:"time,attr"`
+	Failure   *JUnitFailure `xml:"failure"`
+	Error     *JUnitFailure `xml:"error"`
+	Skipped   *struct{}     `xml:"skipped"`
+}
+
+// Failed returns true if the test case has a failure or an error.
+func (t JUnitTestCase) Failed() bool {
+	return t.Failure != nil || t.Error != nil
+}
+
+// testResult returns the result of the test case, identified by id.
+func (t JUnitTestCase) testResult(id string) TestResult {
+	status := TestStatusPassed
+	if t.Failed() {
+		status = TestStatusFailed
+	} else if t.Skipped != nil {
+		status = TestStatusSkipped
+	}
+
+	return TestResult{
+		Id:       id,
+		Name:     t.Name,
+		Scope:    t.Classname,
+		Path:     t.File,
+		Line:     t.Line,
+		Status:   status,
+		Duration: secondsToDuration(t.Time),
+	}
+}
+
+// secondsToDuration converts the duration in seconds reported by the test runners to a time.Duration.
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// JUnitTestSuite represents a <testsuite> element in a JUnit XML report.
+// Test suites can be nested, which some tools use to group test cases by file.
+type JUnitTestSuite struct {
+	Name       string           `xml:"name,attr"`
+	Tests      int              `xml:"tests,attr"`
+	Failures   int              `xml:"failures,attr"`
+	Errors     int              `xml:"errors,attr"`
+	TestSuites []JUnitTestSuite `xml:"testsuite"`
+	TestCases  []JUnitTestCase  `xml:"testcase"`
+}
+
+// JUnitReport is the structure for a JUnit XML report.
+// The root element can be either <testsuites> or a single <testsuite>,
+// in both cases the test suites are available in TestSuites.
+type JUnitReport struct {
+	TestSuites []JUnitTestSuite
+}
+
+// TestCases returns all the test cases in the report, including the ones in nested test suites.
+func (r JUnitReport) TestCases() []JUnitTestCase {
+	var testCases []JUnitTestCase
+	var walk func(suites []JUnitTestSuite)
+	walk = func(suites []JUnitTestSuite) {
+		for _, suite := range suites {
+			testCases = append(testCases, suite.TestCases...)
+			walk(suite.TestSuites)
+		}
+	}
+	walk(r.TestSuites)
+	return testCases
+}
+
+// ParseJUnitReport reads and parses the JUnit XML report at the given path.
+func ParseJUnitReport(path string) (JUnitReport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return JUnitReport{}, fmt.Errorf("failed to read JUnit XML output: %v", err)
+	}
+
+	var root struct {
+		XMLName xml.Name
+	}
+
+	if err := xml.Unmarshal(data, &root); err != nil {
+		return JUnitReport{}, fmt.Errorf("failed to parse JUnit XML output: %s", err)
+	}
+
+	var report JUnitReport
+
+	switch root.XMLName.Local {
+	case "testsuites":
+		var suites struct {
+			TestSuites []JUnitTestSuite `xml:"testsuite"`
+		}
+		if err := xml.Unmarshal(data, &suites); err != nil {
+			return JUnitReport{}, fmt.Errorf("failed to parse JUnit XML output: %s", err)
+		}
+		report.TestSuites = suites.TestSuites
+	case "testsuite":
+		var suite JUnitTestSuite
+		if err := xml.Unmarshal(data, &suite); err != nil {
+			return JUnitReport{}, fmt.Errorf("failed to parse JUnit XML output: %s", err)
+		}
+		report.TestSuites = []JUnitTestSuite{suite}
+	default:
+		return JUnitReport{}, fmt.Errorf("failed to parse JUnit XML output: unexpected root element %q", root.XMLName.Local)
+	}
+
+	return report, nil
+}
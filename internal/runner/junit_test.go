@@ -0,0 +1,62 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseJUnitReport_TestSuites(t *testing.T) {
+	path := "./fixtures/junit/testsuites.xml"
+	report, err := ParseJUnitReport(path)
+	if err != nil {
+		t.Fatalf("ParseJUnitReport(%q) error = %v", path, err)
+	}
+
+	testCases := report.TestCases()
+	if len(testCases) != 5 {
+		t.Fatalf("len(report.TestCases()) = %d, want 5", len(testCases))
+	}
+
+	var failed []string
+	for _, testCase := range testCases {
+		if testCase.Failed() {
+			failed = append(failed, testCase.Name)
+		}
+	}
+
+	want := []string{"test_is_sweet", "test_banana[yellow]"}
+	if diff := cmp.Diff(failed, want); diff != "" {
+		t.Errorf("ParseJUnitReport(%q) failed test cases diff (-got +want):\n%s", path, diff)
+	}
+
+	if testCases[3].Skipped == nil {
+		t.Errorf("ParseJUnitReport(%q) test case %q is not skipped", path, testCases[3].Name)
+	}
+}
+
+func TestParseJUnitReport_TestSuite(t *testing.T) {
+	path := "./fixtures/junit/testsuite.xml"
+	report, err := ParseJUnitReport(path)
+	if err != nil {
+		t.Fatalf("ParseJUnitReport(%q) error = %v", path, err)
+	}
+
+	var got []string
+	for _, testCase := range report.TestCases() {
+		got = append(got, testCase.Classname+" "+testCase.Name)
+	}
+
+	want := []string{"Banana is yellow", "Apple is red"}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("ParseJUnitReport(%q) test cases diff (-got +want):\n%s", path, diff)
+	}
+}
+
+func TestParseJUnitReport_InvalidReport(t *testing.T) {
+	path := "../../test/fixtures/report.json"
+	_, err := ParseJUnitReport(path)
+	if err == nil {
+		t.Errorf("ParseJUnitReport(%q) error = nil, want error", path)
+	}
+}
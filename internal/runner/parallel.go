@@ -0,0 +1,138 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/buildkite/test-engine-client/internal/plan"
+)
+
+var _ = TestRunner(Parallel{})
+
+// Parallel runs the tests of a node with several test runners at the same time,
+// one for each worker. The tests are split between the workers by their estimated
+// duration, and the results of the workers are merged into a single result.
+//
+// Each worker writes its result to its own result path, and its test command is run
+// with TEST_ENV_NUMBER and BUILDKITE_TEST_ENGINE_WORKER_INDEX set, so that the workers
+// can use separate resources such as databases. See workerRunnerConfig.
+type Parallel struct {
+	workers []TestRunner
+	// durations are the estimated durations of the tests keyed by path,
+	// used to split the tests between the workers.
+	durations map[string]int
+}
+
+func NewParallel(workers []TestRunner) Parallel {
+	return Parallel{
+		workers:   workers,
+		durations: map[string]int{},
+	}
+}
+
+// workerRunnerConfig returns the runner config of the worker with the given zero-based index.
+// The result path has the worker index appended to its name, e.g. "tmp/rspec-w1.json".
+// The "w" keeps the worker apart from the attempt and invocation numbers of the result path,
+// e.g. "tmp/rspec-0-1-w0.json" for the second invocation of the first run of the first worker,
+// see invocationResultPath.
+// TEST_ENV_NUMBER follows the parallel_tests convention, which is empty for the first worker
+// and starts at 2 for the second worker.
+func workerRunnerConfig(r RunnerConfig, index int) RunnerConfig {
+	ext := filepath.Ext(r.ResultPath)
+	r.ResultPath = fmt.Sprintf("%s-w%d%s", strings.TrimSuffix(r.ResultPath, ext), index, ext)
+
+	testEnvNumber := ""
+	if index > 0 {
+		testEnvNumber = strconv.Itoa(index + 1)
+	}
+
+	r.Env = append(append([]string{}, r.Env...),
+		"TEST_ENV_NUMBER="+testEnvNumber,
+		"BUILDKITE_TEST_ENGINE_WORKER_INDEX="+strconv.Itoa(index),
+	)
+	return r
+}
+
+func (p Parallel) Name() string {
+	return p.workers[0].Name()
+}
+
+// GetFiles returns the test files discovered by the first worker.
+// All workers have the same test file pattern.
+func (p Parallel) GetFiles() ([]string, error) {
+	return p.workers[0].GetFiles()
+}
+
+// GetExamples returns the examples of the files using the first worker.
+func (p Parallel) GetExamples(files []string) ([]plan.TestCase, error) {
+	return p.workers[0].GetExamples(files)
+}
+
+// SetEstimatedDurations records the estimated durations of the test cases,
+// which are used to split the tests between the workers in the next runs.
+func (p Parallel) SetEstimatedDurations(testCases []plan.TestCase) {
+	for _, testCase := range testCases {
+		p.durations[testCase.Path] = testCase.EstimatedDuration
+	}
+}
+
+// Run splits the test cases between the workers and runs them at the same time.
+// The results are merged with MergeRunResults, so the failed tests of all workers
+// are retried together. The errors of the workers are joined.
+//
+// Workers without any test case are not run, because most test runners would run
+// the whole test suite when no test is given.
+func (p Parallel) Run(testCases []string, attempt int) (RunResult, error) {
+	groups := p.split(testCases)
+
+	results := make([]RunResult, len(groups))
+	errs := make([]error, len(groups))
+
+	var wg sync.WaitGroup
+	for i, group := range groups {
+		if len(group) == 0 {
+			results[i] = RunResult{Status: RunStatusPassed}
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, group []string) {
+			defer wg.Done()
+			results[i], errs[i] = p.workers[i].Run(group, attempt)
+		}(i, group)
+	}
+	wg.Wait()
+
+	return MergeRunResults(results...), errors.Join(errs...)
+}
+
+// split distributes the test cases between the workers by their estimated durations,
+// using the same algorithm as the fallback plan. Test cases without an estimated duration,
+// such as failed tests being retried, are distributed evenly.
+func (p Parallel) split(testCases []string) [][]string {
+	cases := make([]plan.TestCase, 0, len(testCases))
+	for _, testCase := range testCases {
+		cases = append(cases, plan.TestCase{
+			Path:              testCase,
+			EstimatedDuration: p.durations[testCase],
+		})
+	}
+
+	workerPlan := plan.CreatePlan(cases, len(p.workers))
+
+	groups := make([][]string, len(p.workers))
+	for i := range groups {
+		task, ok := workerPlan.Tasks[strconv.Itoa(i)]
+		if !ok {
+			continue
+		}
+		for _, testCase := range task.Tests {
+			groups[i] = append(groups[i], testCase.Path)
+		}
+	}
+	return groups
+}
@@ -0,0 +1,179 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/buildkite/test-engine-client/internal/plan"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestWorkerRunnerConfig(t *testing.T) {
+	r := RunnerConfig{
+		TestRunner: "rspec",
+		ResultPath: "tmp/rspec.json",
+	}
+
+	cases := []struct {
+		index int
+		want  RunnerConfig
+	}{
+		{
+			index: 0,
+			want: RunnerConfig{
+				TestRunner: "rspec",
+				ResultPath: "tmp/rspec-w0.json",
+				Env:        []string{"TEST_ENV_NUMBER=", "BUILDKITE_TEST_ENGINE_WORKER_INDEX=0"},
+			},
+		},
+		{
+			index: 2,
+			want: RunnerConfig{
+				TestRunner: "rspec",
+				ResultPath: "tmp/rspec-w2.json",
+				Env:        []string{"TEST_ENV_NUMBER=3", "BUILDKITE_TEST_ENGINE_WORKER_INDEX=2"},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		got := workerRunnerConfig(r, c.index)
+		if diff := cmp.Diff(got, c.want, cmpopts.IgnoreUnexported(RunnerConfig{})); diff != "" {
+			t.Errorf("workerRunnerConfig(%d) diff (-got +want):\n%s", c.index, diff)
+		}
+	}
+}
+
+func TestParallelSplit(t *testing.T) {
+	p := NewParallel([]TestRunner{Custom{}, Custom{}})
+	p.SetEstimatedDurations([]plan.TestCase{
+		{Path: "a", EstimatedDuration: 100},
+		{Path: "b", EstimatedDuration: 60},
+		{Path: "c", EstimatedDuration: 30},
+		{Path: "d", EstimatedDuration: 20},
+	})
+
+	got := p.split([]string{"a", "b", "c", "d"})
+	want := [][]string{{"a"}, {"b", "c", "d"}}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("Parallel.split() diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestParallelRun(t *testing.T) {
+	dir := t.TempDir()
+	runnerConfig := RunnerConfig{
+		TestCommand: "../../test/support/junit.sh {{resultPath}} {{testExamples}}",
+		ResultPath:  filepath.Join(dir, "junit.xml"),
+	}
+
+	var workers []TestRunner
+	for i := 0; i < 3; i++ {
+		workers = append(workers, NewCustom(workerRunnerConfig(runnerConfig, i)))
+	}
+	p := NewParallel(workers)
+
+	testCases := []string{"apple", "banana fail", "cherry", "damson fail"}
+	got, err := p.Run(testCases, 0)
+	if err != nil {
+		t.Errorf("Parallel.Run(%q) error = %v", testCases, err)
+	}
+
+	sort.Strings(got.FailedTests)
+	want := RunResult{
+		Status:      RunStatusFailed,
+		FailedTests: []string{"Fake::banana fail", "Fake::damson fail"},
+	}
+
+	if diff := cmp.Diff(got, want, cmpopts.IgnoreFields(RunResult{}, "Tests")); diff != "" {
+		t.Errorf("Parallel.Run(%q) diff (-got +want):\n%s", testCases, diff)
+	}
+
+	for _, path := range []string{"junit-w0.xml", "junit-w1.xml", "junit-w2.xml"} {
+		if _, err := os.Stat(filepath.Join(dir, path)); err != nil {
+			t.Errorf("worker result %s: %v", path, err)
+		}
+	}
+}
+
+func TestParallelRun_Invocations(t *testing.T) {
+	setMaxArgsSize(t, minArgsSize)
+
+	dir := t.TempDir()
+	runnerConfig := RunnerConfig{
+		TestCommand: "../../test/support/junit.sh {{resultPath}} {{testExamples}}",
+		ResultPath:  filepath.Join(dir, "junit-{{attempt}}.xml"),
+	}
+
+	p := NewParallel([]TestRunner{
+		NewCustom(workerRunnerConfig(runnerConfig, 0)),
+		NewCustom(workerRunnerConfig(runnerConfig, 1)),
+	})
+
+	// each test case fills half of a batch, so each worker runs its 2 test cases in 2 invocations
+	long := strings.Repeat("a", minArgsSize/2)
+	testCases := []string{"apple fail " + long, "banana " + long, "cherry fail " + long, "damson " + long}
+	got, err := p.Run(testCases, 0)
+	if err != nil {
+		t.Errorf("Parallel.Run() error = %v", err)
+	}
+
+	// The failures of every invocation of every worker are kept.
+	sort.Strings(got.FailedTests)
+	want := []string{"Fake::apple fail " + long, "Fake::cherry fail " + long}
+	if diff := cmp.Diff(got.FailedTests, want); diff != "" {
+		t.Errorf("Parallel.Run() failed tests diff (-got +want):\n%s", diff)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, entry := range entries {
+		paths = append(paths, entry.Name())
+	}
+
+	// Each invocation of each worker has its own result path.
+	wantPaths := []string{"junit-0-1-w0.xml", "junit-0-1-w1.xml", "junit-0-w0.xml", "junit-0-w1.xml"}
+	if diff := cmp.Diff(paths, wantPaths); diff != "" {
+		t.Errorf("result paths diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestParallelRun_EmptyWorker(t *testing.T) {
+	dir := t.TempDir()
+	runnerConfig := RunnerConfig{
+		TestCommand: "../../test/support/junit.sh {{resultPath}} {{testExamples}}",
+		ResultPath:  filepath.Join(dir, "junit.xml"),
+	}
+
+	p := NewParallel([]TestRunner{
+		NewCustom(workerRunnerConfig(runnerConfig, 0)),
+		NewCustom(workerRunnerConfig(runnerConfig, 1)),
+	})
+
+	got, err := p.Run([]string{"apple"}, 0)
+	if err != nil {
+		t.Errorf("Parallel.Run() error = %v", err)
+	}
+
+	want := RunResult{
+		Status: RunStatusPassed,
+		Tests:  []TestResult{{Id: "Fake::apple", Name: "apple", Scope: "Fake", Status: TestStatusPassed}},
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("Parallel.Run() diff (-got +want):\n%s", diff)
+	}
+
+	// the worker without tests is not run
+	if _, err := os.Stat(filepath.Join(dir, "junit-w1.xml")); !os.IsNotExist(err) {
+		t.Errorf("worker without tests wrote a result, err = %v", err)
+	}
+}
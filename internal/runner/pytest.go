@@ -0,0 +1,200 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"github.com/buildkite/test-engine-client/internal/debug"
+	"github.com/buildkite/test-engine-client/internal/plan"
+	"github.com/kballard/go-shellquote"
+)
+
+var _ = TestRunner(Pytest{})
+
+type Pytest struct {
+	RunnerConfig
+}
+
+func NewPytest(p RunnerConfig) Pytest {
+	if p.TestCommand == "" {
+		// junit_family=xunit1 adds the file attribute to each test case in the report,
+		// which we need to build the node ID of the failed tests.
+		p.TestCommand = "pytest --junitxml {{resultPath}} -o junit_family=xunit1 {{testExamples}}"
+	}
+
+	if p.TestFilePattern == "" {
+		p.TestFilePattern = "tests/**/test_*.py"
+	}
+
+	if p.RetryTestCommand == "" {
+		p.RetryTestCommand = p.TestCommand
+	}
+
+	p.invocations = newInvocationCounter()
+	return Pytest{p}
+}
+
+func (p Pytest) Name() string {
+	return "pytest"
+}
+
+// GetFiles returns an array of file names using the discovery pattern.
+func (p Pytest) GetFiles() ([]string, error) {
+	debug.Println("Discovering test files with include pattern:", p.TestFilePattern, "exclude pattern:", p.TestFileExcludePattern)
+	files, err := discoverTestFiles(p.TestFilePattern, p.TestFileExcludePattern)
+	debug.Println("Discovered", len(files), "files")
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no files found with pattern %q and exclude pattern %q", p.TestFilePattern, p.TestFileExcludePattern)
+	}
+
+	return files, nil
+}
+
+// Run executes the test command with the given test cases.
+// If attempt is greater than zero, it will run the command using the retry test command,
+// otherwise it will use the test command.
+//
+// Test cases can be test files or pytest node IDs, e.g. "tests/test_fruits.py::TestApple::test_is_red".
+// The failed tests are returned as node IDs so they can be passed back to Run for retry.
+//
+// Error is returned if the command fails to run, exits prematurely, or if the
+// output cannot be parsed.
+//
+// Test failure is not considered an error, and is instead returned as a RunResult.
+//
+// The test cases are run in several invocations if they don't fit in the arguments
+// of a single one, see runInBatches.
+func (p Pytest) Run(testCases []string, attempt int) (RunResult, error) {
+	command := p.TestCommand
+
+	if attempt > 0 {
+		command = p.RetryTestCommand
+	}
+
+	return runInBatches(command, testCases, func(command string, testCases []string) (RunResult, error) {
+		invocation := p
+		invocation.ResultPath = p.invocationResultPath(attempt)
+		return invocation.run(command, testCases)
+	})
+}
+
+// run executes the command with the given test cases and reads the result from the result path.
+func (p Pytest) run(command string, testCases []string) (RunResult, error) {
+	commandName, commandArgs, err := p.commandNameAndArgs(command, testCases)
+	if err != nil {
+		return RunResult{Status: RunStatusError}, fmt.Errorf("failed to build command: %w", err)
+	}
+
+	fmt.Printf("%s %s\n", commandName, strings.Join(commandArgs, " "))
+	cmd := exec.Command(commandName, commandArgs...)
+	cmd.Env = p.commandEnv()
+
+	err = runAndForwardSignal(cmd, p.Timeout)
+
+	if err == nil { // note: returning success early
+		return RunResult{Status: RunStatusPassed, Tests: readTestResults(p.ResultPath, ParseJUnitReport, pytestTestResults)}, nil
+	}
+
+	if ProcessSignaledError := new(ProcessSignaledError); errors.As(err, &ProcessSignaledError) {
+		return RunResult{Status: RunStatusError}, err
+	}
+
+	if timeoutError := new(TimeoutError); errors.As(err, &timeoutError) {
+		return RunResult{Status: RunStatusTimeout}, err
+	}
+
+	if exitError := new(exec.ExitError); errors.As(err, &exitError) {
+		report, parseErr := ParseJUnitReport(p.ResultPath)
+		if parseErr != nil {
+			fmt.Println("Buildkite Test Engine Client: Failed to read pytest output, tests will not be retried.")
+			return RunResult{Status: RunStatusError}, err
+		}
+
+		var failedTests []string
+		for _, testCase := range report.TestCases() {
+			if testCase.Failed() {
+				failedTests = append(failedTests, pytestNodeID(testCase))
+			}
+		}
+
+		if len(failedTests) > 0 {
+			return RunResult{Status: RunStatusFailed, FailedTests: failedTests, Tests: pytestTestResults(report)}, nil
+		}
+	}
+
+	return RunResult{Status: RunStatusError}, err
+}
+
+// pytestTestResults returns the results of the test cases in the report, identified by their node IDs.
+func pytestTestResults(report JUnitReport) []TestResult {
+	var tests []TestResult
+	for _, testCase := range report.TestCases() {
+		tests = append(tests, testCase.testResult(pytestNodeID(testCase)))
+	}
+	return tests
+}
+
+// pytestNodeID builds the pytest node ID of a JUnit test case,
+// e.g. "tests/test_fruits.py::TestApple::test_is_red".
+//
+// pytest reports the dotted module path followed by the class names as the classname,
+// e.g. "tests.test_fruits.TestApple". When the report has the file attribute
+// (junit_family=xunit1), the module part is stripped from the classname to get the class names.
+// Otherwise, the class names are assumed to be the trailing parts of the classname
+// that start with "Test", which is the default python_classes pattern.
+func pytestNodeID(testCase JUnitTestCase) string {
+	parts := strings.Split(testCase.Classname, ".")
+	file := testCase.File
+
+	var classes []string
+	if file != "" {
+		module := strings.Split(strings.TrimSuffix(file, ".py"), "/")
+		if len(parts) >= len(module) && slices.Equal(parts[:len(module)], module) {
+			classes = parts[len(module):]
+		}
+	} else {
+		i := len(parts)
+		for i > 0 && strings.HasPrefix(parts[i-1], "Test") {
+			i--
+		}
+		file = strings.Join(parts[:i], "/") + ".py"
+		classes = parts[i:]
+	}
+
+	return strings.Join(append(append([]string{file}, classes...), testCase.Name), "::")
+}
+
+// commandNameAndArgs replaces the "{{testExamples}}" placeholder in the test command with the test cases.
+// It returns the command name and arguments to run the tests.
+func (p Pytest) commandNameAndArgs(cmd string, testCases []string) (string, []string, error) {
+	words, err := shellquote.Split(cmd)
+	if err != nil {
+		return "", []string{}, err
+	}
+
+	idx := slices.Index(words, "{{testExamples}}")
+	if idx < 0 {
+		words = append(words, testCases...)
+	} else {
+		words = slices.Replace(words, idx, idx+1, testCases...)
+	}
+
+	idx = slices.Index(words, "{{resultPath}}")
+	if idx >= 0 {
+		words = slices.Replace(words, idx, idx+1, p.ResultPath)
+	}
+
+	return words[0], words[1:], nil
+}
+
+func (p Pytest) GetExamples(files []string) ([]plan.TestCase, error) {
+	return nil, fmt.Errorf("not supported in pytest")
+}
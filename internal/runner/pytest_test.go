@@ -0,0 +1,214 @@
+package runner
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestNewPytest(t *testing.T) {
+	cases := []struct {
+		input RunnerConfig
+		want  RunnerConfig
+	}{
+		//default
+		{
+			input: RunnerConfig{},
+			want: RunnerConfig{
+				TestCommand:            "pytest --junitxml {{resultPath}} -o junit_family=xunit1 {{testExamples}}",
+				TestFilePattern:        "tests/**/test_*.py",
+				TestFileExcludePattern: "",
+				RetryTestCommand:       "pytest --junitxml {{resultPath}} -o junit_family=xunit1 {{testExamples}}",
+			},
+		},
+		// custom
+		{
+			input: RunnerConfig{
+				TestCommand:            "python -m pytest --junitxml {{resultPath}} {{testExamples}}",
+				TestFilePattern:        "src/**/*_test.py",
+				TestFileExcludePattern: "src/vendor",
+				RetryTestCommand:       "python -m pytest --junitxml {{resultPath}} --lf {{testExamples}}",
+			},
+			want: RunnerConfig{
+				TestCommand:            "python -m pytest --junitxml {{resultPath}} {{testExamples}}",
+				TestFilePattern:        "src/**/*_test.py",
+				TestFileExcludePattern: "src/vendor",
+				RetryTestCommand:       "python -m pytest --junitxml {{resultPath}} --lf {{testExamples}}",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		got := NewPytest(c.input)
+		if diff := cmp.Diff(got.RunnerConfig, c.want, cmpopts.IgnoreUnexported(RunnerConfig{})); diff != "" {
+			t.Errorf("NewPytest(%v) diff (-got +want):\n%s", c.input, diff)
+		}
+	}
+}
+
+func TestPytestRun(t *testing.T) {
+	pytest := NewPytest(RunnerConfig{
+		TestCommand: "pytest --rootdir . --junitxml {{resultPath}} -o junit_family=xunit1 {{testExamples}}",
+		ResultPath:  "tmp/pytest.xml",
+	})
+
+	t.Cleanup(func() {
+		os.Remove(pytest.ResultPath)
+	})
+
+	files := []string{"./fixtures/pytest/tests/test_spells.py"}
+	got, err := pytest.Run(files, 0)
+
+	want := RunResult{
+		Status: RunStatusPassed,
+	}
+
+	if err != nil {
+		t.Errorf("Pytest.Run(%q) error = %v", files, err)
+	}
+
+	if diff := cmp.Diff(got, want, cmpopts.IgnoreFields(RunResult{}, "Tests")); diff != "" {
+		t.Errorf("Pytest.Run(%q) diff (-got +want):\n%s", files, diff)
+	}
+}
+
+func TestPytestRun_TestFailed(t *testing.T) {
+	pytest := NewPytest(RunnerConfig{
+		TestCommand: "pytest --rootdir . --junitxml {{resultPath}} -o junit_family=xunit1 {{testExamples}}",
+		ResultPath:  "tmp/pytest.xml",
+	})
+
+	t.Cleanup(func() {
+		os.Remove(pytest.ResultPath)
+	})
+
+	files := []string{"fixtures/pytest/tests/test_failure.py", "fixtures/pytest/tests/test_spells.py"}
+	got, err := pytest.Run(files, 0)
+
+	want := RunResult{
+		Status:      RunStatusFailed,
+		FailedTests: []string{"fixtures/pytest/tests/test_failure.py::test_this_will_fail_for_sure"},
+	}
+
+	if err != nil {
+		t.Errorf("Pytest.Run(%q) error = %v", files, err)
+	}
+
+	if diff := cmp.Diff(got, want, cmpopts.IgnoreFields(RunResult{}, "Tests")); diff != "" {
+		t.Errorf("Pytest.Run(%q) diff (-got +want):\n%s", files, diff)
+	}
+}
+
+func TestPytestRun_CommandFailed(t *testing.T) {
+	pytest := NewPytest(RunnerConfig{
+		TestCommand: "pytest --invalid-option",
+	})
+	files := []string{}
+	got, err := pytest.Run(files, 0)
+
+	want := RunResult{
+		Status: RunStatusError,
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("Pytest.Run(%q) diff (-got +want):\n%s", files, diff)
+	}
+
+	exitError := new(exec.ExitError)
+	if !errors.As(err, &exitError) {
+		t.Errorf("Pytest.Run(%q) error type = %T (%v), want *exec.ExitError", files, err, err)
+	}
+}
+
+func TestPytestRun_SignaledError(t *testing.T) {
+	pytest := NewPytest(RunnerConfig{
+		TestCommand: "../../test/support/segv.sh",
+	})
+	files := []string{"./fixtures/pytest/tests/test_failure.py"}
+
+	got, err := pytest.Run(files, 0)
+
+	want := RunResult{
+		Status: RunStatusError,
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("Pytest.Run(%q) diff (-got +want):\n%s", files, diff)
+	}
+
+	signalError := new(ProcessSignaledError)
+	if !errors.As(err, &signalError) {
+		t.Errorf("Pytest.Run(%q) error type = %T (%v), want *ErrProcessSignaled", files, err, err)
+	}
+	if signalError.Signal != syscall.SIGSEGV {
+		t.Errorf("Pytest.Run(%q) signal = %d, want %d", files, syscall.SIGSEGV, signalError.Signal)
+	}
+}
+
+func TestPytestCommandNameAndArgs(t *testing.T) {
+	testCases := []string{"tests/test_user.py", "tests/test_billing.py::test_invoice"}
+	testCommand := "pytest --junitxml {{resultPath}} {{testExamples}} -x"
+
+	pytest := Pytest{
+		RunnerConfig{
+			TestCommand: testCommand,
+			ResultPath:  "pytest.xml",
+		},
+	}
+
+	gotName, gotArgs, err := pytest.commandNameAndArgs(testCommand, testCases)
+	if err != nil {
+		t.Errorf("commandNameAndArgs(%q, %q) error = %v", testCases, testCommand, err)
+	}
+
+	wantName := "pytest"
+	wantArgs := []string{"--junitxml", "pytest.xml", "tests/test_user.py", "tests/test_billing.py::test_invoice", "-x"}
+
+	if diff := cmp.Diff(gotName, wantName); diff != "" {
+		t.Errorf("commandNameAndArgs(%q, %q) diff (-got +want):\n%s", testCases, testCommand, diff)
+	}
+	if diff := cmp.Diff(gotArgs, wantArgs); diff != "" {
+		t.Errorf("commandNameAndArgs(%q, %q) diff (-got +want):\n%s", testCases, testCommand, diff)
+	}
+}
+
+func TestPytestNodeID(t *testing.T) {
+	cases := []struct {
+		testCase JUnitTestCase
+		want     string
+	}{
+		{
+			testCase: JUnitTestCase{Classname: "tests.test_fruits.TestApple", Name: "test_is_red", File: "tests/test_fruits.py"},
+			want:     "tests/test_fruits.py::TestApple::test_is_red",
+		},
+		{
+			testCase: JUnitTestCase{Classname: "tests.test_fruits", Name: "test_banana[yellow]", File: "tests/test_fruits.py"},
+			want:     "tests/test_fruits.py::test_banana[yellow]",
+		},
+		{
+			testCase: JUnitTestCase{Classname: "tests.test_fruits.TestApple.TestWhenRipe", Name: "test_is_sweet", File: "tests/test_fruits.py"},
+			want:     "tests/test_fruits.py::TestApple::TestWhenRipe::test_is_sweet",
+		},
+		// without the file attribute (junit_family=xunit2)
+		{
+			testCase: JUnitTestCase{Classname: "tests.test_fruits.TestApple", Name: "test_is_red"},
+			want:     "tests/test_fruits.py::TestApple::test_is_red",
+		},
+		{
+			testCase: JUnitTestCase{Classname: "tests.test_fruits", Name: "test_cherry"},
+			want:     "tests/test_fruits.py::test_cherry",
+		},
+	}
+
+	for _, c := range cases {
+		got := pytestNodeID(c.testCase)
+		if diff := cmp.Diff(got, c.want); diff != "" {
+			t.Errorf("pytestNodeID(%v) diff (-got +want):\n%s", c.testCase, diff)
+		}
+	}
+}
@@ -1,14 +1,196 @@
 package runner
 
+import (
+	"slices"
+	"time"
+
+	"github.com/buildkite/test-engine-client/internal/debug"
+)
+
 type RunStatus string
 
 const (
 	RunStatusPassed RunStatus = "passed"
 	RunStatusFailed RunStatus = "failed"
 	RunStatusError  RunStatus = "error"
+	// RunStatusTimeout is the status when the test command is terminated by a timeout.
+	RunStatusTimeout RunStatus = "timeout"
 )
 
 type RunResult struct {
 	Status      RunStatus
 	FailedTests []string
+	// FlakyTests are the tests that failed and then passed when they were retried.
+	FlakyTests []string
+	// QuarantinedTests are the failed tests that are quarantined, which don't fail the run.
+	QuarantinedTests []string
+	// Tests are the results of the individual tests that were run, read from the result path.
+	// They are only available when the result of the test runner can be read.
+	Tests []TestResult
+	// Attempts are the outcomes of the first run of the tests and of each retry, in order.
+	Attempts []AttemptResult
+}
+
+// AttemptResult is the outcome of an attempt of running the tests, counted from the results
+// of the individual tests, so the counts are zero when the result of the test runner can't be read.
+type AttemptResult struct {
+	Passed   int
+	Failed   int
+	Skipped  int
+	Duration time.Duration
+	// Tests are the results of the individual tests run in the attempt.
+	Tests []TestResult
+}
+
+// NewAttemptResult counts the tests of an attempt by their status.
+// The tests are expected to be only the tests requested in the attempt, see RequestedTests.
+func NewAttemptResult(tests []TestResult, duration time.Duration) AttemptResult {
+	attempt := AttemptResult{Duration: duration, Tests: tests}
+	for _, test := range tests {
+		switch test.Status {
+		case TestStatusPassed:
+			attempt.Passed++
+		case TestStatusFailed:
+			attempt.Failed++
+		case TestStatusSkipped:
+			attempt.Skipped++
+		}
+	}
+	return attempt
+}
+
+// TestStatus is the outcome of a single test.
+type TestStatus string
+
+const (
+	TestStatusPassed  TestStatus = "passed"
+	TestStatusFailed  TestStatus = "failed"
+	TestStatusSkipped TestStatus = "skipped"
+)
+
+// TestResult is the result of a single test.
+type TestResult struct {
+	// Id identifies the test the same way as the failed tests of RunResult,
+	// e.g. the example ID for RSpec and the full name for Jest.
+	Id string
+	// Name is the name of the test.
+	Name string
+	// Scope is the group the test belongs to, e.g. the example group for RSpec or the class for pytest.
+	Scope string
+	// Path is the path of the test file, if the test runner reports it.
+	Path string
+	// Line is the line of the test in the test file, or 0 if the test runner doesn't report it.
+	Line     int
+	Status   TestStatus
+	Duration time.Duration
+	// Retries is the number of times the test was retried.
+	Retries int
+}
+
+// MergeRunResults combines the results of running subsets of the tests into a single result.
+// The merged status is the most severe status of the results, i.e. error, timeout, failed, then passed,
+// and the failed, flaky, quarantined and individual tests are concatenated in order.
+// The attempts with the same index are added up, e.g. the first runs of all results.
+func MergeRunResults(results ...RunResult) RunResult {
+	merged := RunResult{Status: RunStatusPassed}
+	for _, result := range results {
+		switch {
+		case result.Status == RunStatusError:
+			merged.Status = RunStatusError
+		case result.Status == RunStatusTimeout && merged.Status != RunStatusError:
+			merged.Status = RunStatusTimeout
+		case result.Status == RunStatusFailed && merged.Status != RunStatusError && merged.Status != RunStatusTimeout:
+			merged.Status = RunStatusFailed
+		}
+		merged.FailedTests = append(merged.FailedTests, result.FailedTests...)
+		merged.FlakyTests = append(merged.FlakyTests, result.FlakyTests...)
+		merged.QuarantinedTests = append(merged.QuarantinedTests, result.QuarantinedTests...)
+		merged.Tests = append(merged.Tests, result.Tests...)
+
+		for i, attempt := range result.Attempts {
+			if i == len(merged.Attempts) {
+				merged.Attempts = append(merged.Attempts, AttemptResult{})
+			}
+			merged.Attempts[i].Passed += attempt.Passed
+			merged.Attempts[i].Failed += attempt.Failed
+			merged.Attempts[i].Skipped += attempt.Skipped
+			merged.Attempts[i].Duration += attempt.Duration
+			merged.Attempts[i].Tests = append(merged.Attempts[i].Tests, attempt.Tests...)
+		}
+	}
+	return merged
+}
+
+// Quarantine moves the failed tests that are quarantined from FailedTests to QuarantinedTests.
+// A failed run passes when all of its failed tests are quarantined.
+func (r RunResult) Quarantine(quarantinedTests map[string]bool) RunResult {
+	var failedTests []string
+	for _, test := range r.FailedTests {
+		if quarantinedTests[test] {
+			r.QuarantinedTests = append(r.QuarantinedTests, test)
+		} else {
+			failedTests = append(failedTests, test)
+		}
+	}
+
+	if r.Status == RunStatusFailed && len(failedTests) == 0 && len(r.QuarantinedTests) > 0 {
+		r.Status = RunStatusPassed
+	}
+	r.FailedTests = failedTests
+	return r
+}
+
+// readTestResults reads the results of the individual tests from the report at the result path,
+// using parse to read the report and results to convert it.
+// The results are only used for reporting, so nothing is returned if the report can't be read.
+func readTestResults[R any](resultPath string, parse func(string) (R, error), results func(R) []TestResult) []TestResult {
+	report, err := parse(resultPath)
+	if err != nil {
+		debug.Printf("Couldn't read the test results: %v", err)
+		return nil
+	}
+	return results(report)
+}
+
+// RequestedTests returns the results of the tests whose Id is one of ids.
+// Test runners may report more tests than were requested, e.g. Jest reports every test
+// that doesn't match the test name pattern as skipped.
+func RequestedTests(tests []TestResult, ids []string) []TestResult {
+	requested := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		requested[id] = true
+	}
+
+	var filtered []TestResult
+	for _, test := range tests {
+		if requested[test.Id] {
+			filtered = append(filtered, test)
+		}
+	}
+	return filtered
+}
+
+// MergeRetriedTests updates the results of the tests with the results of a retry,
+// so that each test has the outcome of its last attempt and the number of times it was retried.
+// Retried tests that are not in tests are appended. The given tests are not modified,
+// as they are also the results of the attempt they were run in.
+func MergeRetriedTests(tests []TestResult, retried []TestResult) []TestResult {
+	tests = slices.Clone(tests)
+	index := make(map[string]int, len(tests))
+	for i, test := range tests {
+		index[test.Id] = i
+	}
+
+	for _, test := range retried {
+		i, ok := index[test.Id]
+		if !ok {
+			index[test.Id] = len(tests)
+			tests = append(tests, test)
+			continue
+		}
+
+		test.Retries = tests[i].Retries + 1
+		tests[i] = test
+	}
+	return tests
 }
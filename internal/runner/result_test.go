@@ -0,0 +1,169 @@
+package runner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMergeRunResults(t *testing.T) {
+	cases := []struct {
+		results []RunResult
+		want    RunResult
+	}{
+		{
+			results: []RunResult{},
+			want:    RunResult{Status: RunStatusPassed},
+		},
+		{
+			results: []RunResult{
+				{Status: RunStatusPassed},
+				{Status: RunStatusFailed, FailedTests: []string{"a"}},
+				{Status: RunStatusFailed, FailedTests: []string{"b", "c"}},
+			},
+			want: RunResult{Status: RunStatusFailed, FailedTests: []string{"a", "b", "c"}},
+		},
+		{
+			results: []RunResult{
+				{Status: RunStatusFailed, FailedTests: []string{"a"}},
+				{Status: RunStatusError},
+				{Status: RunStatusPassed},
+			},
+			want: RunResult{Status: RunStatusError, FailedTests: []string{"a"}},
+		},
+		{
+			results: []RunResult{
+				{Status: RunStatusFailed, FailedTests: []string{"a"}},
+				{Status: RunStatusTimeout},
+				{Status: RunStatusFailed, FailedTests: []string{"b"}},
+			},
+			want: RunResult{Status: RunStatusTimeout, FailedTests: []string{"a", "b"}},
+		},
+		{
+			results: []RunResult{
+				{Status: RunStatusPassed, FlakyTests: []string{"a"}},
+				{Status: RunStatusFailed, FailedTests: []string{"b"}, FlakyTests: []string{"c"}},
+			},
+			want: RunResult{Status: RunStatusFailed, FailedTests: []string{"b"}, FlakyTests: []string{"a", "c"}},
+		},
+		{
+			results: []RunResult{
+				{Status: RunStatusPassed, Attempts: []AttemptResult{{Passed: 2, Failed: 1, Duration: time.Second}, {Passed: 1, Duration: time.Second}}},
+				{Status: RunStatusPassed, Attempts: []AttemptResult{{Passed: 3, Skipped: 1, Duration: 2 * time.Second}}},
+			},
+			want: RunResult{Status: RunStatusPassed, Attempts: []AttemptResult{
+				{Passed: 5, Failed: 1, Skipped: 1, Duration: 3 * time.Second},
+				{Passed: 1, Duration: time.Second},
+			}},
+		},
+	}
+
+	for _, c := range cases {
+		got := MergeRunResults(c.results...)
+		if diff := cmp.Diff(got, c.want); diff != "" {
+			t.Errorf("MergeRunResults(%v) diff (-got +want):\n%s", c.results, diff)
+		}
+	}
+}
+
+func TestRunResultQuarantine(t *testing.T) {
+	quarantinedTests := map[string]bool{"b": true, "c": true}
+
+	cases := []struct {
+		result RunResult
+		want   RunResult
+	}{
+		{
+			result: RunResult{Status: RunStatusPassed},
+			want:   RunResult{Status: RunStatusPassed},
+		},
+		{
+			result: RunResult{Status: RunStatusFailed, FailedTests: []string{"a", "b"}},
+			want:   RunResult{Status: RunStatusFailed, FailedTests: []string{"a"}, QuarantinedTests: []string{"b"}},
+		},
+		{
+			result: RunResult{Status: RunStatusFailed, FailedTests: []string{"b", "c"}},
+			want:   RunResult{Status: RunStatusPassed, QuarantinedTests: []string{"b", "c"}},
+		},
+		{
+			result: RunResult{Status: RunStatusFailed},
+			want:   RunResult{Status: RunStatusFailed},
+		},
+		{
+			result: RunResult{Status: RunStatusError, FailedTests: []string{"b"}},
+			want:   RunResult{Status: RunStatusError, QuarantinedTests: []string{"b"}},
+		},
+	}
+
+	for _, c := range cases {
+		got := c.result.Quarantine(quarantinedTests)
+		if diff := cmp.Diff(got, c.want); diff != "" {
+			t.Errorf("%v.Quarantine(...) diff (-got +want):\n%s", c.result, diff)
+		}
+	}
+}
+
+func TestRequestedTests(t *testing.T) {
+	tests := []TestResult{
+		{Id: "a", Status: TestStatusSkipped},
+		{Id: "b", Status: TestStatusFailed},
+		{Id: "c", Status: TestStatusSkipped},
+		{Id: "d", Status: TestStatusPassed},
+	}
+
+	got := RequestedTests(tests, []string{"b", "d", "e"})
+
+	want := []TestResult{
+		{Id: "b", Status: TestStatusFailed},
+		{Id: "d", Status: TestStatusPassed},
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("RequestedTests(...) diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestMergeRetriedTests(t *testing.T) {
+	tests := []TestResult{
+		{Id: "a", Status: TestStatusPassed, Duration: time.Second},
+		{Id: "b", Status: TestStatusFailed, Duration: time.Second},
+		{Id: "c", Status: TestStatusFailed, Duration: time.Second},
+	}
+
+	tests = MergeRetriedTests(tests, []TestResult{
+		{Id: "b", Status: TestStatusPassed, Duration: 2 * time.Second},
+		{Id: "c", Status: TestStatusFailed, Duration: 2 * time.Second},
+	})
+	got := MergeRetriedTests(tests, []TestResult{
+		{Id: "c", Status: TestStatusFailed, Duration: 3 * time.Second},
+		{Id: "d", Status: TestStatusPassed, Duration: 3 * time.Second},
+	})
+
+	want := []TestResult{
+		{Id: "a", Status: TestStatusPassed, Duration: time.Second},
+		{Id: "b", Status: TestStatusPassed, Duration: 2 * time.Second, Retries: 1},
+		{Id: "c", Status: TestStatusFailed, Duration: 3 * time.Second, Retries: 2},
+		{Id: "d", Status: TestStatusPassed, Duration: 3 * time.Second},
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("MergeRetriedTests(...) diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestNewAttemptResult(t *testing.T) {
+	tests := []TestResult{
+		{Id: "a", Status: TestStatusPassed},
+		{Id: "b", Status: TestStatusFailed},
+		{Id: "c", Status: TestStatusPassed},
+		{Id: "d", Status: TestStatusSkipped},
+	}
+
+	got := NewAttemptResult(tests, time.Minute)
+	want := AttemptResult{Passed: 2, Failed: 1, Skipped: 1, Duration: time.Minute, Tests: tests}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("NewAttemptResult(%v, %v) diff (-got +want):\n%s", tests, time.Minute, diff)
+	}
+}
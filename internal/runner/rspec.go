@@ -8,6 +8,7 @@ import (
 	"os/exec"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/buildkite/test-engine-client/internal/debug"
 	"github.com/buildkite/test-engine-client/internal/plan"
@@ -33,6 +34,7 @@ func NewRspec(r RunnerConfig) Rspec {
 		r.RetryTestCommand = r.TestCommand
 	}
 
+	r.invocations = newInvocationCounter()
 	return Rspec{r}
 }
 
@@ -65,20 +67,32 @@ func (r Rspec) GetFiles() ([]string, error) {
 }
 
 // Run executes the test command with the given test cases.
-// If retry is true, it will run the command using the retry test command,
+// If attempt is greater than zero, it will run the command using the retry test command,
 // otherwise it will use the test command.
 //
 // Error is returned if the command fails to run, exits prematurely, or if the
 // output cannot be parsed.
 //
 // Test failure is not considered an error, and is instead returned as a RunResult.
-func (r Rspec) Run(testCases []string, retry bool) (RunResult, error) {
+//
+// The test cases are run in several invocations if they don't fit in the arguments
+// of a single one, see runInBatches.
+func (r Rspec) Run(testCases []string, attempt int) (RunResult, error) {
 	command := r.TestCommand
 
-	if retry {
+	if attempt > 0 {
 		command = r.RetryTestCommand
 	}
 
+	return runInBatches(command, testCases, func(command string, testCases []string) (RunResult, error) {
+		invocation := r
+		invocation.ResultPath = r.invocationResultPath(attempt)
+		return invocation.run(command, testCases)
+	})
+}
+
+// run executes the command with the given test cases and reads the result from the result path.
+func (r Rspec) run(command string, testCases []string) (RunResult, error) {
 	commandName, commandArgs, err := r.commandNameAndArgs(command, testCases)
 	if err != nil {
 		return RunResult{Status: RunStatusError}, fmt.Errorf("failed to build command: %w", err)
@@ -86,17 +100,22 @@ func (r Rspec) Run(testCases []string, retry bool) (RunResult, error) {
 
 	fmt.Printf("%s %s\n", commandName, strings.Join(commandArgs, " "))
 	cmd := exec.Command(commandName, commandArgs...)
+	cmd.Env = r.commandEnv()
 
-	err = runAndForwardSignal(cmd)
+	err = runAndForwardSignal(cmd, r.Timeout)
 
 	if err == nil { // note: returning success early
-		return RunResult{Status: RunStatusPassed}, nil
+		return RunResult{Status: RunStatusPassed, Tests: readTestResults(r.ResultPath, r.ParseReport, RspecReport.testResults)}, nil
 	}
 
 	if ProcessSignaledError := new(ProcessSignaledError); errors.As(err, &ProcessSignaledError) {
 		return RunResult{Status: RunStatusError}, err
 	}
 
+	if timeoutError := new(TimeoutError); errors.As(err, &timeoutError) {
+		return RunResult{Status: RunStatusTimeout}, err
+	}
+
 	if exitError := new(exec.ExitError); errors.As(err, &exitError) {
 		report, parseErr := r.ParseReport(r.ResultPath)
 		if parseErr != nil {
@@ -113,7 +132,7 @@ func (r Rspec) Run(testCases []string, retry bool) (RunResult, error) {
 					failedTests = append(failedTests, example.Id)
 				}
 			}
-			return RunResult{Status: RunStatusFailed, FailedTests: failedTests}, nil
+			return RunResult{Status: RunStatusFailed, FailedTests: failedTests, Tests: report.testResults()}, nil
 		}
 	}
 
@@ -143,6 +162,32 @@ type RspecReport struct {
 	}
 }
 
+// testResults returns the results of the examples in the report, identified by their example IDs.
+// Pending examples are reported as skipped.
+func (r RspecReport) testResults() []TestResult {
+	var tests []TestResult
+	for _, example := range r.Examples {
+		status := TestStatusPassed
+		switch example.Status {
+		case "failed":
+			status = TestStatusFailed
+		case "pending":
+			status = TestStatusSkipped
+		}
+
+		tests = append(tests, TestResult{
+			Id:       example.Id,
+			Name:     example.Description,
+			Scope:    strings.TrimSpace(strings.TrimSuffix(example.FullDescription, example.Description)),
+			Path:     example.FilePath,
+			Line:     example.LineNumber,
+			Status:   status,
+			Duration: secondsToDuration(example.RunTime),
+		})
+	}
+	return tests
+}
+
 func (r Rspec) ParseReport(path string) (RspecReport, error) {
 	var report RspecReport
 	data, err := os.ReadFile(path)
@@ -157,6 +202,35 @@ func (r Rspec) ParseReport(path string) (RspecReport, error) {
 	return report, nil
 }
 
+// FileDurations returns the total run time of the examples of each file in the result of
+// the first run of a previous build, e.g. when the result path is restored from a cache.
+// When the first run had several invocations, the results of all of them are read, see invocationResultPath.
+// Nil is returned if there is no result to read.
+func (r Rspec) FileDurations() map[string]time.Duration {
+	var durations map[string]time.Duration
+	for invocation := 0; ; invocation++ {
+		report, err := r.ParseReport(r.invocationPath(0, invocation))
+		if err != nil {
+			if invocation == 0 {
+				debug.Printf("Couldn't read the durations of the files: %v", err)
+			}
+			return durations
+		}
+
+		if durations == nil {
+			durations = make(map[string]time.Duration)
+		}
+		for _, example := range report.Examples {
+			durations[example.FilePath] += secondsToDuration(example.RunTime)
+		}
+
+		// Without the attempt placeholder, every invocation writes to the same result path.
+		if !strings.Contains(r.ResultPath, attemptPlaceholder) {
+			return durations
+		}
+	}
+}
+
 // commandNameAndArgs replaces the "{{testExamples}}" placeholder in the test command with the test cases.
 // It returns the command name and arguments to run the tests.
 func (r Rspec) commandNameAndArgs(cmd string, testCases []string) (string, []string, error) {
@@ -195,6 +269,8 @@ func (r Rspec) GetExamples(files []string) ([]plan.TestCase, error) {
 		os.Remove(f.Name())
 	}()
 
+	// The test command may write to the result path too, which is overwritten by the first run.
+	r.ResultPath = r.attemptResultPath(0)
 	cmdName, cmdArgs, err := r.commandNameAndArgs(r.TestCommand, files)
 	if err != nil {
 		return nil, err
@@ -4,11 +4,14 @@ import (
 	"errors"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"syscall"
 	"testing"
+	"time"
 
 	"github.com/buildkite/test-engine-client/internal/plan"
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/kballard/go-shellquote"
 )
 
@@ -58,7 +61,7 @@ func TestNewRspec(t *testing.T) {
 
 	for _, c := range cases {
 		got := NewRspec(c.input)
-		if diff := cmp.Diff(got.RunnerConfig, c.want); diff != "" {
+		if diff := cmp.Diff(got.RunnerConfig, c.want, cmpopts.IgnoreUnexported(RunnerConfig{})); diff != "" {
 			t.Errorf("NewRspec(%v) diff (-got +want):\n%s", c.input, diff)
 		}
 	}
@@ -69,7 +72,7 @@ func TestRspecRun(t *testing.T) {
 		TestCommand: "rspec",
 	})
 	files := []string{"./fixtures/rspec/spec/spells/expelliarmus_spec.rb"}
-	got, err := rspec.Run(files, false)
+	got, err := rspec.Run(files, 0)
 
 	want := RunResult{
 		Status: RunStatusPassed,
@@ -79,7 +82,7 @@ func TestRspecRun(t *testing.T) {
 		t.Errorf("Rspec.Run(%q) error = %v", files, err)
 	}
 
-	if diff := cmp.Diff(got, want); diff != "" {
+	if diff := cmp.Diff(got, want, cmpopts.IgnoreFields(RunResult{}, "Tests")); diff != "" {
 		t.Errorf("Rspec.Run(%q) diff (-got +want):\n%s", files, diff)
 	}
 }
@@ -92,7 +95,7 @@ func TestRspecRun_RetryCommand(t *testing.T) {
 		},
 	}
 	files := []string{}
-	got, err := rspec.Run(files, true)
+	got, err := rspec.Run(files, 1)
 
 	want := RunResult{
 		Status: RunStatusPassed,
@@ -102,7 +105,7 @@ func TestRspecRun_RetryCommand(t *testing.T) {
 		t.Errorf("Rspec.Run(%q) error = %v", files, err)
 	}
 
-	if diff := cmp.Diff(got, want); diff != "" {
+	if diff := cmp.Diff(got, want, cmpopts.IgnoreFields(RunResult{}, "Tests")); diff != "" {
 		t.Errorf("Rspec.Run(%q) diff (-got +want):\n%s", files, diff)
 	}
 }
@@ -118,7 +121,7 @@ func TestRspecRun_TestFailedWithResultFile(t *testing.T) {
 	})
 
 	files := []string{"./fixtures/rspec/spec/failure_spec.rb"}
-	got, err := rspec.Run(files, false)
+	got, err := rspec.Run(files, 0)
 
 	want := RunResult{
 		Status:      RunStatusFailed,
@@ -129,7 +132,7 @@ func TestRspecRun_TestFailedWithResultFile(t *testing.T) {
 		t.Errorf("Rspec.Run(%q) error = %v", files, err)
 	}
 
-	if diff := cmp.Diff(got, want); diff != "" {
+	if diff := cmp.Diff(got, want, cmpopts.IgnoreFields(RunResult{}, "Tests")); diff != "" {
 		t.Errorf("Rspec.Run(%q) diff (-got +want):\n%s", files, diff)
 	}
 }
@@ -144,7 +147,7 @@ func TestRspecRun_TestFailedWithoutResultFile(t *testing.T) {
 	})
 
 	files := []string{"./fixtures/rspec/spec/failure_spec.rb"}
-	got, err := rspec.Run(files, false)
+	got, err := rspec.Run(files, 0)
 
 	want := RunResult{
 		Status: RunStatusError,
@@ -167,7 +170,7 @@ func TestRspecRun_CommandFailed(t *testing.T) {
 		},
 	}
 	files := []string{}
-	got, err := rspec.Run(files, false)
+	got, err := rspec.Run(files, 0)
 
 	want := RunResult{
 		Status: RunStatusError,
@@ -189,7 +192,7 @@ func TestRspecRun_SignaledError(t *testing.T) {
 	})
 	files := []string{"./fixtures/rspec/spec/failure_spec.rb"}
 
-	got, err := rspec.Run(files, false)
+	got, err := rspec.Run(files, 0)
 
 	want := RunResult{
 		Status: RunStatusError,
@@ -390,3 +393,70 @@ func TestRspecGetExamples_WithSharedExamples(t *testing.T) {
 		t.Errorf("Rspec.GetExamples(%q) diff (-got +want):\n%s", files, diff)
 	}
 }
+
+func TestRspecReportTestResults(t *testing.T) {
+	report := RspecReport{
+		Examples: []RspecExample{
+			{Id: "./spec/fruits/apple_spec.rb[1:1]", Description: "is red", FullDescription: "Apple is red", Status: "passed", FilePath: "./spec/fruits/apple_spec.rb", LineNumber: 2, RunTime: 0.5},
+			{Id: "./spec/fruits/apple_spec.rb[1:2]", Description: "is sweet", FullDescription: "Apple is sweet", Status: "failed", FilePath: "./spec/fruits/apple_spec.rb", RunTime: 1.25},
+			{Id: "./spec/fruits/apple_spec.rb[1:3]", Description: "is crunchy", FullDescription: "Apple is crunchy", Status: "pending", FilePath: "./spec/fruits/apple_spec.rb"},
+		},
+	}
+
+	got := report.testResults()
+
+	want := []TestResult{
+		{Id: "./spec/fruits/apple_spec.rb[1:1]", Name: "is red", Scope: "Apple", Path: "./spec/fruits/apple_spec.rb", Line: 2, Status: TestStatusPassed, Duration: 500 * time.Millisecond},
+		{Id: "./spec/fruits/apple_spec.rb[1:2]", Name: "is sweet", Scope: "Apple", Path: "./spec/fruits/apple_spec.rb", Status: TestStatusFailed, Duration: 1250 * time.Millisecond},
+		{Id: "./spec/fruits/apple_spec.rb[1:3]", Name: "is crunchy", Scope: "Apple", Path: "./spec/fruits/apple_spec.rb", Status: TestStatusSkipped},
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("RspecReport.testResults() diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestRspecFileDurations(t *testing.T) {
+	dir := t.TempDir()
+	rspec := NewRspec(RunnerConfig{
+		ResultPath: filepath.Join(dir, "rspec-{{attempt}}.json"),
+	})
+
+	report := `{"examples": [
+		{"id": "./spec/apple_spec.rb[1:1]", "file_path": "./spec/apple_spec.rb", "run_time": 1.5},
+		{"id": "./spec/apple_spec.rb[1:2]", "file_path": "./spec/apple_spec.rb", "run_time": 0.5},
+		{"id": "./spec/banana_spec.rb[1:1]", "file_path": "./spec/banana_spec.rb", "run_time": 0.25}
+	]}`
+	if err := os.WriteFile(filepath.Join(dir, "rspec-0.json"), []byte(report), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The result of the second invocation of the first run.
+	report = `{"examples": [
+		{"id": "./spec/cherry_spec.rb[1:1]", "file_path": "./spec/cherry_spec.rb", "run_time": 3}
+	]}`
+	if err := os.WriteFile(filepath.Join(dir, "rspec-0-1.json"), []byte(report), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got := rspec.FileDurations()
+	want := map[string]time.Duration{
+		"./spec/apple_spec.rb":  2 * time.Second,
+		"./spec/banana_spec.rb": 250 * time.Millisecond,
+		"./spec/cherry_spec.rb": 3 * time.Second,
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("Rspec.FileDurations() diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestRspecFileDurations_NoResult(t *testing.T) {
+	rspec := NewRspec(RunnerConfig{
+		ResultPath: filepath.Join(t.TempDir(), "rspec.json"),
+	})
+
+	if got := rspec.FileDurations(); got != nil {
+		t.Errorf("Rspec.FileDurations() = %v, want nil", got)
+	}
+}
@@ -0,0 +1,3 @@
+// Package server provides a local implementation of the Test Plan API,
+// which computes test plans from timings stored in a local file.
+package server
@@ -0,0 +1,307 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/buildkite/test-engine-client/internal/api"
+	"github.com/buildkite/test-engine-client/internal/debug"
+	"github.com/buildkite/test-engine-client/internal/plan"
+)
+
+// Server implements the endpoints of the Test Plan API used by api.Client.
+// Test plans and queues are kept in memory, keyed by suite slug and identifier,
+// and the estimated durations are looked up from the timings in milliseconds keyed by test path.
+type Server struct {
+	timings map[string]int
+
+	mu       sync.Mutex
+	plans    map[string]plan.TestPlan
+	queues   map[string]*queue
+	metadata map[string]api.TestPlanMetadataParams
+}
+
+// queue is a queue of tests that nodes pull batches of tests from.
+type queue struct {
+	parallelism int
+	tests       []plan.TestCase
+}
+
+// New creates a new Server with the given timings in milliseconds keyed by test path.
+func New(timings map[string]int) *Server {
+	if timings == nil {
+		timings = map[string]int{}
+	}
+
+	return &Server{
+		timings:  timings,
+		plans:    map[string]plan.TestPlan{},
+		queues:   map[string]*queue{},
+		metadata: map[string]api.TestPlanMetadataParams{},
+	}
+}
+
+// ReadTimings reads the timings from the JSON file at the given path.
+// The file contains an object of durations in milliseconds keyed by test path, for example:
+//
+//	{"spec/models/user_spec.rb": 12000, "spec/models/user_spec.rb[1:2]": 3000}
+func ReadTimings(path string) (map[string]int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read timings: %w", err)
+	}
+
+	var timings map[string]int
+	if err := json.Unmarshal(data, &timings); err != nil {
+		return nil, fmt.Errorf("failed to parse timings %s: %w", path, err)
+	}
+
+	return timings, nil
+}
+
+// ServeHTTP routes the requests to the Test Plan API endpoints, i.e.
+// /v2/analytics/organizations/{org}/suites/{suite}/{endpoint}.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	debug.Printf("%s %s", r.Method, r.URL.Path)
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) < 7 || parts[0] != "v2" || parts[1] != "analytics" || parts[2] != "organizations" || parts[4] != "suites" {
+		writeError(w, http.StatusNotFound, "Not found")
+		return
+	}
+
+	suite := parts[5]
+	endpoint := strings.Join(parts[6:], "/")
+
+	switch {
+	case endpoint == "test_plan" && r.Method == http.MethodGet:
+		s.fetchTestPlan(w, r, suite)
+	case endpoint == "test_plan" && r.Method == http.MethodPost:
+		s.createTestPlan(w, r, suite)
+	case endpoint == "test_plan/filter_tests" && r.Method == http.MethodPost:
+		s.filterTests(w, r)
+	case endpoint == "test_plan/queue" && r.Method == http.MethodPost:
+		s.createTestQueue(w, r, suite)
+	case endpoint == "test_plan/queue/next" && r.Method == http.MethodPost:
+		s.fetchNextTestBatch(w, r, suite)
+	case endpoint == "test_files" && r.Method == http.MethodPost:
+		s.fetchFilesTiming(w, r)
+	case endpoint == "test_plan_metadata" && r.Method == http.MethodPost:
+		s.postTestPlanMetadata(w, r, suite)
+	default:
+		writeError(w, http.StatusNotFound, "Not found")
+	}
+}
+
+func (s *Server) fetchTestPlan(w http.ResponseWriter, r *http.Request, suite string) {
+	key := suite + "/" + r.URL.Query().Get("identifier")
+
+	s.mu.Lock()
+	testPlan, ok := s.plans[key]
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, "Test plan not found")
+		return
+	}
+
+	writeJSON(w, testPlan)
+}
+
+func (s *Server) createTestPlan(w http.ResponseWriter, r *http.Request, suite string) {
+	var params api.TestPlanParams
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+
+	if params.Parallelism < 1 {
+		writeError(w, http.StatusBadRequest, "Parallelism must be greater than 0")
+		return
+	}
+
+	testPlan := plan.CreatePlan(s.testCases(params.Tests), params.Parallelism)
+
+	s.mu.Lock()
+	s.plans[suite+"/"+params.Identifier] = testPlan
+	s.mu.Unlock()
+
+	writeJSON(w, testPlan)
+}
+
+// filterTests returns the files that are slower than the ideal duration of a node,
+// i.e. the total duration divided by the parallelism, when split by example is enabled.
+func (s *Server) filterTests(w http.ResponseWriter, r *http.Request) {
+	var params api.FilterTestsParams
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+
+	if params.Parallelism < 1 {
+		writeError(w, http.StatusBadRequest, "Parallelism must be greater than 0")
+		return
+	}
+
+	tests := []api.FilteredTest{}
+
+	if strings.ToLower(params.Env["BUILDKITE_TEST_ENGINE_SPLIT_BY_EXAMPLE"]) == "true" {
+		files := s.testCases(api.TestPlanParamsTest{Files: params.Files})
+
+		total := 0
+		for _, file := range files {
+			total += file.EstimatedDuration
+		}
+
+		for _, file := range files {
+			if file.EstimatedDuration > total/params.Parallelism {
+				tests = append(tests, api.FilteredTest{Path: file.Path})
+			}
+		}
+	}
+
+	writeJSON(w, map[string]any{"tests": tests})
+}
+
+func (s *Server) createTestQueue(w http.ResponseWriter, r *http.Request, suite string) {
+	var params api.TestPlanParams
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+
+	if params.Parallelism < 1 {
+		writeError(w, http.StatusBadRequest, "Parallelism must be greater than 0")
+		return
+	}
+
+	key := suite + "/" + params.Identifier
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Every node creates the queue, only the first one is used.
+	if _, ok := s.queues[key]; !ok {
+		// A plan with a single task sorts the tests longest first.
+		testPlan := plan.CreatePlan(s.testCases(params.Tests), 1)
+		s.queues[key] = &queue{
+			parallelism: params.Parallelism,
+			tests:       testPlan.Tasks["0"].Tests,
+		}
+	}
+
+	writeJSON(w, map[string]any{"identifier": params.Identifier})
+}
+
+// fetchNextTestBatch returns the next batch of tests from the queue.
+// The batch size is the remaining tests divided by twice the parallelism,
+// so the batches get smaller towards the end of the queue and the nodes finish at around the same time.
+func (s *Server) fetchNextTestBatch(w http.ResponseWriter, r *http.Request, suite string) {
+	var params api.NextTestBatchParams
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	q, ok := s.queues[suite+"/"+params.Identifier]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Test queue not found")
+		return
+	}
+
+	size := max(1, len(q.tests)/(2*q.parallelism))
+	size = min(size, len(q.tests))
+
+	batch := q.tests[:size]
+	q.tests = q.tests[size:]
+
+	writeJSON(w, map[string]any{"tests": batch})
+}
+
+func (s *Server) fetchFilesTiming(w http.ResponseWriter, r *http.Request) {
+	var params struct {
+		Paths []string `json:"paths"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+
+	timings := map[string]int{}
+	for _, path := range params.Paths {
+		if duration, ok := s.timings[path]; ok {
+			timings[path] = duration
+		}
+	}
+
+	writeJSON(w, timings)
+}
+
+func (s *Server) postTestPlanMetadata(w http.ResponseWriter, r *http.Request, suite string) {
+	var params api.TestPlanMetadataParams
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+
+	s.mu.Lock()
+	s.metadata[suite+"/"+params.Env["BUILDKITE_TEST_ENGINE_IDENTIFIER"]] = params
+	s.mu.Unlock()
+
+	writeJSON(w, map[string]any{})
+}
+
+// testCases returns the files and examples of the test plan params with their estimated duration.
+// Tests without timings are estimated with the average duration of the tests with timings.
+func (s *Server) testCases(tests api.TestPlanParamsTest) []plan.TestCase {
+	testCases := []plan.TestCase{}
+	for _, file := range tests.Files {
+		file.Format = plan.TestCaseFormatFile
+		testCases = append(testCases, file)
+	}
+	for _, example := range tests.Examples {
+		example.Format = plan.TestCaseFormatExample
+		testCases = append(testCases, example)
+	}
+
+	total, known := 0, 0
+	for _, testCase := range testCases {
+		if duration, ok := s.timings[testCase.Path]; ok {
+			total += duration
+			known++
+		}
+	}
+
+	average := 0
+	if known > 0 {
+		average = total / known
+	}
+
+	for i, testCase := range testCases {
+		if duration, ok := s.timings[testCase.Path]; ok {
+			testCases[i].EstimatedDuration = duration
+		} else {
+			testCases[i].EstimatedDuration = average
+		}
+	}
+
+	return testCases
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"message": message})
+}
@@ -0,0 +1,272 @@
+package server
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/buildkite/test-engine-client/internal/api"
+	"github.com/buildkite/test-engine-client/internal/plan"
+	"github.com/google/go-cmp/cmp"
+)
+
+func newTestClient(t *testing.T, timings map[string]int) *api.Client {
+	t.Helper()
+	svr := httptest.NewServer(New(timings))
+	t.Cleanup(svr.Close)
+
+	return api.NewClient(api.ClientConfig{
+		AccessToken:      "asdf1234",
+		OrganizationSlug: "buildkite",
+		ServerBaseUrl:    svr.URL,
+	})
+}
+
+func TestServer_TestPlan(t *testing.T) {
+	c := newTestClient(t, map[string]int{
+		"apple_spec.rb":  3000,
+		"banana_spec.rb": 2000,
+		"cherry_spec.rb": 1000,
+	})
+	ctx := context.Background()
+
+	got, err := c.FetchTestPlan(ctx, "rspec", "abc123")
+	if err != nil {
+		t.Errorf("FetchTestPlan() error = %v", err)
+	}
+	if got != nil {
+		t.Errorf("FetchTestPlan() = %v, want nil", got)
+	}
+
+	params := api.TestPlanParams{
+		Identifier:  "abc123",
+		Parallelism: 2,
+		Tests: api.TestPlanParamsTest{
+			Files: []plan.TestCase{
+				{Path: "apple_spec.rb"},
+				{Path: "banana_spec.rb"},
+				{Path: "cherry_spec.rb"},
+				{Path: "durian_spec.rb"},
+			},
+		},
+	}
+
+	created, err := c.CreateTestPlan(ctx, "rspec", params)
+	if err != nil {
+		t.Errorf("CreateTestPlan() error = %v", err)
+	}
+
+	// durian_spec.rb has no timing, so it's estimated with the average.
+	want := plan.TestPlan{
+		Tasks: map[string]*plan.Task{
+			"0": {
+				NodeNumber: 0,
+				Tests: []plan.TestCase{
+					{Path: "apple_spec.rb", Format: plan.TestCaseFormatFile, EstimatedDuration: 3000},
+					{Path: "cherry_spec.rb", Format: plan.TestCaseFormatFile, EstimatedDuration: 1000},
+				},
+			},
+			"1": {
+				NodeNumber: 1,
+				Tests: []plan.TestCase{
+					{Path: "banana_spec.rb", Format: plan.TestCaseFormatFile, EstimatedDuration: 2000},
+					{Path: "durian_spec.rb", Format: plan.TestCaseFormatFile, EstimatedDuration: 2000},
+				},
+			},
+		},
+	}
+
+	if diff := cmp.Diff(created, want); diff != "" {
+		t.Errorf("CreateTestPlan() diff (-got +want):\n%s", diff)
+	}
+
+	got, err = c.FetchTestPlan(ctx, "rspec", "abc123")
+	if err != nil {
+		t.Errorf("FetchTestPlan() error = %v", err)
+	}
+	if diff := cmp.Diff(got, &want); diff != "" {
+		t.Errorf("FetchTestPlan() diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestServer_FilterTests(t *testing.T) {
+	c := newTestClient(t, map[string]int{
+		"apple_spec.rb":  8000,
+		"banana_spec.rb": 1000,
+		"cherry_spec.rb": 1000,
+	})
+
+	params := api.FilterTestsParams{
+		Files: []plan.TestCase{
+			{Path: "apple_spec.rb"},
+			{Path: "banana_spec.rb"},
+			{Path: "cherry_spec.rb"},
+		},
+		Parallelism: 2,
+		Env: map[string]string{
+			"BUILDKITE_TEST_ENGINE_SPLIT_BY_EXAMPLE": "true",
+		},
+	}
+
+	got, err := c.FilterTests(context.Background(), "rspec", params)
+	if err != nil {
+		t.Errorf("FilterTests() error = %v", err)
+	}
+
+	want := []api.FilteredTest{{Path: "apple_spec.rb"}}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("FilterTests() diff (-got +want):\n%s", diff)
+	}
+
+	// nothing is filtered when split by example is disabled
+	params.Env["BUILDKITE_TEST_ENGINE_SPLIT_BY_EXAMPLE"] = "false"
+	got, err = c.FilterTests(context.Background(), "rspec", params)
+	if err != nil {
+		t.Errorf("FilterTests() error = %v", err)
+	}
+	if diff := cmp.Diff(got, []api.FilteredTest{}); diff != "" {
+		t.Errorf("FilterTests() diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestServer_FilterTests_MissingParallelism(t *testing.T) {
+	c := newTestClient(t, map[string]int{"apple_spec.rb": 8000})
+
+	params := api.FilterTestsParams{
+		Files: []plan.TestCase{{Path: "apple_spec.rb"}},
+		Env: map[string]string{
+			"BUILDKITE_TEST_ENGINE_SPLIT_BY_EXAMPLE": "true",
+		},
+	}
+
+	_, err := c.FilterTests(context.Background(), "rspec", params)
+	if err == nil {
+		t.Errorf("FilterTests() error = nil, want error")
+	}
+}
+
+func TestServer_FetchFilesTiming(t *testing.T) {
+	c := newTestClient(t, map[string]int{
+		"apple_spec.rb":  3000,
+		"banana_spec.rb": 2000,
+	})
+
+	files := []string{"apple_spec.rb", "cherry_spec.rb"}
+	got, err := c.FetchFilesTiming(context.Background(), "rspec", files)
+	if err != nil {
+		t.Errorf("FetchFilesTiming(%v) error = %v", files, err)
+	}
+
+	want := map[string]time.Duration{
+		"apple_spec.rb": 3 * time.Second,
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("FetchFilesTiming(%v) diff (-got +want):\n%s", files, diff)
+	}
+}
+
+func TestServer_TestQueue(t *testing.T) {
+	c := newTestClient(t, map[string]int{
+		"apple_spec.rb":  5000,
+		"banana_spec.rb": 4000,
+		"cherry_spec.rb": 3000,
+		"durian_spec.rb": 2000,
+		"grape_spec.rb":  1000,
+	})
+	ctx := context.Background()
+
+	params := api.TestPlanParams{
+		Identifier:  "abc123",
+		Parallelism: 1,
+		Tests: api.TestPlanParamsTest{
+			Files: []plan.TestCase{
+				{Path: "grape_spec.rb"},
+				{Path: "durian_spec.rb"},
+				{Path: "cherry_spec.rb"},
+				{Path: "banana_spec.rb"},
+				{Path: "apple_spec.rb"},
+			},
+		},
+	}
+
+	// creating the queue twice is a no-op
+	for i := 0; i < 2; i++ {
+		if err := c.CreateTestQueue(ctx, "rspec", params); err != nil {
+			t.Errorf("CreateTestQueue() error = %v", err)
+		}
+	}
+
+	var got [][]string
+	for {
+		batch, err := c.FetchNextTestBatch(ctx, "rspec", api.NextTestBatchParams{Identifier: "abc123"})
+		if err != nil {
+			t.Fatalf("FetchNextTestBatch() error = %v", err)
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		paths := []string{}
+		for _, testCase := range batch {
+			paths = append(paths, testCase.Path)
+		}
+		got = append(got, paths)
+	}
+
+	// the batches get smaller towards the end of the queue, longest tests first
+	want := [][]string{
+		{"apple_spec.rb", "banana_spec.rb"},
+		{"cherry_spec.rb"},
+		{"durian_spec.rb"},
+		{"grape_spec.rb"},
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("FetchNextTestBatch() diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestServer_FetchNextTestBatch_NotFound(t *testing.T) {
+	c := newTestClient(t, nil)
+
+	_, err := c.FetchNextTestBatch(context.Background(), "rspec", api.NextTestBatchParams{Identifier: "abc123"})
+	if err == nil {
+		t.Errorf("FetchNextTestBatch() error = nil, want error")
+	}
+}
+
+func TestServer_PostTestPlanMetadata(t *testing.T) {
+	c := newTestClient(t, nil)
+
+	err := c.PostTestPlanMetadata(context.Background(), "rspec", "abc123", api.TestPlanMetadataParams{
+		Version: "1.0.0",
+		Env: map[string]string{
+			"BUILDKITE_TEST_ENGINE_IDENTIFIER": "abc123",
+		},
+		Timeline: []api.Timeline{{Event: "test_start", Timestamp: "2024-01-01T00:00:00Z"}},
+	})
+
+	if err != nil {
+		t.Errorf("PostTestPlanMetadata() error = %v", err)
+	}
+}
+
+func TestReadTimings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "timings.json")
+	os.WriteFile(path, []byte(`{"apple_spec.rb": 3000, "apple_spec.rb[1:2]": 1000}`), 0644)
+
+	got, err := ReadTimings(path)
+	if err != nil {
+		t.Errorf("ReadTimings(%q) error = %v", path, err)
+	}
+
+	want := map[string]int{
+		"apple_spec.rb":      3000,
+		"apple_spec.rb[1:2]": 1000,
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("ReadTimings(%q) diff (-got +want):\n%s", path, diff)
+	}
+}
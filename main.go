@@ -17,6 +17,7 @@ import (
 	"github.com/buildkite/test-engine-client/internal/config"
 	"github.com/buildkite/test-engine-client/internal/debug"
 	"github.com/buildkite/test-engine-client/internal/plan"
+	"github.com/buildkite/test-engine-client/internal/report"
 	"github.com/buildkite/test-engine-client/internal/runner"
 	"golang.org/x/sys/unix"
 )
@@ -24,7 +25,7 @@ import (
 var Version = ""
 
 type TestRunner interface {
-	Run(testCases []string, retry bool) (runner.RunResult, error)
+	Run(testCases []string, attempt int) (runner.RunResult, error)
 	GetExamples(files []string) ([]plan.TestCase, error)
 	GetFiles() ([]string, error)
 	Name() string
@@ -33,17 +34,51 @@ type TestRunner interface {
 func main() {
 	debug.SetDebug(os.Getenv("BUILDKITE_TEST_ENGINE_DEBUG_ENABLED") == "true")
 
-	versionFlag := flag.Bool("version", false, "print version information")
+	args := os.Args[1:]
+	command := "run"
+	if len(args) > 0 {
+		switch args[0] {
+		case "plan", "run", "serve":
+			command, args = args[0], args[1:]
+		}
+	}
+
+	switch command {
+	case "plan":
+		planCommand(args)
+	case "serve":
+		serveCommand(args)
+	default:
+		runCommand(args)
+	}
+}
 
-	flag.Parse()
+// runCommand implements the "bktec run" subcommand, which is also the default command.
+// It fetches the test plan, or reads it from the plan file, and runs the tests for this node.
+func runCommand(args []string) {
+	flags := flag.NewFlagSet("run", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: bktec [run] [flags]\n       bktec plan [flags]\n       bktec serve [flags]\n\nFlags of run:\n")
+		flags.PrintDefaults()
+	}
+	versionFlag := flags.Bool("version", false, "print version information")
+	planFile := flags.String("plan-file", "", "path of a test plan file to run instead of fetching the plan from Test Engine, e.g. written by \"bktec plan\" (BUILDKITE_TEST_ENGINE_PLAN_FILE)")
+	configOverrides := addConfigFlags(flags)
+	flags.Parse(args)
 
 	if *versionFlag {
 		fmt.Println(Version)
 		os.Exit(0)
 	}
 
+	overrides := configOverrides()
+	if *planFile != "" {
+		overrides["BUILDKITE_TEST_ENGINE_PLAN_FILE"] = *planFile
+	}
+	setDebugFromOverrides(overrides)
+
 	// get config
-	cfg, err := config.New()
+	cfg, err := config.New(overrides)
 	if err != nil {
 		logErrorAndExit(16, "Invalid configuration...\n%v", err)
 	}
@@ -53,47 +88,99 @@ func main() {
 		logErrorAndExit(16, "Unsupported value for BUILDKITE_TEST_ENGINE_TEST_RUNNER %q: %v", cfg.TestRunner, err)
 	}
 
-	files, err := testRunner.GetFiles()
-	if err != nil {
-		logErrorAndExit(16, "Couldn't get files: %v", err)
-	}
-
 	// get plan
 	ctx := context.Background()
-	apiClient := api.NewClient(api.ClientConfig{
-		ServerBaseUrl:    cfg.ServerBaseUrl,
-		AccessToken:      cfg.AccessToken,
-		OrganizationSlug: cfg.OrganizationSlug,
-		Version:          Version,
-	})
+	var apiClient *api.Client
+	var testPlan plan.TestPlan
+	useQueue := false
+
+	if cfg.PlanFile != "" {
+		// The API is not used at all when running a plan from a file.
+		debug.Printf("Reading test plan from file: %s", cfg.PlanFile)
+		testPlan, err = plan.ReadPlanFile(cfg.PlanFile)
+		if err != nil {
+			logErrorAndExit(16, "Couldn't read test plan: %v", err)
+		}
+	} else {
+		files, err := testRunner.GetFiles()
+		if err != nil {
+			logErrorAndExit(16, "Couldn't get files: %v", err)
+		}
 
-	testPlan, err := fetchOrCreateTestPlan(ctx, apiClient, cfg, files, testRunner)
-	if err != nil {
-		logErrorAndExit(16, "Couldn't fetch or create test plan: %v", err)
+		apiClient = api.NewClient(api.ClientConfig{
+			ServerBaseUrl:    cfg.ServerBaseUrl,
+			AccessToken:      cfg.AccessToken,
+			OrganizationSlug: cfg.OrganizationSlug,
+			Version:          Version,
+		})
+
+		if cfg.Mode == "dynamic" {
+			testPlan, useQueue, err = createTestQueue(ctx, apiClient, cfg, files, testRunner)
+			if err != nil {
+				logErrorAndExit(16, "Couldn't create test queue: %v", err)
+			}
+		} else {
+			testPlan, err = fetchOrCreateTestPlan(ctx, apiClient, cfg, files, testRunner)
+			if err != nil {
+				logErrorAndExit(16, "Couldn't fetch or create test plan: %v", err)
+			}
+		}
 	}
 
 	debug.Printf("My favourite ice cream is %s", testPlan.Experiment)
 
-	// get plan for this node
-	thisNodeTask := testPlan.Tasks[strconv.Itoa(cfg.NodeIndex)]
+	// Metadata is only sent for plans created by Test Engine.
+	shouldSendMetadata := !testPlan.Fallback && apiClient != nil
 
-	// execute tests
-	runnableTests := []string{}
-	for _, testCase := range thisNodeTask.Tests {
-		runnableTests = append(runnableTests, testCase.Path)
+	quarantinedTests, err := loadQuarantinedTests(ctx, apiClient, cfg)
+	if err != nil {
+		logErrorAndExit(16, "Couldn't read quarantined tests: %v", err)
 	}
 
 	var timeline []api.Timeline
-	testResult, err := runTestsWithRetry(testRunner, &runnableTests, cfg.MaxRetries, &timeline)
+	var testResult runner.RunResult
+	testStart := time.Now()
+
+	if useQueue {
+		testResult, err = runTestsFromQueue(ctx, apiClient, cfg, testRunner, quarantinedTests, &timeline)
+	} else {
+		// get plan for this node
+		thisNodeTask, ok := testPlan.Tasks[strconv.Itoa(cfg.NodeIndex)]
+		if !ok {
+			logErrorAndExit(16, "Couldn't find the tasks for node %d in the test plan", cfg.NodeIndex)
+		}
+
+		// execute tests
+		setEstimatedDurations(testRunner, thisNodeTask.Tests)
+		runnableTests := []string{}
+		for _, testCase := range thisNodeTask.Tests {
+			runnableTests = append(runnableTests, testCase.Path)
+		}
+
+		testResult, err = runTestsWithRetry(testRunner, &runnableTests, cfg.MaxRetries, quarantinedTests, &timeline)
+	}
+
+	printFlakyTests(testResult.FlakyTests)
+	printQuarantinedTests(testResult.QuarantinedTests)
+	writeReports(cfg, testResult.Tests)
+	writeSummary(cfg, newSummary(cfg, testRunner.Name(), testPlan, useQueue, testResult, err, time.Since(testStart)))
+	uploadTestResults(ctx, cfg, testResult.Attempts, err)
 
 	if err != nil {
+		if timeoutError := new(runner.TimeoutError); errors.As(err, &timeoutError) {
+			if shouldSendMetadata {
+				sendMetadata(ctx, apiClient, cfg, timeline, testResult.FlakyTests)
+			}
+			logErrorAndExit(124, "%s timed out: %v", testRunner.Name(), err)
+		}
+
 		if ProcessSignaledError := new(runner.ProcessSignaledError); errors.As(err, &ProcessSignaledError) {
 			logSignalAndExit(testRunner.Name(), ProcessSignaledError.Signal)
 		}
 
 		if exitError := new(exec.ExitError); errors.As(err, &exitError) {
-			if !testPlan.Fallback {
-				sendMetadata(ctx, apiClient, cfg, timeline)
+			if shouldSendMetadata {
+				sendMetadata(ctx, apiClient, cfg, timeline, testResult.FlakyTests)
 			}
 			logErrorAndExit(exitError.ExitCode(), "%s exited with error: %v", testRunner.Name(), err)
 		}
@@ -102,8 +189,8 @@ func main() {
 	}
 
 	if testResult.Status == runner.RunStatusFailed {
-		if !testPlan.Fallback {
-			sendMetadata(ctx, apiClient, cfg, timeline)
+		if shouldSendMetadata {
+			sendMetadata(ctx, apiClient, cfg, timeline, testResult.FlakyTests)
 		}
 
 		if failedCount := len(testResult.FailedTests); failedCount > 1 {
@@ -112,8 +199,34 @@ func main() {
 		logErrorAndExit(1, "%s exited with 1 failure", testRunner.Name())
 	}
 
-	if !testPlan.Fallback {
-		sendMetadata(ctx, apiClient, cfg, timeline)
+	if shouldSendMetadata {
+		sendMetadata(ctx, apiClient, cfg, timeline, testResult.FlakyTests)
+	}
+
+	if exitCode := flakyPolicyExitCode(cfg.FlakyPolicy, testResult.FlakyTests); exitCode != 0 {
+		logErrorAndExit(exitCode, "%s passed after retrying %d flaky tests, exiting with status %d because the flaky policy is %q", testRunner.Name(), len(testResult.FlakyTests), exitCode, cfg.FlakyPolicy)
+	}
+}
+
+// softFailExitCode is the exit status when the tests pass only after retrying flaky tests
+// and the flaky policy is "soft-fail". It's distinct from the exit statuses of the test runners,
+// so that it can be allowed with the soft_fail attribute of a Buildkite step.
+const softFailExitCode = 17
+
+// flakyPolicyExitCode returns the exit status of a run whose tests passed, which is non-zero
+// when some tests only passed on retry and the flaky policy doesn't allow it.
+func flakyPolicyExitCode(policy string, flakyTests []string) int {
+	if len(flakyTests) == 0 {
+		return 0
+	}
+
+	switch policy {
+	case "fail":
+		return 1
+	case "soft-fail":
+		return softFailExitCode
+	default:
+		return 0
 	}
 }
 
@@ -121,11 +234,12 @@ func createTimestamp() string {
 	return time.Now().Format(time.RFC3339Nano)
 }
 
-func sendMetadata(ctx context.Context, apiClient *api.Client, cfg config.Config, timeline []api.Timeline) {
+func sendMetadata(ctx context.Context, apiClient *api.Client, cfg config.Config, timeline []api.Timeline, flakyTests []string) {
 	err := apiClient.PostTestPlanMetadata(ctx, cfg.SuiteSlug, cfg.Identifier, api.TestPlanMetadataParams{
-		Timeline: timeline,
-		Env:      cfg.DumpEnv(),
-		Version:  Version,
+		Timeline:   timeline,
+		Env:        cfg.DumpEnv(),
+		Version:    Version,
+		FlakyTests: flakyTests,
 	})
 
 	// Error is suppressed because we don't want to fail the build if we can't send metadata.
@@ -134,11 +248,50 @@ func sendMetadata(ctx context.Context, apiClient *api.Client, cfg config.Config,
 	}
 }
 
-func runTestsWithRetry(testRunner TestRunner, testsCases *[]string, maxRetries int, timeline *[]api.Timeline) (runner.RunResult, error) {
+// printFlakyTests prints the tests that failed and then passed when they were retried.
+func printFlakyTests(flakyTests []string) {
+	if len(flakyTests) == 0 {
+		return
+	}
+
+	fmt.Printf("+++ Buildkite Test Engine Client: ⚠️ %d flaky tests passed on retry\n", len(flakyTests))
+	for _, test := range flakyTests {
+		fmt.Printf("  %s\n", test)
+	}
+}
+
+// writeReports writes the reports of the tests combining all attempts, when they are configured.
+func writeReports(cfg config.Config, tests []runner.TestResult) {
+	if cfg.JUnitReportPath != "" {
+		if err := report.WriteJUnit(cfg.JUnitReportPath, tests); err != nil {
+			logReportError("Failed to write JUnit XML report: %v", err)
+		}
+	}
+
+	if cfg.JSONReportPath != "" {
+		if err := report.WriteJSON(cfg.JSONReportPath, tests); err != nil {
+			logReportError("Failed to write JSON report: %v", err)
+		}
+	}
+}
+
+// runTestsWithRetry runs the tests and retries the failed tests up to maxRetries times.
+// The result is the result of the last attempt, with the tests that failed in an attempt
+// and passed in a later one reported as flaky tests, and the results of the individual tests
+// combined across all attempts, see runner.MergeRetriedTests. The outcome of each attempt
+// is counted in the attempts of the result.
+//
+// The failures of the quarantined tests are reported as quarantined tests instead of failed tests,
+// so they don't fail the run, and they are never retried.
+func runTestsWithRetry(testRunner TestRunner, testsCases *[]string, maxRetries int, quarantinedTests map[string]bool, timeline *[]api.Timeline) (runner.RunResult, error) {
 	attemptCount := 0
 
 	var testResult runner.RunResult
 	var err error
+	var flakyTests []string
+	var failedQuarantinedTests []string
+	var tests []runner.TestResult
+	var attempts []runner.AttemptResult
 
 	for attemptCount <= maxRetries {
 		if attemptCount == 0 {
@@ -155,7 +308,46 @@ func runTestsWithRetry(testRunner TestRunner, testsCases *[]string, maxRetries i
 			})
 		}
 
-		testResult, err = testRunner.Run(*testsCases, attemptCount > 0)
+		start := time.Now()
+		testResult, err = testRunner.Run(*testsCases, attemptCount)
+		// Only the retried tests are counted and merged, the test runner may report other tests as skipped.
+		if attemptCount > 0 {
+			testResult.Tests = runner.RequestedTests(testResult.Tests, *testsCases)
+		}
+		attempts = append(attempts, runner.NewAttemptResult(testResult.Tests, time.Since(start)))
+		testResult = testResult.Quarantine(quarantinedTests)
+		failedQuarantinedTests = append(failedQuarantinedTests, testResult.QuarantinedTests...)
+
+		if attemptCount == 0 {
+			tests = testResult.Tests
+		} else {
+			tests = runner.MergeRetriedTests(tests, testResult.Tests)
+		}
+
+		// The retried tests that passed on retry are flaky. The retried tests that didn't run,
+		// e.g. because the test runner didn't find them, are still failed.
+		// The outcome of the tests is unknown when the attempt returns an error.
+		if attemptCount > 0 && err == nil {
+			passed, notRun := retriedTestOutcomes(*testsCases, testResult.Tests, testResult.FailedTests)
+			flakyTests = append(flakyTests, passed...)
+			if len(notRun) > 0 {
+				fmt.Printf("Buildkite Test Engine Client: %d retried tests didn't run, they are still considered failed\n", len(notRun))
+				testResult.FailedTests = append(testResult.FailedTests, notRun...)
+				testResult.Status = runner.RunStatusFailed
+			}
+		}
+
+		// Record the timeout so the hang shows up in the timeline of the test plan.
+		if timeoutError := new(runner.TimeoutError); errors.As(err, &timeoutError) {
+			event := "test_timeout"
+			if attemptCount > 0 {
+				event = fmt.Sprintf("retry_%d_timeout", attemptCount)
+			}
+			*timeline = append(*timeline, api.Timeline{
+				Event:     event,
+				Timestamp: createTimestamp(),
+			})
+		}
 
 		if attemptCount == 0 {
 			*timeline = append(*timeline, api.Timeline{
@@ -189,9 +381,113 @@ func runTestsWithRetry(testRunner TestRunner, testsCases *[]string, maxRetries i
 		attemptCount++
 	}
 
+	testResult.FlakyTests = flakyTests
+	testResult.QuarantinedTests = failedQuarantinedTests
+	testResult.Tests = tests
+	testResult.Attempts = attempts
 	return testResult, err
 }
 
+// retriedTestOutcomes returns the retried test cases that passed, and the ones that didn't run,
+// i.e. that have no result or were skipped, in order. The other test cases failed again,
+// so they are in failedTests. A test with several results passed only if all of them passed.
+func retriedTestOutcomes(testCases []string, tests []runner.TestResult, failedTests []string) (passed []string, notRun []string) {
+	failed := make(map[string]bool, len(failedTests))
+	for _, test := range failedTests {
+		failed[test] = true
+	}
+
+	statuses := make(map[string]runner.TestStatus, len(tests))
+	for _, test := range tests {
+		if status, ok := statuses[test.Id]; ok && status != runner.TestStatusPassed {
+			continue
+		}
+		statuses[test.Id] = test.Status
+	}
+
+	for _, test := range testCases {
+		switch {
+		case failed[test]:
+			continue
+		case statuses[test] == runner.TestStatusPassed:
+			passed = append(passed, test)
+		default:
+			notRun = append(notRun, test)
+		}
+	}
+	return passed, notRun
+}
+
+// setEstimatedDurations passes the estimated durations of the test cases to the test runner
+// when it splits the tests between several workers, see runner.Parallel.
+func setEstimatedDurations(testRunner TestRunner, testCases []plan.TestCase) {
+	if parallel, ok := testRunner.(runner.Parallel); ok {
+		parallel.SetEstimatedDurations(testCases)
+	}
+}
+
+// runTestsFromQueue pulls batches of tests from the queue and runs them until the queue is drained.
+// The failed tests of each batch are retried up to maxRetries times before pulling the next batch.
+// The results of all batches are merged into a single result.
+//
+// The timeline has a single "test_start" and "test_end" event around the whole queue, and the events
+// of each batch are prefixed by the number of the batch, e.g. "batch_1_test_start" and "batch_1_retry_1_start".
+//
+// Error is returned if the next batch cannot be fetched, or if running a batch returns an error,
+// in which case the remaining tests in the queue are not run by this node.
+func runTestsFromQueue(ctx context.Context, apiClient *api.Client, cfg config.Config, testRunner TestRunner, quarantinedTests map[string]bool, timeline *[]api.Timeline) (runner.RunResult, error) {
+	var results []runner.RunResult
+
+	*timeline = append(*timeline, api.Timeline{
+		Event:     "test_start",
+		Timestamp: createTimestamp(),
+	})
+	defer func() {
+		*timeline = append(*timeline, api.Timeline{
+			Event:     "test_end",
+			Timestamp: createTimestamp(),
+		})
+	}()
+
+	for batchNumber := 1; ; batchNumber++ {
+		batch, err := apiClient.FetchNextTestBatch(ctx, cfg.SuiteSlug, api.NextTestBatchParams{
+			Identifier: cfg.Identifier,
+			NodeNumber: cfg.NodeIndex,
+		})
+		if err != nil {
+			return runner.MergeRunResults(results...), fmt.Errorf("failed to fetch next batch of tests: %w", err)
+		}
+
+		if len(batch) == 0 {
+			debug.Println("Test queue is drained")
+			break
+		}
+
+		debug.Printf("Fetched a batch of %d tests from the queue", len(batch))
+
+		setEstimatedDurations(testRunner, batch)
+		testCases := []string{}
+		for _, testCase := range batch {
+			testCases = append(testCases, testCase.Path)
+		}
+
+		var batchTimeline []api.Timeline
+		result, err := runTestsWithRetry(testRunner, &testCases, cfg.MaxRetries, quarantinedTests, &batchTimeline)
+		results = append(results, result)
+
+		for _, event := range batchTimeline {
+			event.Event = fmt.Sprintf("batch_%d_%s", batchNumber, event.Event)
+			*timeline = append(*timeline, event)
+		}
+
+		if err != nil {
+			return runner.MergeRunResults(results...), err
+		}
+	}
+
+	return runner.MergeRunResults(results...), nil
+}
+
 func logSignalAndExit(name string, signal syscall.Signal) {
 	fmt.Printf("Buildkite Test Engine: %s was terminated with signal: %v (%v)\n", name, unix.SignalName(signal), signal)
 
@@ -199,6 +495,12 @@ func logSignalAndExit(name string, signal syscall.Signal) {
 	os.Exit(exitCode)
 }
 
+// logReportError logs an error of reporting the results of the tests, e.g. writing a report,
+// the summary or uploading the results. It doesn't fail the run, because the tests have already been run.
+func logReportError(format string, v ...any) {
+	fmt.Printf(format+"\n", v...)
+}
+
 // logErrorAndExit logs an error message and exits with the given exit code.
 func logErrorAndExit(exitCode int, format string, v ...any) {
 	fmt.Printf("Buildkite Test Engine: "+format+"\n", v...)
@@ -207,7 +509,44 @@ func logErrorAndExit(exitCode int, format string, v ...any) {
 
 // fetchOrCreateTestPlan fetches a test plan from the server, or creates a
 // fallback plan if the server is unavailable or returns an error plan.
+//
+// If the plan cache directory is configured, the plan is read from the cache before
+// reaching out to the server, and the plan is written to the cache afterwards.
+// When another node has cached a plan in the meantime, the cached plan is used instead
+// so that all nodes sharing the cache run the same plan.
 func fetchOrCreateTestPlan(ctx context.Context, apiClient *api.Client, cfg config.Config, files []string, testRunner TestRunner) (plan.TestPlan, error) {
+	if cfg.PlanCacheDir == "" {
+		return fetchOrCreateServerTestPlan(ctx, apiClient, cfg, files, testRunner)
+	}
+
+	debug.Printf("Reading test plan from cache directory: %s", cfg.PlanCacheDir)
+	cachedPlan, err := plan.ReadCache(cfg.PlanCacheDir, cfg.Identifier)
+	if err != nil {
+		// Error is suppressed because the plan can still be fetched from the server.
+		fmt.Printf("⚠️ Failed to read test plan from cache: %v\n", err)
+	}
+
+	if cachedPlan != nil {
+		debug.Printf("Test plan found in cache. Identifier: %q", cfg.Identifier)
+		return *cachedPlan, nil
+	}
+
+	testPlan, err := fetchOrCreateServerTestPlan(ctx, apiClient, cfg, files, testRunner)
+	if err != nil {
+		return testPlan, err
+	}
+
+	testPlan, err = plan.WriteCache(cfg.PlanCacheDir, cfg.Identifier, testPlan)
+	if err != nil {
+		fmt.Printf("⚠️ Failed to write test plan to cache: %v\n", err)
+	}
+
+	return testPlan, nil
+}
+
+// fetchOrCreateServerTestPlan fetches a test plan from the server, or creates a
+// fallback plan if the server is unavailable or returns an error plan.
+func fetchOrCreateServerTestPlan(ctx context.Context, apiClient *api.Client, cfg config.Config, files []string, testRunner TestRunner) (plan.TestPlan, error) {
 	debug.Println("Fetching test plan")
 
 	// Fetch the plan from the server's cache.
@@ -216,15 +555,13 @@ func fetchOrCreateTestPlan(ctx context.Context, apiClient *api.Client, cfg confi
 	handleError := func(err error) (plan.TestPlan, error) {
 		if errors.Is(err, api.ErrRetryTimeout) {
 			fmt.Println("⚠️ Could not fetch or create plan from server, falling back to non-intelligent splitting. Your build may take longer than usual.")
-			p := plan.CreateFallbackPlan(files, cfg.Parallelism)
-			return p, nil
+			return createFallbackPlan(files, cfg.Parallelism, fallbackDurations(cfg, files, testRunner), fallbackReasonUnavailable), nil
 		}
 
 		if billingError := new(api.BillingError); errors.As(err, &billingError) {
 			fmt.Println(billingError.Message)
 			fmt.Println("⚠️ Falling back to non-intelligent splitting. Your build may take longer than usual.")
-			p := plan.CreateFallbackPlan(files, cfg.Parallelism)
-			return p, nil
+			return createFallbackPlan(files, cfg.Parallelism, fallbackDurations(cfg, files, testRunner), billingError.Message), nil
 		}
 
 		return plan.TestPlan{}, err
@@ -239,8 +576,7 @@ func fetchOrCreateTestPlan(ctx context.Context, apiClient *api.Client, cfg confi
 		// In this case, we should create a fallback plan.
 		if len(cachedPlan.Tasks) == 0 {
 			fmt.Println("⚠️ Error plan received, falling back to non-intelligent splitting. Your build may take longer than usual.")
-			testPlan := plan.CreateFallbackPlan(files, cfg.Parallelism)
-			return testPlan, nil
+			return createFallbackPlan(files, cfg.Parallelism, fallbackDurations(cfg, files, testRunner), fallbackReasonErrorPlan), nil
 		}
 
 		debug.Printf("Test plan found. Identifier: %q", cfg.Identifier)
@@ -265,14 +601,94 @@ func fetchOrCreateTestPlan(ctx context.Context, apiClient *api.Client, cfg confi
 	// In this case, we should create a fallback plan.
 	if len(testPlan.Tasks) == 0 {
 		fmt.Println("⚠️ Error plan received, falling back to non-intelligent splitting. Your build may take longer than usual.")
-		testPlan = plan.CreateFallbackPlan(files, cfg.Parallelism)
-		return testPlan, nil
+		return createFallbackPlan(files, cfg.Parallelism, fallbackDurations(cfg, files, testRunner), fallbackReasonErrorPlan), nil
 	}
 
 	debug.Printf("Test plan created. Identifier: %q", cfg.Identifier)
 	return testPlan, nil
 }
 
+const (
+	fallbackReasonUnavailable = "Test Engine is unavailable"
+	fallbackReasonErrorPlan   = "Test Engine returned an error plan"
+)
+
+// createFallbackPlan creates a fallback plan that splits the files by their known durations,
+// or by their size when the durations are unknown, recording why it's used instead of a plan from Test Engine.
+func createFallbackPlan(files []string, parallelism int, durations map[string]time.Duration, reason string) plan.TestPlan {
+	testPlan := plan.CreateFallbackPlan(plan.EstimateDurations(files, durations), parallelism)
+	testPlan.FallbackReason = reason
+	return testPlan
+}
+
+// fileDurationsReader is implemented by the test runners that can read the durations of the test files
+// from the result of a previous run, see runner.Rspec.FileDurations.
+type fileDurationsReader interface {
+	FileDurations() map[string]time.Duration
+}
+
+// fallbackDurations returns the known durations of the files for the fallback plan: the estimates of
+// the latest plan created by Test Engine in the plan cache directory, then the durations read by the
+// test runner for the other files.
+//
+// The known durations can differ between nodes, which would make them create different fallback plans.
+// So they are only used with the plan cache directory, where the fallback plan of the first node
+// is cached and run by all the nodes sharing the directory.
+func fallbackDurations(cfg config.Config, files []string, testRunner TestRunner) map[string]time.Duration {
+	if cfg.PlanCacheDir == "" {
+		return nil
+	}
+
+	durations := make(map[string]time.Duration)
+	if reader, ok := testRunner.(fileDurationsReader); ok {
+		for file, duration := range reader.FileDurations() {
+			durations[file] = duration
+		}
+	}
+	for file, duration := range plan.CachedDurations(cfg.PlanCacheDir, files) {
+		durations[file] = duration
+	}
+	return durations
+}
+
+// createTestQueue creates the queue of tests on the server for the nodes to pull from,
+// with the same request parameters as creating a test plan.
+// It returns true if the queue is created.
+//
+// If the server is unavailable, it returns false and a fallback plan instead,
+// so the tests can still be run as a static split.
+func createTestQueue(ctx context.Context, apiClient *api.Client, cfg config.Config, files []string, testRunner TestRunner) (plan.TestPlan, bool, error) {
+	// The fallback plan of dynamic mode is not cached, so the known durations are not used
+	// to make sure every node creates the same plan.
+	handleError := func(err error) (plan.TestPlan, bool, error) {
+		if errors.Is(err, api.ErrRetryTimeout) {
+			fmt.Println("⚠️ Could not create test queue on server, falling back to non-intelligent splitting. Your build may take longer than usual.")
+			return createFallbackPlan(files, cfg.Parallelism, nil, fallbackReasonUnavailable), false, nil
+		}
+
+		if billingError := new(api.BillingError); errors.As(err, &billingError) {
+			fmt.Println(billingError.Message)
+			fmt.Println("⚠️ Falling back to non-intelligent splitting. Your build may take longer than usual.")
+			return createFallbackPlan(files, cfg.Parallelism, nil, billingError.Message), false, nil
+		}
+
+		return plan.TestPlan{}, false, err
+	}
+
+	params, err := createRequestParam(ctx, cfg, files, *apiClient, testRunner)
+	if err != nil {
+		return handleError(err)
+	}
+
+	debug.Println("Creating test queue")
+	if err := apiClient.CreateTestQueue(ctx, cfg.SuiteSlug, params); err != nil {
+		return handleError(err)
+	}
+
+	debug.Printf("Test queue created. Identifier: %q", cfg.Identifier)
+	return plan.TestPlan{}, true, nil
+}
+
 // createRequestParam creates the request parameters for the test plan with the given configuration and files.
 // The files should have been filtered by include/exclude patterns before passing to this function.
 // If SplitByExample is disabled (default), it will return the default params that contain all the files.
@@ -293,8 +709,9 @@ func createRequestParam(ctx context.Context, cfg config.Config, files []string,
 
 	debug.Printf("Filtering %d files", len(files))
 	filteredFiles, err := client.FilterTests(ctx, cfg.SuiteSlug, api.FilterTestsParams{
-		Files: testFiles,
-		Env:   cfg.DumpEnv(),
+		Files:       testFiles,
+		Parallelism: cfg.Parallelism,
+		Env:         cfg.DumpEnv(),
 	})
 
 	if err != nil {
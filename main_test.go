@@ -10,6 +10,7 @@ import (
 	"net/http/httptest"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -18,6 +19,7 @@ import (
 	"github.com/buildkite/test-engine-client/internal/plan"
 	"github.com/buildkite/test-engine-client/internal/runner"
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 )
 
 func TestRunTestsWithRetry(t *testing.T) {
@@ -30,7 +32,7 @@ func TestRunTestsWithRetry(t *testing.T) {
 	maxRetries := 3
 	testCases := []string{"test/spec/fruits/apple_spec.rb"}
 	timeline := []api.Timeline{}
-	testResult, err := runTestsWithRetry(testRunner, &testCases, maxRetries, &timeline)
+	testResult, err := runTestsWithRetry(testRunner, &testCases, maxRetries, nil, &timeline)
 
 	t.Cleanup(func() {
 		os.Remove(testRunner.ResultPath)
@@ -69,7 +71,7 @@ func TestRunTestsWithRetry_TestPassedAfterRetry(t *testing.T) {
 	maxRetries := 2
 	testCases := []string{"test/spec/fruits/apple_spec.rb", "test/spec/fruits/tomato_spec.rb"}
 	timeline := []api.Timeline{}
-	testResult, err := runTestsWithRetry(testRunner, &testCases, maxRetries, &timeline)
+	testResult, err := runTestsWithRetry(testRunner, &testCases, maxRetries, nil, &timeline)
 
 	t.Cleanup(func() {
 		os.Remove(testRunner.ResultPath)
@@ -111,7 +113,7 @@ func TestRunTestsWithRetry_TestFailedAfterRetry(t *testing.T) {
 	maxRetries := 2
 	testCases := []string{"test/spec/fruits/apple_spec.rb", "test/spec/fruits/tomato_spec.rb"}
 	timeline := []api.Timeline{}
-	testResult, err := runTestsWithRetry(testRunner, &testCases, maxRetries, &timeline)
+	testResult, err := runTestsWithRetry(testRunner, &testCases, maxRetries, nil, &timeline)
 
 	t.Cleanup(func() {
 		os.Remove(testRunner.ResultPath)
@@ -155,7 +157,7 @@ func TestRunTestsWithRetry_Error(t *testing.T) {
 	maxRetries := 2
 	testCases := []string{"test/spec/fruits/fig_spec.rb"}
 	timeline := []api.Timeline{}
-	testResult, err := runTestsWithRetry(testRunner, &testCases, maxRetries, &timeline)
+	testResult, err := runTestsWithRetry(testRunner, &testCases, maxRetries, nil, &timeline)
 
 	exitError := new(exec.ExitError)
 	if !errors.As(err, &exitError) {
@@ -336,7 +338,8 @@ func TestFetchOrCreateTestPlan_PlanError(t *testing.T) {
 	})
 
 	// we want the function to return a fallback plan
-	want := plan.CreateFallbackPlan(files, cfg.Parallelism)
+	want := plan.CreateFallbackPlan(plan.EstimateDurations(files, nil), cfg.Parallelism)
+	want.FallbackReason = "Test Engine returned an error plan"
 
 	got, err := fetchOrCreateTestPlan(ctx, apiClient, cfg, files, TestRunner)
 	if err != nil {
@@ -374,7 +377,8 @@ func TestFetchOrCreateTestPlan_InternalServerError(t *testing.T) {
 	})
 
 	// we want the function to return a fallback plan
-	want := plan.CreateFallbackPlan(files, cfg.Parallelism)
+	want := plan.CreateFallbackPlan(plan.EstimateDurations(files, nil), cfg.Parallelism)
+	want.FallbackReason = "Test Engine is unavailable"
 
 	got, err := fetchOrCreateTestPlan(fetchCtx, apiClient, cfg, files, testRunner)
 	if err != nil {
@@ -444,7 +448,8 @@ func TestFetchOrCreateTestPlan_BillingError(t *testing.T) {
 	})
 
 	// we want the function to return a fallback plan
-	want := plan.CreateFallbackPlan(files, cfg.Parallelism)
+	want := plan.CreateFallbackPlan(plan.EstimateDurations(files, nil), cfg.Parallelism)
+	want.FallbackReason = "Billing Error: please update your plan"
 
 	got, err := fetchOrCreateTestPlan(ctx, apiClient, cfg, files, testRunner)
 	if err != nil {
@@ -455,6 +460,470 @@ func TestFetchOrCreateTestPlan_BillingError(t *testing.T) {
 	}
 }
 
+func TestFetchOrCreateTestPlan_PlanCache(t *testing.T) {
+	files := []string{"apple", "banana"}
+	testRunner := runner.Rspec{}
+
+	// mock server that should not be called when the plan is cached
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request to %s %s", r.Method, r.URL.Path)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}))
+	defer svr.Close()
+
+	cfg := config.Config{
+		NodeIndex:     0,
+		Parallelism:   2,
+		Identifier:    "identifier",
+		ServerBaseUrl: svr.URL,
+		PlanCacheDir:  t.TempDir(),
+	}
+	apiClient := api.NewClient(api.ClientConfig{
+		ServerBaseUrl: cfg.ServerBaseUrl,
+	})
+
+	want := plan.TestPlan{
+		Tasks: map[string]*plan.Task{
+			"0": {NodeNumber: 0, Tests: []plan.TestCase{{Path: "banana", Format: plan.TestCaseFormatFile}}},
+			"1": {NodeNumber: 1, Tests: []plan.TestCase{{Path: "apple", Format: plan.TestCaseFormatFile}}},
+		},
+	}
+	if _, err := plan.WriteCache(cfg.PlanCacheDir, cfg.Identifier, want); err != nil {
+		t.Fatalf("plan.WriteCache(%q, %q, %v) error = %v", cfg.PlanCacheDir, cfg.Identifier, want, err)
+	}
+
+	got, err := fetchOrCreateTestPlan(context.Background(), apiClient, cfg, files, testRunner)
+	if err != nil {
+		t.Errorf("fetchOrCreateTestPlan(ctx, %v, %v) error = %v", cfg, files, err)
+	}
+	want.Cached = true
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("fetchOrCreateTestPlan(ctx, %v, %v) diff (-got +want):\n%s", cfg, files, diff)
+	}
+}
+
+func TestFetchOrCreateTestPlan_PlanCacheMiss(t *testing.T) {
+	files := []string{"apple"}
+	testRunner := runner.Rspec{}
+
+	response := `{
+	"tasks": {
+		"0": {
+			"node_number": 0,
+			"tests": [
+				{
+					"path": "apple",
+					"format": "file"
+				}
+			]
+		}
+	}
+}`
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, response)
+	}))
+	defer svr.Close()
+
+	cfg := config.Config{
+		NodeIndex:     0,
+		Parallelism:   1,
+		Identifier:    "identifier",
+		ServerBaseUrl: svr.URL,
+		PlanCacheDir:  t.TempDir(),
+	}
+	apiClient := api.NewClient(api.ClientConfig{
+		ServerBaseUrl: cfg.ServerBaseUrl,
+	})
+
+	want := plan.TestPlan{
+		Tasks: map[string]*plan.Task{
+			"0": {NodeNumber: 0, Tests: []plan.TestCase{{Path: "apple", Format: plan.TestCaseFormatFile}}},
+		},
+	}
+
+	got, err := fetchOrCreateTestPlan(context.Background(), apiClient, cfg, files, testRunner)
+	if err != nil {
+		t.Errorf("fetchOrCreateTestPlan(ctx, %v, %v) error = %v", cfg, files, err)
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("fetchOrCreateTestPlan(ctx, %v, %v) diff (-got +want):\n%s", cfg, files, diff)
+	}
+
+	// the plan fetched from the server should be cached
+	cached, err := plan.ReadCache(cfg.PlanCacheDir, cfg.Identifier)
+	if err != nil {
+		t.Errorf("plan.ReadCache(%q, %q) error = %v", cfg.PlanCacheDir, cfg.Identifier, err)
+	}
+	want.Cached = true
+	if diff := cmp.Diff(cached, &want); diff != "" {
+		t.Errorf("plan.ReadCache(%q, %q) diff (-got +want):\n%s", cfg.PlanCacheDir, cfg.Identifier, diff)
+	}
+}
+
+func TestRunTestsWithRetry_Timeout(t *testing.T) {
+	testRunner := runner.NewCustom(runner.RunnerConfig{
+		TestCommand: "sh -c 'sleep 10'",
+		Timeout: runner.Timeout{
+			Run:         200 * time.Millisecond,
+			GracePeriod: time.Second,
+		},
+	})
+
+	testCases := []string{"apple is red"}
+	timeline := []api.Timeline{}
+	got, err := runTestsWithRetry(testRunner, &testCases, 2, nil, &timeline)
+
+	timeoutError := new(runner.TimeoutError)
+	if !errors.As(err, &timeoutError) {
+		t.Errorf("runTestsWithRetry(...) error = %v, want runner.TimeoutError", err)
+	}
+
+	if got.Status != runner.RunStatusTimeout {
+		t.Errorf("runTestsWithRetry(...) status = %q, want %q", got.Status, runner.RunStatusTimeout)
+	}
+
+	var events []string
+	for _, event := range timeline {
+		events = append(events, event.Event)
+	}
+	if diff := cmp.Diff(events, []string{"test_start", "test_timeout", "test_end"}); diff != "" {
+		t.Errorf("timeline events diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestRunTestsWithRetry_FlakyTests(t *testing.T) {
+	resultPath := filepath.Join(t.TempDir(), "junit.xml")
+	testRunner := runner.NewCustom(runner.RunnerConfig{
+		TestCommand:      "test/support/junit.sh {{resultPath}} {{testExamples}}",
+		RetryTestCommand: "test/support/junit.sh {{resultPath}} {{failedTests}}",
+		ResultPath:       resultPath,
+	})
+
+	testCases := []string{"apple is red", "banana is flaky", "cherry will fail", "grape is flaky"}
+	timeline := []api.Timeline{}
+	got, err := runTestsWithRetry(testRunner, &testCases, 2, nil, &timeline)
+	if err != nil {
+		t.Errorf("runTestsWithRetry(...) error = %v", err)
+	}
+
+	want := runner.RunResult{
+		Status:      runner.RunStatusFailed,
+		FailedTests: []string{"Fake::cherry will fail"},
+		FlakyTests:  []string{"Fake::banana is flaky", "Fake::grape is flaky"},
+		Tests: []runner.TestResult{
+			{Id: "Fake::apple is red", Name: "apple is red", Scope: "Fake", Status: runner.TestStatusPassed},
+			{Id: "Fake::banana is flaky", Name: "banana is flaky", Scope: "Fake", Status: runner.TestStatusPassed, Retries: 1},
+			{Id: "Fake::cherry will fail", Name: "cherry will fail", Scope: "Fake", Status: runner.TestStatusFailed, Retries: 2},
+			{Id: "Fake::grape is flaky", Name: "grape is flaky", Scope: "Fake", Status: runner.TestStatusPassed, Retries: 1},
+		},
+		Attempts: []runner.AttemptResult{
+			{Passed: 1, Failed: 3},
+			{Passed: 2, Failed: 1},
+			{Failed: 1},
+		},
+	}
+
+	if diff := cmp.Diff(got, want, cmpopts.IgnoreFields(runner.AttemptResult{}, "Duration", "Tests")); diff != "" {
+		t.Errorf("runTestsWithRetry(...) diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestRunTestsWithRetry_UnrequestedRetriedTests(t *testing.T) {
+	resultPath := filepath.Join(t.TempDir(), "junit.xml")
+	// The retry command also reports a test that wasn't retried,
+	// like Jest reports the tests that don't match the test name pattern as skipped.
+	testRunner := runner.NewCustom(runner.RunnerConfig{
+		TestCommand:      "test/support/junit.sh {{resultPath}} {{testExamples}}",
+		RetryTestCommand: "test/support/junit.sh {{resultPath}} 'apple is red' 'kiwi is green' {{failedTests}}",
+		ResultPath:       resultPath,
+	})
+
+	testCases := []string{"apple is red", "banana is flaky"}
+	timeline := []api.Timeline{}
+	got, err := runTestsWithRetry(testRunner, &testCases, 1, nil, &timeline)
+	if err != nil {
+		t.Errorf("runTestsWithRetry(...) error = %v", err)
+	}
+
+	want := []runner.TestResult{
+		{Id: "Fake::apple is red", Name: "apple is red", Scope: "Fake", Status: runner.TestStatusPassed},
+		{Id: "Fake::banana is flaky", Name: "banana is flaky", Scope: "Fake", Status: runner.TestStatusPassed, Retries: 1},
+	}
+
+	if diff := cmp.Diff(got.Tests, want); diff != "" {
+		t.Errorf("runTestsWithRetry(...) tests diff (-got +want):\n%s", diff)
+	}
+
+	// The retry only counts the retried test.
+	wantAttempts := []runner.AttemptResult{
+		{Passed: 1, Failed: 1},
+		{Passed: 1},
+	}
+	if diff := cmp.Diff(got.Attempts, wantAttempts, cmpopts.IgnoreFields(runner.AttemptResult{}, "Duration", "Tests")); diff != "" {
+		t.Errorf("runTestsWithRetry(...) attempts diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestRunTestsWithRetry_RetriedTestsNotRun(t *testing.T) {
+	resultPath := filepath.Join(t.TempDir(), "junit.xml")
+	// The retry command runs no test, so the retried test has no result.
+	testRunner := runner.NewCustom(runner.RunnerConfig{
+		TestCommand:      "test/support/junit.sh {{resultPath}} {{testExamples}}",
+		RetryTestCommand: `bash -c 'exec test/support/junit.sh "$0"' {{resultPath}} {{failedTests}}`,
+		ResultPath:       resultPath,
+	})
+
+	testCases := []string{"apple is red", "banana is flaky"}
+	timeline := []api.Timeline{}
+	got, err := runTestsWithRetry(testRunner, &testCases, 1, nil, &timeline)
+	if err != nil {
+		t.Errorf("runTestsWithRetry(...) error = %v", err)
+	}
+
+	// The test that didn't run on retry is not flaky, and it still fails the run.
+	want := runner.RunResult{
+		Status:      runner.RunStatusFailed,
+		FailedTests: []string{"Fake::banana is flaky"},
+	}
+
+	if diff := cmp.Diff(got, want, cmpopts.IgnoreFields(runner.RunResult{}, "Tests", "Attempts")); diff != "" {
+		t.Errorf("runTestsWithRetry(...) diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestRunTestsWithRetry_QuarantinedTests(t *testing.T) {
+	resultPath := filepath.Join(t.TempDir(), "junit.xml")
+	testRunner := runner.NewCustom(runner.RunnerConfig{
+		TestCommand:      "test/support/junit.sh {{resultPath}} {{testExamples}}",
+		RetryTestCommand: "test/support/junit.sh {{resultPath}} {{failedTests}}",
+		ResultPath:       resultPath,
+	})
+
+	quarantinedTests := map[string]bool{"Fake::banana will fail": true}
+	testCases := []string{"apple is red", "banana will fail", "cherry will fail"}
+	timeline := []api.Timeline{}
+	got, err := runTestsWithRetry(testRunner, &testCases, 1, quarantinedTests, &timeline)
+	if err != nil {
+		t.Errorf("runTestsWithRetry(...) error = %v", err)
+	}
+
+	want := runner.RunResult{
+		Status:           runner.RunStatusFailed,
+		FailedTests:      []string{"Fake::cherry will fail"},
+		QuarantinedTests: []string{"Fake::banana will fail"},
+	}
+
+	if diff := cmp.Diff(got, want, cmpopts.IgnoreFields(runner.RunResult{}, "Tests", "Attempts")); diff != "" {
+		t.Errorf("runTestsWithRetry(...) diff (-got +want):\n%s", diff)
+	}
+
+	// The quarantined test is not retried.
+	if diff := cmp.Diff(testCases, []string{"Fake::cherry will fail"}); diff != "" {
+		t.Errorf("retried tests diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestRunTestsWithRetry_OnlyQuarantinedTestsFailed(t *testing.T) {
+	resultPath := filepath.Join(t.TempDir(), "junit.xml")
+	testRunner := runner.NewCustom(runner.RunnerConfig{
+		TestCommand:      "test/support/junit.sh {{resultPath}} {{testExamples}}",
+		RetryTestCommand: "test/support/junit.sh {{resultPath}} {{failedTests}}",
+		ResultPath:       resultPath,
+	})
+
+	quarantinedTests := map[string]bool{"Fake::banana will fail": true}
+	testCases := []string{"apple is red", "banana will fail"}
+	timeline := []api.Timeline{}
+	got, err := runTestsWithRetry(testRunner, &testCases, 2, quarantinedTests, &timeline)
+	if err != nil {
+		t.Errorf("runTestsWithRetry(...) error = %v", err)
+	}
+
+	want := runner.RunResult{
+		Status:           runner.RunStatusPassed,
+		QuarantinedTests: []string{"Fake::banana will fail"},
+	}
+
+	if diff := cmp.Diff(got, want, cmpopts.IgnoreFields(runner.RunResult{}, "Tests", "Attempts")); diff != "" {
+		t.Errorf("runTestsWithRetry(...) diff (-got +want):\n%s", diff)
+	}
+
+	var events []string
+	for _, event := range timeline {
+		events = append(events, event.Event)
+	}
+	if diff := cmp.Diff(events, []string{"test_start", "test_end"}); diff != "" {
+		t.Errorf("timeline events diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestFallbackDurations(t *testing.T) {
+	dir := t.TempDir()
+	previousPlan := plan.TestPlan{
+		Tasks: map[string]*plan.Task{
+			"0": {NodeNumber: 0, Tests: []plan.TestCase{{Path: "./spec/apple_spec.rb", EstimatedDuration: 3000}}},
+		},
+	}
+	if _, err := plan.WriteCache(dir, "previous build", previousPlan); err != nil {
+		t.Fatal(err)
+	}
+
+	resultPath := filepath.Join(dir, "rspec.json")
+	result := `{"examples": [
+		{"id": "./spec/apple_spec.rb[1:1]", "file_path": "./spec/apple_spec.rb", "run_time": 1},
+		{"id": "./spec/banana_spec.rb[1:1]", "file_path": "./spec/banana_spec.rb", "run_time": 2}
+	]}`
+	if err := os.WriteFile(resultPath, []byte(result), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.Config{PlanCacheDir: dir}
+	files := []string{"./spec/apple_spec.rb", "./spec/banana_spec.rb"}
+	testRunner := runner.NewRspec(runner.RunnerConfig{ResultPath: resultPath})
+
+	got := fallbackDurations(cfg, files, testRunner)
+
+	// The estimate of Test Engine takes precedence over the result of the previous run.
+	want := map[string]time.Duration{
+		"./spec/apple_spec.rb":  3 * time.Second,
+		"./spec/banana_spec.rb": 2 * time.Second,
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("fallbackDurations(...) diff (-got +want):\n%s", diff)
+	}
+
+	// The durations are not used without the plan cache directory, because they may differ between nodes.
+	if got := fallbackDurations(config.Config{}, files, testRunner); got != nil {
+		t.Errorf("fallbackDurations(...) without plan cache directory = %v, want nil", got)
+	}
+}
+
+func TestFlakyPolicyExitCode(t *testing.T) {
+	cases := []struct {
+		policy     string
+		flakyTests []string
+		want       int
+	}{
+		{policy: "pass", flakyTests: []string{"banana is flaky"}, want: 0},
+		{policy: "fail", flakyTests: []string{"banana is flaky"}, want: 1},
+		{policy: "soft-fail", flakyTests: []string{"banana is flaky"}, want: softFailExitCode},
+		{policy: "fail", flakyTests: nil, want: 0},
+		{policy: "soft-fail", flakyTests: nil, want: 0},
+	}
+
+	for _, c := range cases {
+		got := flakyPolicyExitCode(c.policy, c.flakyTests)
+		if got != c.want {
+			t.Errorf("flakyPolicyExitCode(%q, %v) = %d, want %d", c.policy, c.flakyTests, got, c.want)
+		}
+	}
+}
+
+func TestRunTestsFromQueue(t *testing.T) {
+	batches := [][]plan.TestCase{
+		{{Path: "apple is red"}, {Path: "banana will fail"}},
+		{{Path: "cherry is red"}},
+		{},
+	}
+
+	requests := 0
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var params api.NextTestBatchParams
+		json.NewDecoder(r.Body).Decode(&params)
+		if params.NodeNumber != 1 {
+			t.Errorf("node_number = %d, want %d", params.NodeNumber, 1)
+		}
+
+		json.NewEncoder(w).Encode(map[string]any{"tests": batches[requests]})
+		requests++
+	}))
+	defer svr.Close()
+
+	cfg := config.Config{
+		NodeIndex:     1,
+		Parallelism:   2,
+		Identifier:    "identifier",
+		ServerBaseUrl: svr.URL,
+		SuiteSlug:     "suite",
+	}
+	apiClient := api.NewClient(api.ClientConfig{
+		ServerBaseUrl: cfg.ServerBaseUrl,
+	})
+
+	testRunner := runner.NewCustom(runner.RunnerConfig{
+		TestCommand: "test/support/junit.sh {{resultPath}} {{testExamples}}",
+		ResultPath:  "junit.xml",
+	})
+
+	t.Cleanup(func() {
+		os.Remove(testRunner.ResultPath)
+	})
+
+	timeline := []api.Timeline{}
+	got, err := runTestsFromQueue(context.Background(), apiClient, cfg, testRunner, nil, &timeline)
+	if err != nil {
+		t.Errorf("runTestsFromQueue(...) error = %v", err)
+	}
+
+	want := runner.RunResult{
+		Status:      runner.RunStatusFailed,
+		FailedTests: []string{"Fake::banana will fail"},
+	}
+
+	if diff := cmp.Diff(got, want, cmpopts.IgnoreFields(runner.RunResult{}, "Tests", "Attempts")); diff != "" {
+		t.Errorf("runTestsFromQueue(...) diff (-got +want):\n%s", diff)
+	}
+
+	if requests != 3 {
+		t.Errorf("runTestsFromQueue(...) requests = %d, want %d", requests, 3)
+	}
+
+	events := []string{}
+	for _, event := range timeline {
+		events = append(events, event.Event)
+	}
+	if diff := cmp.Diff(events, []string{"test_start", "batch_1_test_start", "batch_1_test_end", "batch_2_test_start", "batch_2_test_end", "test_end"}); diff != "" {
+		t.Errorf("timeline events diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestCreateTestQueue_BillingError(t *testing.T) {
+	files := []string{"apple", "banana"}
+	testRunner := runner.Rspec{}
+
+	// mock server to return 403 with a billing error
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message": "Billing Error: please update your plan"}`, http.StatusForbidden)
+	}))
+	defer svr.Close()
+
+	cfg := config.Config{
+		NodeIndex:     0,
+		Parallelism:   2,
+		Identifier:    "identifier",
+		ServerBaseUrl: svr.URL,
+		Mode:          "dynamic",
+	}
+	apiClient := api.NewClient(api.ClientConfig{
+		ServerBaseUrl: cfg.ServerBaseUrl,
+	})
+
+	// we want the function to return a fallback plan instead of a queue
+	want := plan.CreateFallbackPlan(plan.EstimateDurations(files, nil), cfg.Parallelism)
+	want.FallbackReason = "Billing Error: please update your plan"
+
+	got, useQueue, err := createTestQueue(context.Background(), apiClient, cfg, files, testRunner)
+	if err != nil {
+		t.Errorf("createTestQueue(ctx, %v, %v) error = %v", cfg, files, err)
+	}
+	if useQueue {
+		t.Errorf("createTestQueue(ctx, %v, %v) useQueue = %v, want %v", cfg, files, useQueue, false)
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("createTestQueue(ctx, %v, %v) diff (-got +want):\n%s", cfg, files, diff)
+	}
+}
+
 func TestCreateRequestParams(t *testing.T) {
 	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		fmt.Fprint(w, `
@@ -684,8 +1153,9 @@ func TestSendMetadata(t *testing.T) {
 		}
 
 		want := api.TestPlanMetadataParams{
-			Version:  "0.1.0",
-			Timeline: timeline,
+			Version:    "0.1.0",
+			Timeline:   timeline,
+			FlakyTests: []string{"spec/fruits/banana_spec.rb[1:2]"},
 			Env: map[string]string{
 				"BUILDKITE_BUILD_ID":                  "xyz",
 				"BUILDKITE_JOB_ID":                    "abc",
@@ -706,6 +1176,27 @@ func TestSendMetadata(t *testing.T) {
 				"BUILDKITE_TEST_ENGINE_TEST_FILE_PATTERN":         "",
 				"BUILDKITE_TEST_ENGINE_TEST_RUNNER":               "rspec",
 				"BUILDKITE_BRANCH":                                "",
+				"BUILDKITE_TEST_ENGINE_CONFIG_FILE":               "",
+				"BUILDKITE_TEST_ENGINE_MODE":                      "",
+				"BUILDKITE_TEST_ENGINE_NODE_INDEX":                "",
+				"BUILDKITE_TEST_ENGINE_PARALLELISM":               "",
+				"BUILDKITE_TEST_ENGINE_WORKERS":                   "",
+				"BUILDKITE_TEST_ENGINE_FLAKY_POLICY":              "",
+				"BUILDKITE_TEST_ENGINE_JSON_REPORT_PATH":          "",
+				"BUILDKITE_TEST_ENGINE_ANNOTATE":                  "",
+				"BUILDKITE_TEST_ENGINE_SUMMARY_DIR":               "",
+				"BUILDKITE_TEST_ENGINE_UPLOAD_RESULTS":            "",
+				"BUILDKITE_TEST_ENGINE_JUNIT_REPORT_PATH":         "",
+				"BUILDKITE_TEST_ENGINE_QUARANTINE_FILE":           "",
+				"BUILDKITE_TEST_ENGINE_QUARANTINE_FROM_API":       "",
+				"BUILDKITE_TEST_ENGINE_OUTPUT_TIMEOUT":            "",
+				"BUILDKITE_TEST_ENGINE_TIMEOUT":                   "",
+				"BUILDKITE_TEST_ENGINE_TIMEOUT_GRACE_PERIOD":      "",
+				"BUILDKITE_TEST_ENGINE_PLAN_CACHE_DIR":            "",
+				"BUILDKITE_TEST_ENGINE_PLAN_FILE":                 "",
+				"BUILDKITE_TEST_ENGINE_BRANCH":                    "",
+				"BUILDKITE_TEST_ENGINE_BUILD_ID":                  "",
+				"BUILDKITE_TEST_ENGINE_STEP_ID":                   "",
 			},
 		}
 
@@ -729,7 +1220,7 @@ func TestSendMetadata(t *testing.T) {
 		ServerBaseUrl: cfg.ServerBaseUrl,
 	})
 
-	sendMetadata(context.Background(), client, cfg, timeline)
+	sendMetadata(context.Background(), client, cfg, timeline, []string{"spec/fruits/banana_spec.rb[1:2]"})
 }
 
 func TestSendMetadata_Unauthorized(t *testing.T) {
@@ -750,5 +1241,5 @@ func TestSendMetadata_Unauthorized(t *testing.T) {
 
 	timeline := []api.Timeline{}
 
-	sendMetadata(context.Background(), client, cfg, timeline)
+	sendMetadata(context.Background(), client, cfg, timeline, nil)
 }
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/buildkite/test-engine-client/internal/api"
+	"github.com/buildkite/test-engine-client/internal/config"
+	"github.com/buildkite/test-engine-client/internal/plan"
+	"github.com/buildkite/test-engine-client/internal/runner"
+)
+
+// planCommand implements the "bktec plan" subcommand.
+// It discovers the test files, fetches or creates the test plan, and writes the plan
+// for all nodes without running the tests.
+func planCommand(args []string) {
+	flags := flag.NewFlagSet("plan", flag.ExitOnError)
+	format := flags.String("format", "json", "output format of the plan, either \"json\" or \"table\"")
+	output := flags.String("output", "", "path of the file to write the plan to, defaults to stdout")
+	configOverrides := addConfigFlags(flags)
+	flags.Parse(args)
+
+	if *format != "json" && *format != "table" {
+		logErrorAndExit(16, "Unsupported value for --format %q, must be \"json\" or \"table\"", *format)
+	}
+
+	overrides := configOverrides()
+	setDebugFromOverrides(overrides)
+
+	cfg, err := config.New(overrides)
+	if err != nil {
+		logErrorAndExit(16, "Invalid configuration...\n%v", err)
+	}
+
+	testRunner, err := runner.DetectRunner(cfg)
+	if err != nil {
+		logErrorAndExit(16, "Unsupported value for BUILDKITE_TEST_ENGINE_TEST_RUNNER %q: %v", cfg.TestRunner, err)
+	}
+
+	files, err := testRunner.GetFiles()
+	if err != nil {
+		logErrorAndExit(16, "Couldn't get files: %v", err)
+	}
+
+	ctx := context.Background()
+	apiClient := api.NewClient(api.ClientConfig{
+		ServerBaseUrl:    cfg.ServerBaseUrl,
+		AccessToken:      cfg.AccessToken,
+		OrganizationSlug: cfg.OrganizationSlug,
+		Version:          Version,
+	})
+
+	testPlan, err := fetchOrCreateTestPlan(ctx, apiClient, cfg, files, testRunner)
+	if err != nil {
+		logErrorAndExit(16, "Couldn't fetch or create test plan: %v", err)
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			logErrorAndExit(16, "Couldn't create plan output file: %v", err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if *format == "table" {
+		err = writePlanTable(w, testPlan)
+	} else {
+		err = writePlanJSON(w, testPlan)
+	}
+
+	if err != nil {
+		logErrorAndExit(16, "Couldn't write test plan: %v", err)
+	}
+}
+
+// writePlanJSON writes the test plan as indented JSON.
+func writePlanJSON(w io.Writer, testPlan plan.TestPlan) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(testPlan)
+}
+
+// writePlanTable writes the test plan as a human readable table,
+// with a row for each test and a row for the total of each node.
+// Unknown estimated durations, e.g. of a fallback plan, are shown as "-".
+func writePlanTable(w io.Writer, testPlan plan.TestPlan) error {
+	tasks := make([]*plan.Task, 0, len(testPlan.Tasks))
+	for _, task := range testPlan.Tasks {
+		tasks = append(tasks, task)
+	}
+	slices.SortFunc(tasks, func(a, b *plan.Task) int {
+		return cmp.Compare(a.NodeNumber, b.NodeNumber)
+	})
+
+	estimate := func(value int) string {
+		if value == 0 {
+			return "-"
+		}
+		return (time.Duration(value) * time.Millisecond).String()
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "NODE\tTEST\tESTIMATED DURATION\n")
+
+	for _, task := range tasks {
+		total := 0
+		for _, testCase := range task.Tests {
+			total += testCase.EstimatedDuration
+			fmt.Fprintf(tw, "%d\t%s\t%s\n", task.NodeNumber, testCase.Path, estimate(testCase.EstimatedDuration))
+		}
+		fmt.Fprintf(tw, "%d\tTotal (%d tests)\t%s\n", task.NodeNumber, len(task.Tests), estimate(total))
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/buildkite/test-engine-client/internal/plan"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWritePlanTable(t *testing.T) {
+	testPlan := plan.TestPlan{
+		Tasks: map[string]*plan.Task{
+			"1": {
+				NodeNumber: 1,
+				Tests: []plan.TestCase{
+					{Path: "spec/banana_spec.rb", EstimatedDuration: 90000},
+				},
+			},
+			"0": {
+				NodeNumber: 0,
+				Tests: []plan.TestCase{
+					{Path: "spec/apple_spec.rb", EstimatedDuration: 60000},
+					{Path: "spec/cherry_spec.rb:12", EstimatedDuration: 1500},
+				},
+			},
+		},
+	}
+
+	var got bytes.Buffer
+	if err := writePlanTable(&got, testPlan); err != nil {
+		t.Errorf("writePlanTable(%v) error = %v", testPlan, err)
+	}
+
+	want := `NODE  TEST                    ESTIMATED DURATION
+0     spec/apple_spec.rb      1m0s
+0     spec/cherry_spec.rb:12  1.5s
+0     Total (2 tests)         1m1.5s
+1     spec/banana_spec.rb     1m30s
+1     Total (1 tests)         1m30s
+`
+	if diff := cmp.Diff(got.String(), want); diff != "" {
+		t.Errorf("writePlanTable(%v) diff (-got +want):\n%s", testPlan, diff)
+	}
+}
+
+func TestWritePlanTable_Fallback(t *testing.T) {
+	testPlan := plan.CreateFallbackPlan([]plan.TestCase{
+		{Path: "spec/apple_spec.rb", EstimatedDuration: 2000, Weight: 2048},
+		{Path: "spec/banana_spec.rb", Weight: 1024},
+	}, 2)
+
+	var got bytes.Buffer
+	if err := writePlanTable(&got, testPlan); err != nil {
+		t.Errorf("writePlanTable(%v) error = %v", testPlan, err)
+	}
+
+	want := `NODE  TEST                 ESTIMATED DURATION
+0     spec/apple_spec.rb   2s
+0     Total (1 tests)      2s
+1     spec/banana_spec.rb  -
+1     Total (1 tests)      -
+`
+	if diff := cmp.Diff(got.String(), want); diff != "" {
+		t.Errorf("writePlanTable(%v) diff (-got +want):\n%s", testPlan, diff)
+	}
+}
+
+func TestWritePlanJSON(t *testing.T) {
+	testPlan := plan.TestPlan{
+		Tasks: map[string]*plan.Task{
+			"0": {
+				NodeNumber: 0,
+				Tests: []plan.TestCase{
+					{Path: "spec/apple_spec.rb", EstimatedDuration: 60000, Format: plan.TestCaseFormatFile},
+				},
+			},
+		},
+	}
+
+	var got bytes.Buffer
+	if err := writePlanJSON(&got, testPlan); err != nil {
+		t.Errorf("writePlanJSON(%v) error = %v", testPlan, err)
+	}
+
+	want := `{
+  "experiment": "",
+  "tasks": {
+    "0": {
+      "node_number": 0,
+      "tests": [
+        {
+          "estimated_duration": 60000,
+          "format": "file",
+          "path": "spec/apple_spec.rb"
+        }
+      ]
+    }
+  },
+  "fallback": false
+}
+`
+	if diff := cmp.Diff(got.String(), want); diff != "" {
+		t.Errorf("writePlanJSON(%v) diff (-got +want):\n%s", testPlan, diff)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/buildkite/test-engine-client/internal/api"
+	"github.com/buildkite/test-engine-client/internal/config"
+	"github.com/buildkite/test-engine-client/internal/debug"
+)
+
+// loadQuarantinedTests returns the quarantined tests read from the quarantine file,
+// and fetched from Test Engine when enabled. The tests are identified the same way as
+// the failed tests reported by the test runner, e.g. RSpec example IDs or Jest full names.
+//
+// An error is returned if the quarantine file can't be read. Failing to fetch the quarantined tests
+// from Test Engine is not an error, because it only makes the quarantined tests fail the build.
+func loadQuarantinedTests(ctx context.Context, apiClient *api.Client, cfg config.Config) (map[string]bool, error) {
+	quarantinedTests := map[string]bool{}
+
+	if cfg.QuarantineFile != "" {
+		tests, err := readQuarantineFile(cfg.QuarantineFile)
+		if err != nil {
+			return nil, err
+		}
+		for _, test := range tests {
+			quarantinedTests[test] = true
+		}
+	}
+
+	if cfg.QuarantineFromAPI && apiClient != nil {
+		tests, err := apiClient.FetchQuarantinedTests(ctx, cfg.SuiteSlug)
+		if err != nil {
+			fmt.Printf("Failed to fetch quarantined tests from Test Engine: %v\n", err)
+		}
+		for _, test := range tests {
+			quarantinedTests[test] = true
+		}
+	}
+
+	debug.Printf("Loaded %d quarantined tests", len(quarantinedTests))
+	return quarantinedTests, nil
+}
+
+// readQuarantineFile reads the tests listed in the quarantine file, one per line.
+// Blank lines and lines starting with "#" are ignored.
+func readQuarantineFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var tests []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		tests = append(tests, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return tests, nil
+}
+
+// printQuarantinedTests prints the quarantined tests that failed, which don't fail the build.
+func printQuarantinedTests(quarantinedTests []string) {
+	if len(quarantinedTests) == 0 {
+		return
+	}
+
+	fmt.Printf("+++ Buildkite Test Engine Client: 🔇 %d quarantined tests failed\n", len(quarantinedTests))
+	for _, test := range quarantinedTests {
+		fmt.Printf("  %s\n", test)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/buildkite/test-engine-client/internal/api"
+	"github.com/buildkite/test-engine-client/internal/config"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestReadQuarantineFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "quarantine.txt")
+	content := "# Known flaky tests\n./spec/banana_spec.rb[1:2]\n\n  Fruits banana is yellow  \n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := readQuarantineFile(path)
+	if err != nil {
+		t.Errorf("readQuarantineFile(%q) error = %v", path, err)
+	}
+
+	want := []string{"./spec/banana_spec.rb[1:2]", "Fruits banana is yellow"}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("readQuarantineFile(%q) diff (-got +want):\n%s", path, diff)
+	}
+}
+
+func TestLoadQuarantinedTests(t *testing.T) {
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/analytics/organizations/my-org/suites/my-suite/quarantined_tests" {
+			t.Errorf("request path = %q", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"tests": ["./spec/cherry_spec.rb[1:1]"]}`))
+	}))
+	defer svr.Close()
+
+	path := filepath.Join(t.TempDir(), "quarantine.txt")
+	if err := os.WriteFile(path, []byte("./spec/banana_spec.rb[1:2]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.Config{
+		OrganizationSlug:  "my-org",
+		SuiteSlug:         "my-suite",
+		ServerBaseUrl:     svr.URL,
+		QuarantineFile:    path,
+		QuarantineFromAPI: true,
+	}
+	apiClient := api.NewClient(api.ClientConfig{
+		ServerBaseUrl:    cfg.ServerBaseUrl,
+		OrganizationSlug: cfg.OrganizationSlug,
+	})
+
+	got, err := loadQuarantinedTests(context.Background(), apiClient, cfg)
+	if err != nil {
+		t.Errorf("loadQuarantinedTests(...) error = %v", err)
+	}
+
+	want := map[string]bool{
+		"./spec/banana_spec.rb[1:2]": true,
+		"./spec/cherry_spec.rb[1:1]": true,
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("loadQuarantinedTests(...) diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestLoadQuarantinedTests_FileNotFound(t *testing.T) {
+	cfg := config.Config{
+		QuarantineFile: filepath.Join(t.TempDir(), "quarantine.txt"),
+	}
+
+	_, err := loadQuarantinedTests(context.Background(), nil, cfg)
+	if !os.IsNotExist(err) {
+		t.Errorf("loadQuarantinedTests(...) error = %v, want os.ErrNotExist", err)
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+
+	"github.com/buildkite/test-engine-client/internal/server"
+)
+
+// serveCommand implements the "bktec serve" subcommand.
+// It serves a local implementation of the Test Plan API, which computes the test plans
+// from the timings in a local file, for CI systems that can't reach Buildkite.
+func serveCommand(args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	listen := flags.String("listen", ":8080", "address to listen on, which is every interface by default so that the other CI nodes can reach the server, e.g. 127.0.0.1:8080 to only accept local connections")
+	timingsPath := flags.String("timings", "", "path of a JSON file of test durations in milliseconds keyed by test path")
+	flags.Parse(args)
+
+	timings := map[string]int{}
+	if *timingsPath != "" {
+		var err error
+		timings, err = server.ReadTimings(*timingsPath)
+		if err != nil {
+			logErrorAndExit(16, "Couldn't read timings: %v", err)
+		}
+	}
+
+	fmt.Printf("Buildkite Test Engine Client: Serving Test Plan API on http://%s with %d timings\n", *listen, len(timings))
+
+	if err := http.ListenAndServe(*listen, server.New(timings)); err != nil {
+		logErrorAndExit(16, "Couldn't serve Test Plan API: %v", err)
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/buildkite/test-engine-client/internal/config"
+	"github.com/buildkite/test-engine-client/internal/plan"
+	"github.com/buildkite/test-engine-client/internal/report"
+	"github.com/buildkite/test-engine-client/internal/runner"
+)
+
+// buildkiteAgent is the command used to annotate the build, which can be replaced in tests.
+var buildkiteAgent = "buildkite-agent"
+
+// planSource returns where the test plan of the node comes from, see report.Summary.
+func planSource(cfg config.Config, testPlan plan.TestPlan, useQueue bool) string {
+	switch {
+	case useQueue:
+		return "queue"
+	case cfg.PlanFile != "":
+		return "file"
+	case testPlan.Cached:
+		return "cache"
+	case testPlan.Fallback:
+		return "fallback"
+	default:
+		return "server"
+	}
+}
+
+// newSummary creates the summary of running the tests of the node.
+// The error is the error of running the tests, which makes the status of the run "error"
+// unless the tests timed out.
+func newSummary(cfg config.Config, runnerName string, testPlan plan.TestPlan, useQueue bool, testResult runner.RunResult, err error, duration time.Duration) report.Summary {
+	summary := report.Summary{
+		NodeIndex:        cfg.NodeIndex,
+		Runner:           runnerName,
+		Status:           testResult.Status,
+		PlanSource:       planSource(cfg, testPlan, useQueue),
+		FallbackReason:   testPlan.FallbackReason,
+		Duration:         duration,
+		Attempts:         testResult.Attempts,
+		FailedTests:      testResult.FailedTests,
+		FlakyTests:       testResult.FlakyTests,
+		QuarantinedTests: testResult.QuarantinedTests,
+	}
+
+	if err != nil && summary.Status != runner.RunStatusTimeout {
+		summary.Status = runner.RunStatusError
+	}
+
+	if task, ok := testPlan.Tasks[strconv.Itoa(cfg.NodeIndex)]; ok && !useQueue {
+		estimatedDuration := 0
+		for _, testCase := range task.Tests {
+			if testCase.Format == plan.TestCaseFormatExample {
+				summary.Examples++
+			} else {
+				summary.Files++
+			}
+			estimatedDuration += testCase.EstimatedDuration
+		}
+		summary.EstimatedDuration = time.Duration(estimatedDuration) * time.Millisecond
+	}
+
+	return summary
+}
+
+// writeSummary writes the summary of the node as JSON and markdown to the summary directory,
+// and annotates the build with the markdown when enabled.
+func writeSummary(cfg config.Config, summary report.Summary) {
+	if cfg.SummaryDir != "" {
+		if err := os.MkdirAll(cfg.SummaryDir, 0755); err != nil {
+			logReportError("Failed to create summary directory: %v", err)
+			return
+		}
+	}
+
+	name := fmt.Sprintf("bktec-summary-%d", cfg.NodeIndex)
+	jsonPath := filepath.Join(cfg.SummaryDir, name+".json")
+	markdownPath := filepath.Join(cfg.SummaryDir, name+".md")
+
+	if err := report.WriteSummaryJSON(jsonPath, summary); err != nil {
+		logReportError("Failed to write JSON summary: %v", err)
+	}
+
+	if err := report.WriteSummaryMarkdown(markdownPath, summary); err != nil {
+		logReportError("Failed to write markdown summary: %v", err)
+		return
+	}
+
+	if cfg.Annotate {
+		annotationContext := fmt.Sprintf("bktec-summary-%s-%d", cfg.Identifier, cfg.NodeIndex)
+		if err := annotateBuild(markdownPath, annotationContext, annotationStyle(summary)); err != nil {
+			logReportError("Failed to annotate the build with the summary: %v", err)
+		}
+	}
+}
+
+// annotationStyle returns the style of the annotation of the summary.
+func annotationStyle(summary report.Summary) string {
+	switch {
+	case summary.Status != runner.RunStatusPassed:
+		return "error"
+	case len(summary.FlakyTests) > 0 || len(summary.QuarantinedTests) > 0:
+		return "warning"
+	default:
+		return "success"
+	}
+}
+
+// annotateBuild annotates the Buildkite build with the markdown file using buildkite-agent.
+// The context identifies the annotation, so annotating again replaces it, e.g. when the job is retried.
+func annotateBuild(markdownPath string, context string, style string) error {
+	f, err := os.Open(markdownPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	cmd := exec.Command(buildkiteAgent, "annotate", "--context", context, "--style", style)
+	cmd.Stdin = f
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/buildkite/test-engine-client/internal/config"
+	"github.com/buildkite/test-engine-client/internal/plan"
+	"github.com/buildkite/test-engine-client/internal/report"
+	"github.com/buildkite/test-engine-client/internal/runner"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPlanSource(t *testing.T) {
+	cases := []struct {
+		name     string
+		cfg      config.Config
+		testPlan plan.TestPlan
+		useQueue bool
+		want     string
+	}{
+		{name: "server", want: "server"},
+		{name: "cache", testPlan: plan.TestPlan{Cached: true}, want: "cache"},
+		{name: "fallback", testPlan: plan.TestPlan{Fallback: true}, want: "fallback"},
+		{name: "cached fallback", testPlan: plan.TestPlan{Fallback: true, Cached: true}, want: "cache"},
+		{name: "file", cfg: config.Config{PlanFile: "plan.json"}, want: "file"},
+		{name: "queue", useQueue: true, want: "queue"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := planSource(c.cfg, c.testPlan, c.useQueue); got != c.want {
+				t.Errorf("planSource(...) = %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestNewSummary(t *testing.T) {
+	cfg := config.Config{NodeIndex: 1}
+	testPlan := plan.TestPlan{
+		Tasks: map[string]*plan.Task{
+			"0": {NodeNumber: 0, Tests: []plan.TestCase{{Path: "a_spec.rb", Format: plan.TestCaseFormatFile, EstimatedDuration: 5000}}},
+			"1": {NodeNumber: 1, Tests: []plan.TestCase{
+				{Path: "b_spec.rb", Format: plan.TestCaseFormatFile, EstimatedDuration: 2000},
+				{Path: "c_spec.rb[1:1]", Format: plan.TestCaseFormatExample, EstimatedDuration: 500},
+				{Path: "c_spec.rb[1:2]", Format: plan.TestCaseFormatExample, EstimatedDuration: 500},
+			}},
+		},
+	}
+	testResult := runner.RunResult{
+		Status:      runner.RunStatusFailed,
+		FailedTests: []string{"c_spec.rb[1:2]"},
+		FlakyTests:  []string{"c_spec.rb[1:1]"},
+		Attempts: []runner.AttemptResult{
+			{Passed: 1, Failed: 2, Duration: 3 * time.Second},
+			{Passed: 1, Failed: 1, Duration: time.Second},
+		},
+	}
+
+	got := newSummary(cfg, "RSpec", testPlan, false, testResult, nil, 4*time.Second)
+
+	want := report.Summary{
+		NodeIndex:         1,
+		Runner:            "RSpec",
+		Status:            runner.RunStatusFailed,
+		PlanSource:        "server",
+		Files:             1,
+		Examples:          2,
+		EstimatedDuration: 3 * time.Second,
+		Duration:          4 * time.Second,
+		Attempts:          testResult.Attempts,
+		FailedTests:       []string{"c_spec.rb[1:2]"},
+		FlakyTests:        []string{"c_spec.rb[1:1]"},
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("newSummary(...) diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestNewSummary_Fallback(t *testing.T) {
+	cfg := config.Config{NodeIndex: 0}
+	testPlan := plan.CreateFallbackPlan([]plan.TestCase{{Path: "apple", Weight: 1024}}, 1)
+	testPlan.FallbackReason = "Test Engine is unavailable"
+	testResult := runner.RunResult{Status: runner.RunStatusError}
+
+	got := newSummary(cfg, "Jest", testPlan, false, testResult, errors.New("exit status 1"), time.Second)
+
+	want := report.Summary{
+		Runner:         "Jest",
+		Status:         runner.RunStatusError,
+		PlanSource:     "fallback",
+		FallbackReason: "Test Engine is unavailable",
+		Files:          1,
+		Duration:       time.Second,
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("newSummary(...) diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestWriteSummary(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "summary")
+	cfg := config.Config{NodeIndex: 2, SummaryDir: dir}
+
+	writeSummary(cfg, report.Summary{NodeIndex: 2, Runner: "RSpec", Status: runner.RunStatusPassed})
+
+	for _, name := range []string{"bktec-summary-2.json", "bktec-summary-2.md"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("os.Stat(%q) error = %v", name, err)
+		}
+	}
+}
+
+func TestWriteSummary_Annotate(t *testing.T) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "annotation.txt")
+
+	// The fake buildkite-agent writes its arguments and the annotation from stdin to the output file.
+	agent := filepath.Join(dir, "buildkite-agent")
+	script := "#!/bin/sh\necho \"$@\" > " + output + "\ncat >> " + output + "\n"
+	if err := os.WriteFile(agent, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	original := buildkiteAgent
+	buildkiteAgent = agent
+	t.Cleanup(func() {
+		buildkiteAgent = original
+	})
+
+	cfg := config.Config{NodeIndex: 0, Identifier: "build/step", SummaryDir: dir, Annotate: true}
+	summary := report.Summary{
+		Runner:     "RSpec",
+		Status:     runner.RunStatusPassed,
+		FlakyTests: []string{"apple_spec.rb[1:1]"},
+	}
+
+	writeSummary(cfg, summary)
+
+	got, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("os.ReadFile(%q) error = %v", output, err)
+	}
+
+	args, annotation, _ := strings.Cut(string(got), "\n")
+	if want := "annotate --context bktec-summary-build/step-0 --style warning"; args != want {
+		t.Errorf("buildkite-agent args = %q, want %q", args, want)
+	}
+
+	markdown, err := os.ReadFile(filepath.Join(dir, "bktec-summary-0.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(annotation, string(markdown)); diff != "" {
+		t.Errorf("annotation diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestAnnotationStyle(t *testing.T) {
+	cases := []struct {
+		summary report.Summary
+		want    string
+	}{
+		{summary: report.Summary{Status: runner.RunStatusPassed}, want: "success"},
+		{summary: report.Summary{Status: runner.RunStatusPassed, FlakyTests: []string{"a"}}, want: "warning"},
+		{summary: report.Summary{Status: runner.RunStatusPassed, QuarantinedTests: []string{"a"}}, want: "warning"},
+		{summary: report.Summary{Status: runner.RunStatusFailed, FlakyTests: []string{"a"}}, want: "error"},
+		{summary: report.Summary{Status: runner.RunStatusTimeout}, want: "error"},
+	}
+
+	for _, c := range cases {
+		if got := annotationStyle(c.summary); got != c.want {
+			t.Errorf("annotationStyle(%v) = %q, want %q", c.summary, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+#!/usr/bin/env bash
+
+# This is a fake test runner that writes a JUnit XML report.
+# It is used to test the custom runner.
+#
+# Usage: junit.sh <result path> <test name>...
+# Each test name is reported as a test case of the "Fake" class, and test names
+# can be qualified by the class like "Fake::apple is red". Test names containing
+# "fail" are reported as failures. Test names containing "flaky" are reported
+# as failures only when the result file doesn't exist yet, so they pass when
+# they are retried. It exits with 1 if any test failed.
+set -euo pipefail
+
+result_path="$1"
+shift
+
+first_run=true
+if [[ -e "$result_path" ]]; then
+  first_run=false
+fi
+
+status=0
+{
+  echo '<?xml version="1.0" encoding="UTF-8"?>'
+  echo '<testsuites>'
+  echo '  <testsuite name="fake">'
+  for name in "$@"; do
+    name="${name#Fake::}"
+    if [[ "$name" == *fail* ]] || [[ "$name" == *flaky* && "$first_run" == true ]]; then
+      echo "    <testcase classname=\"Fake\" name=\"$name\"><failure message=\"failed\" /></testcase>"
+      status=1
+    else
+      echo "    <testcase classname=\"Fake\" name=\"$name\" />"
+    fi
+  done
+  echo '  </testsuite>'
+  echo '</testsuites>'
+} > "$result_path"
+
+exit $status
@@ -0,0 +1,20 @@
+#!/usr/bin/env bash
+
+# This is a fake test runner that spawns a child process, like the workers
+# of Jest or the Spring server of RSpec. It is used to test that the children
+# are signaled and killed with the test runner.
+#
+# Usage: spawn_children.sh <pid path> [wait|exit]
+# The pid of the child is written to <pid path>. With "wait", it waits for
+# the child to exit, otherwise it exits immediately and leaves the child behind.
+set -euo pipefail
+
+pid_path="$1"
+mode="${2:-wait}"
+
+sleep 30 &
+echo $! > "$pid_path"
+
+if [[ "$mode" == "wait" ]]; then
+  wait
+fi
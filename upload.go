@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/buildkite/test-engine-client/internal/api"
+	"github.com/buildkite/test-engine-client/internal/config"
+	"github.com/buildkite/test-engine-client/internal/runner"
+)
+
+// uploadTestResults uploads the results of the tests of every attempt to Test Engine, when it's enabled,
+// so each execution of a retried test is uploaded. Only the tests requested in an attempt are uploaded,
+// not the other tests the test runner reports as skipped, see runTestsWithRetry.
+// The results are not uploaded when the run was interrupted, i.e. runErr is a signal or a cancellation,
+// so that bktec exits promptly instead of waiting for the upload after it's told to stop.
+func uploadTestResults(ctx context.Context, cfg config.Config, attempts []runner.AttemptResult, runErr error) {
+	if !cfg.UploadResults {
+		return
+	}
+
+	if interrupted(runErr) {
+		fmt.Println("+++ Buildkite Test Engine Client: Skipping the upload of the test results because the run was interrupted")
+		return
+	}
+
+	results := uploadResults(attempts)
+	if len(results) == 0 {
+		return
+	}
+
+	fmt.Printf("+++ Buildkite Test Engine Client: Uploading %d test results to Test Engine\n", len(results))
+
+	client := api.NewUploadClient(api.UploadClientConfig{
+		SuiteToken:    cfg.SuiteToken,
+		ServerBaseUrl: cfg.UploadBaseUrl,
+		Version:       Version,
+	})
+
+	runEnv := api.UploadRunEnv{
+		CI:        cfg.CIProvider,
+		Key:       cfg.Identifier,
+		JobId:     cfg.JobId,
+		Branch:    cfg.Branch,
+		Collector: "bktec",
+		Version:   Version,
+	}
+
+	if err := client.UploadTestResults(ctx, runEnv, results); err != nil {
+		logReportError("Failed to upload test results to Test Engine: %v", err)
+	}
+}
+
+// interrupted returns true if err means that the run was stopped by a signal or a cancellation.
+func interrupted(err error) bool {
+	if signaledError := new(runner.ProcessSignaledError); errors.As(err, &signaledError) {
+		return true
+	}
+	return errors.Is(err, context.Canceled)
+}
+
+// uploadResults converts the results of the tests of every attempt to the format of the upload API.
+func uploadResults(attempts []runner.AttemptResult) []api.UploadTestResult {
+	var results []api.UploadTestResult
+	for _, attempt := range attempts {
+		for _, test := range attempt.Tests {
+			duration := test.Duration.Seconds()
+			results = append(results, api.UploadTestResult{
+				Scope:    test.Scope,
+				Name:     test.Name,
+				Location: uploadLocation(test),
+				FileName: test.Path,
+				Result:   string(test.Status),
+				History: api.UploadTestHistory{
+					Section:  "top",
+					EndAt:    duration,
+					Duration: duration,
+				},
+			})
+		}
+	}
+	return results
+}
+
+// uploadLocation returns the location of the test as reported by the test runner,
+// i.e. the file and line of the test, or only the file when the line is not reported.
+func uploadLocation(test runner.TestResult) string {
+	if test.Path == "" || test.Line == 0 {
+		return test.Path
+	}
+	return test.Path + ":" + strconv.Itoa(test.Line)
+}
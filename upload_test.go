@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/buildkite/test-engine-client/internal/api"
+	"github.com/buildkite/test-engine-client/internal/config"
+	"github.com/buildkite/test-engine-client/internal/runner"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestUploadTestResults(t *testing.T) {
+	var got struct {
+		RunEnv api.UploadRunEnv       `json:"run_env"`
+		Data   []api.UploadTestResult `json:"data"`
+	}
+	requests := 0
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decoding request body error = %v", err)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer svr.Close()
+
+	cfg := config.Config{
+		UploadResults: true,
+		SuiteToken:    "suite-token",
+		UploadBaseUrl: svr.URL,
+		CIProvider:    "buildkite",
+		Identifier:    "build/step",
+		JobId:         "job",
+		Branch:        "main",
+	}
+	attempts := []runner.AttemptResult{
+		{Tests: []runner.TestResult{
+			{Id: "./apple_spec.rb[1:1]", Scope: "Apple", Name: "is red", Path: "./apple_spec.rb", Line: 4, Status: runner.TestStatusPassed, Duration: 1500 * time.Millisecond},
+			{Id: "./apple_spec.rb[1:2]", Scope: "Apple", Name: "is sweet", Path: "./apple_spec.rb", Status: runner.TestStatusFailed, Duration: time.Second},
+		}},
+		{Tests: []runner.TestResult{
+			{Id: "./apple_spec.rb[1:2]", Scope: "Apple", Name: "is sweet", Path: "./apple_spec.rb", Status: runner.TestStatusPassed, Duration: 2 * time.Second},
+		}},
+	}
+
+	uploadTestResults(context.Background(), cfg, attempts, nil)
+
+	if requests != 1 {
+		t.Fatalf("request count = %d, want 1", requests)
+	}
+
+	wantRunEnv := api.UploadRunEnv{CI: "buildkite", Key: "build/step", JobId: "job", Branch: "main", Collector: "bktec", Version: Version}
+	if diff := cmp.Diff(got.RunEnv, wantRunEnv); diff != "" {
+		t.Errorf("run_env diff (-got +want):\n%s", diff)
+	}
+
+	wantData := []api.UploadTestResult{
+		{Scope: "Apple", Name: "is red", Location: "./apple_spec.rb:4", FileName: "./apple_spec.rb", Result: "passed", History: api.UploadTestHistory{Section: "top", EndAt: 1.5, Duration: 1.5}},
+		{Scope: "Apple", Name: "is sweet", Location: "./apple_spec.rb", FileName: "./apple_spec.rb", Result: "failed", History: api.UploadTestHistory{Section: "top", EndAt: 1, Duration: 1}},
+		{Scope: "Apple", Name: "is sweet", Location: "./apple_spec.rb", FileName: "./apple_spec.rb", Result: "passed", History: api.UploadTestHistory{Section: "top", EndAt: 2, Duration: 2}},
+	}
+	if diff := cmp.Diff(got.Data, wantData, cmpopts.IgnoreFields(api.UploadTestResult{}, "Id")); diff != "" {
+		t.Errorf("uploaded test results diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestUploadTestResults_RetriedTests(t *testing.T) {
+	var got struct {
+		Data []api.UploadTestResult `json:"data"`
+	}
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decoding request body error = %v", err)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer svr.Close()
+
+	// The retry command also reports a test that wasn't retried,
+	// like Jest reports the tests that don't match the test name pattern as skipped.
+	testRunner := runner.NewCustom(runner.RunnerConfig{
+		TestCommand:      "test/support/junit.sh {{resultPath}} {{testExamples}}",
+		RetryTestCommand: "test/support/junit.sh {{resultPath}} 'apple is red' {{failedTests}}",
+		ResultPath:       filepath.Join(t.TempDir(), "junit.xml"),
+	})
+
+	testCases := []string{"apple is red", "banana is flaky"}
+	timeline := []api.Timeline{}
+	result, err := runTestsWithRetry(testRunner, &testCases, 1, nil, &timeline)
+	if err != nil {
+		t.Fatalf("runTestsWithRetry(...) error = %v", err)
+	}
+
+	cfg := config.Config{UploadResults: true, UploadBaseUrl: svr.URL}
+	uploadTestResults(context.Background(), cfg, result.Attempts, nil)
+
+	want := []api.UploadTestResult{
+		{Scope: "Fake", Name: "apple is red", Result: "passed", History: api.UploadTestHistory{Section: "top"}},
+		{Scope: "Fake", Name: "banana is flaky", Result: "failed", History: api.UploadTestHistory{Section: "top"}},
+		{Scope: "Fake", Name: "banana is flaky", Result: "passed", History: api.UploadTestHistory{Section: "top"}},
+	}
+	if diff := cmp.Diff(got.Data, want, cmpopts.IgnoreFields(api.UploadTestResult{}, "Id"), cmpopts.IgnoreFields(api.UploadTestHistory{}, "EndAt", "Duration")); diff != "" {
+		t.Errorf("uploaded test results diff (-got +want):\n%s", diff)
+	}
+}
+
+func TestUploadTestResults_Disabled(t *testing.T) {
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+	}))
+	defer svr.Close()
+
+	cfg := config.Config{UploadBaseUrl: svr.URL}
+	attempts := []runner.AttemptResult{
+		{Tests: []runner.TestResult{{Name: "is red", Status: runner.TestStatusPassed}}},
+	}
+
+	uploadTestResults(context.Background(), cfg, attempts, nil)
+}
+
+func TestUploadTestResults_Interrupted(t *testing.T) {
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+	}))
+	defer svr.Close()
+
+	cfg := config.Config{UploadResults: true, UploadBaseUrl: svr.URL}
+	attempts := []runner.AttemptResult{
+		{Tests: []runner.TestResult{{Name: "is red", Status: runner.TestStatusPassed}}},
+	}
+
+	uploadTestResults(context.Background(), cfg, attempts, &runner.ProcessSignaledError{Signal: syscall.SIGTERM})
+	uploadTestResults(context.Background(), cfg, attempts, fmt.Errorf("fetching the next batch: %w", context.Canceled))
+}